@@ -8,21 +8,46 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"log/slog"
+	"os"
+	"time"
 	_ "wallet/docs"
 	authClient "wallet/internal/clients/auth"
 	exchangeClient "wallet/internal/clients/exchange"
+	"wallet/internal/clients/resilience"
 	"wallet/internal/config"
+	"wallet/internal/domain/apikey"
+	apikeyDB "wallet/internal/domain/apikey/db"
 	"wallet/internal/domain/auth"
+	"wallet/internal/domain/idempotency"
+	idempotencyDB "wallet/internal/domain/idempotency/db"
+	"wallet/internal/domain/outbox"
+	outboxDB "wallet/internal/domain/outbox/db"
+	"wallet/internal/domain/realtime"
+	"wallet/internal/domain/stepup"
+	stepupDB "wallet/internal/domain/stepup/db"
 	wallet2 "wallet/internal/domain/wallet"
 	"wallet/internal/domain/wallet/db"
+	"wallet/internal/domain/webhook"
+	webhookDB "wallet/internal/domain/webhook/db"
 	"wallet/pkg/clients/psql"
 	"wallet/pkg/clients/redis"
 )
 
+// outboxBatchSize is the number of events the relay publishes per transaction.
+const outboxBatchSize = 100
+
 type App struct {
-	config *config.Config
-	logger *slog.Logger
-	router *gin.Engine
+	config   *config.Config
+	logger   *slog.Logger
+	router   *gin.Engine
+	wallet   wallet2.Service
+	relay    *outbox.Relay
+	webhooks webhook.Service
+	broker   *realtime.Broker
+	// idempotencyKeys are purged of expired keys.
+	idempotencyKeys idempotency.Storage
+	// keys is nil unless tokens are verified with a JWKS.
+	keys *auth.KeySet
 }
 
 // @title Wallet service API
@@ -52,20 +77,67 @@ func NewApp(logger *slog.Logger, cfg *config.Config) (*App, error) {
 	}
 	repo := db.NewRepository(c, logger)
 	cache := db.NewCache(logger, rdb)
+	idempotencyRepo := idempotencyDB.NewRepository(c, logger)
+	webhooks := webhook.NewService(webhookDB.NewRepository(c, logger), logger, webhook.NewClient())
+	relay := outbox.NewRelay(
+		outboxDB.NewRepository(c, logger),
+		outbox.MultiPublisher(outboxDB.NewRedisPublisher(logger, rdb, cfg.Events.Stream), webhooks),
+		logger,
+		outboxBatchSize,
+	)
 
+	breakerCfg := resilience.BreakerConfig{
+		FailureThreshold: cfg.Clients.Breaker.FailureThreshold,
+		OpenTimeout:      cfg.Clients.Breaker.OpenTimeout,
+		HalfOpenRequests: cfg.Clients.Breaker.HalfOpenRequests,
+	}
+	authGuard := resilience.NewGuard("auth",
+		resilience.NewBreaker(breakerCfg),
+		resilience.NewBulkhead(cfg.Clients.Auth.MaxConcurrent, cfg.Clients.BulkheadMaxWait),
+	)
+	exchangeGuard := resilience.NewGuard("exchange",
+		resilience.NewBreaker(breakerCfg),
+		resilience.NewBulkhead(cfg.Clients.Exchange.MaxConcurrent, cfg.Clients.BulkheadMaxWait),
+	)
 	authGRPC, err := authClient.New(
 		logger,
 		cfg.Clients.Auth.Address,
 		cfg.Clients.Auth.Timeout,
 		cfg.Clients.Auth.Retries,
+		authGuard,
 	)
 	exchangeGRPC, err := exchangeClient.New(
 		logger,
 		cfg.Clients.Exchange.Address,
 		cfg.Clients.Exchange.Timeout,
 		cfg.Clients.Exchange.Retries,
+		exchangeGuard,
 	)
-	s := wallet2.NewService(repo, logger, cache, exchangeGRPC)
+	hub := realtime.NewHub()
+	broker := realtime.NewBroker(logger, rdb, hub)
+	stepUp := stepup.NewService(
+		stepupDB.NewRepository(c, logger),
+		logger,
+		cache,
+		stepupDB.NewStreamNotifier(logger, rdb, cfg.StepUp.NotifyStream),
+		stepup.Config{Issuer: cfg.StepUp.Issuer, ChallengeTTL: cfg.StepUp.ChallengeTTL},
+	)
+	s := wallet2.NewService(repo, logger, cache, exchangeGRPC, broker, stepUp, wallet2.RateConfig{
+		MaxStaleness:        cfg.Rates.MaxStaleness,
+		AllowStaleExchanges: cfg.Rates.AllowStaleExchanges,
+	})
+	signer, verifier, keys, err := newTokenAuth(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	sessions := auth.NewSessions(logger, cache, signer, verifier, auth.SessionsConfig{
+		LoginSecret: []byte(cfg.Secret),
+		AccessTTL:   cfg.Tokens.AccessTTL,
+		RefreshTTL:  cfg.Tokens.RefreshTTL,
+		Admins:      cfg.Admins,
+	})
+	apiKeys := apikey.NewService(apikeyDB.NewRepository(c, logger), logger)
+	authorized := auth.AuthorizationMiddleware(verifier, sessions, apiKeys)
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 	v := validator.New()
@@ -73,35 +145,136 @@ func NewApp(logger *slog.Logger, cfg *config.Config) (*App, error) {
 	walletGroup := apiV1.Group("/wallet")
 	authGroup := apiV1.Group("/auth")
 	exchangeGroup := apiV1.Group("/exchange")
+	adminGroup := apiV1.Group("/admin")
+	webhookGroup := apiV1.Group("/webhooks")
+	apiKeyGroup := apiV1.Group("/api-keys")
+	stepUpGroup := apiV1.Group("/step-up")
 
-	walletGroup.Use(auth.AuthorizationMiddleware([]byte(cfg.Secret)))
+	walletGroup.Use(authorized)
 
-	walletGroup.GET("/balance/", wallet2.GetWalletBalanceHandler(s))
-	walletGroup.POST("/deposit/", wallet2.UpdateWalletBalanceDeposit(s, v))
-	walletGroup.POST("/withdraw/", wallet2.UpdateWalletBalanceWithdraw(s, v))
+	balanceRead := auth.RequireScope(auth.ScopeBalanceRead)
+	walletGroup.GET("/balance/", balanceRead, wallet2.GetWalletBalanceHandler(s))
+	walletGroup.GET("/transactions/", balanceRead, wallet2.GetTransactionsHandler(s, v))
+	walletGroup.GET("/statement/", balanceRead, wallet2.GetStatementHandler(s, v))
+	walletGroup.GET("/stream/", balanceRead, realtime.StreamBalanceHandler(hub, s))
+	idempotent := idempotency.Middleware(idempotencyRepo, logger)
+
+	withdraw := auth.RequireScope(auth.ScopeWithdraw)
+	walletGroup.POST("/deposit/", auth.RequireScope(auth.ScopeDeposit), idempotent, wallet2.UpdateWalletBalanceDeposit(s, v))
+	walletGroup.POST("/withdraw/", withdraw, idempotent, wallet2.UpdateWalletBalanceWithdraw(s, v))
+	walletGroup.POST("/withdraw/confirm/", withdraw, idempotent, wallet2.ConfirmWithdrawHandler(s, v))
+	walletGroup.POST("/transfer/", withdraw, idempotent, wallet2.TransferHandler(s, v))
+	walletGroup.POST("/transfer/confirm/", withdraw, idempotent, wallet2.ConfirmTransferHandler(s, v))
 
 	authGroup.POST("/register/", auth.Register(authGRPC, s, v))
-	authGroup.POST("/login/", auth.Login(authGRPC, v))
+	authGroup.POST("/login/", auth.Login(authGRPC, sessions, v))
+	authGroup.POST("/refresh/", auth.Refresh(sessions))
+	authGroup.POST("/logout/", authorized, auth.Logout(sessions))
 
-	exchangeGroup.Use(auth.AuthorizationMiddleware([]byte(cfg.Secret)))
-	exchangeGroup.POST("/", wallet2.ExchangeRatesForCurrency(s, v))
+	exchangeGroup.Use(authorized)
+	exchange := auth.RequireScope(auth.ScopeExchange)
+	exchangeGroup.POST("/", exchange, idempotent, wallet2.ExchangeRatesForCurrency(s, v))
+	exchangeGroup.POST("/quote/", exchange, wallet2.CreateQuoteHandler(s, v))
 	exchangeGroup.GET("/rates/", wallet2.GetExchangeRates(s))
+	exchangeGroup.GET("/rates/stream/", realtime.StreamRatesHandler(hub, s))
+	exchangeGroup.GET("/rates/history/", wallet2.GetRateHistoryHandler(s, v))
+
+	webhookGroup.Use(authorized, auth.RequireScope(auth.ScopeWebhooks))
+	webhookGroup.POST("/endpoints/", webhook.CreateEndpointHandler(webhooks, v))
+	webhookGroup.GET("/endpoints/", webhook.GetEndpointsHandler(webhooks))
+	webhookGroup.DELETE("/endpoints/:id/", webhook.DeleteEndpointHandler(webhooks, v))
+	webhookGroup.GET("/endpoints/:id/deliveries/", webhook.GetDeliveriesHandler(webhooks, v))
+	webhookGroup.GET("/deliveries/:id/", webhook.GetDeliveryHandler(webhooks, v))
+	webhookGroup.POST("/deliveries/:id/replay/", webhook.ReplayDeliveryHandler(webhooks, v))
+
+	apiKeyGroup.Use(authorized, auth.RequireScope(auth.ScopeAPIKeys))
+	apiKeyGroup.POST("/", apikey.CreateKeyHandler(apiKeys, v))
+	apiKeyGroup.GET("/", apikey.GetKeysHandler(apiKeys))
+	apiKeyGroup.DELETE("/:id/", apikey.RevokeKeyHandler(apiKeys, v))
+
+	stepUpGroup.Use(authorized, auth.RequireScope(auth.ScopeStepUp))
+	stepUpGroup.POST("/totp/", stepup.EnrolTOTPHandler(stepUp))
+	stepUpGroup.POST("/totp/confirm/", stepup.ConfirmTOTPHandler(stepUp, v))
+	stepUpGroup.DELETE("/totp/", stepup.DisableTOTPHandler(stepUp, v))
+
+	adminGroup.Use(authorized)
+	settings := auth.RequireScope(auth.ScopeSettings)
+	adminGroup.GET("/currencies/", settings, wallet2.GetCurrenciesHandler(s))
+	adminGroup.POST("/currencies/", settings, wallet2.CreateCurrencyHandler(s, v))
+	adminGroup.PUT("/currencies/:code/", settings, wallet2.UpdateCurrencyHandler(s, v))
+	adminGroup.POST("/currencies/:code/enable/", settings, wallet2.SetCurrencyEnabledHandler(s, true))
+	adminGroup.POST("/currencies/:code/disable/", settings, wallet2.SetCurrencyEnabledHandler(s, false))
+	adminGroup.GET("/fee-rules/", settings, wallet2.GetFeeRulesHandler(s))
+	adminGroup.PUT("/fee-rules/:from/:to/", settings, wallet2.SetFeeRulesHandler(s, v))
+	adminGroup.GET("/ledger/trial-balance/", settings, wallet2.GetTrialBalanceHandler(s))
+
+	walletsRead := auth.RequireScope(auth.ScopeWalletsRead)
+	walletsFreeze := auth.RequireScope(auth.ScopeWalletsFreeze)
+	adminGroup.GET("/wallets/:user_id/", walletsRead, wallet2.AdminGetWalletHandler(s, v))
+	adminGroup.GET("/wallets/:user_id/transactions/", walletsRead, wallet2.AdminGetTransactionsHandler(s, v))
+	adminGroup.GET("/wallets/:user_id/actions/", walletsRead, wallet2.GetAdminActionsHandler(s, v))
+	adminGroup.POST("/wallets/:user_id/freeze/", walletsFreeze, wallet2.FreezeWalletHandler(s, v, true))
+	adminGroup.POST("/wallets/:user_id/unfreeze/", walletsFreeze, wallet2.FreezeWalletHandler(s, v, false))
+	adminGroup.POST("/wallets/:user_id/adjustments/", auth.RequireScope(auth.ScopeWalletsAdjust), idempotent, wallet2.AdjustBalanceHandler(s, v))
 
+	r.GET("/health/", resilience.HealthHandler(authGuard, exchangeGuard))
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	var app = &App{
-		config: cfg,
-		logger: logger,
-		router: r,
+		config:   cfg,
+		logger:   logger,
+		router:   r,
+		wallet:   s,
+		relay:    relay,
+		webhooks: webhooks,
+		broker:   broker,
+		keys:     keys,
+
+		idempotencyKeys: idempotencyRepo,
 	}
 	return app, nil
 }
 
+// newTokenAuth signs and verifies tokens with the shared secret, or with the
+// signing key and the JWKS when a JWKS source is configured.
+func newTokenAuth(logger *slog.Logger, cfg *config.Config) (*auth.Signer, *auth.Verifier, *auth.KeySet, error) {
+	t := cfg.Tokens
+	if t.JWKSSource == "" {
+		return auth.NewHMACSigner([]byte(cfg.Secret), t.Issuer, t.Audience),
+			auth.NewHMACVerifier([]byte(cfg.Secret), t.Issuer, t.Audience, t.Leeway), nil, nil
+	}
+	if t.SigningKeyFile == "" || t.SigningKeyID == "" {
+		return nil, nil, nil, fmt.Errorf("JWT_SIGNING_KEY_FILE and JWT_SIGNING_KEY_ID are required with JWKS_SOURCE")
+	}
+	pemData, err := os.ReadFile(t.SigningKeyFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	signer, err := auth.NewKeySigner(pemData, t.SigningKeyID, t.Issuer, t.Audience)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keys := auth.NewKeySet(logger, t.JWKSSource, t.JWKSOverlap)
+	if err = keys.Refresh(context.Background()); err != nil {
+		return nil, nil, nil, err
+	}
+	return signer, auth.NewJWKSVerifier(keys, t.Issuer, t.Audience, t.Leeway), keys, nil
+}
+
 func (app *App) Start() {
 	app.logger.Info("Start HTTP server")
 	errChan := make(chan error)
 	serverAddr := fmt.Sprintf("%s:%s", app.config.Server.Address, app.config.Server.Port)
 
+	go app.takeSnapshots(context.Background())
+	go app.relayEvents(context.Background())
+	go app.deliverWebhooks(context.Background())
+	go app.refreshRates(context.Background())
+	go app.purgeIdempotencyKeys(context.Background())
+	go app.broker.Run(context.Background())
+	if app.keys != nil {
+		go app.keys.Run(context.Background(), app.config.Tokens.JWKSRefreshInterval)
+	}
 	go func() {
 		if err := app.router.Run(serverAddr); err != nil {
 			app.logger.Error("Failed to start HTTP server", "error", err)
@@ -115,3 +288,99 @@ func (app *App) Start() {
 		}
 	}
 }
+
+// takeSnapshots periodically stores the balances of all accounts, which keeps
+// point-in-time balance queries fast.
+func (app *App) takeSnapshots(ctx context.Context) {
+	ticker := time.NewTicker(app.config.Workers.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.wallet.TakeSnapshots(ctx); err != nil {
+				app.logger.Error("Failed to take balance snapshots", "error", err)
+			}
+		}
+	}
+}
+
+// relayEvents publishes the domain events written to the outbox.
+func (app *App) relayEvents(ctx context.Context) {
+	ticker := time.NewTicker(app.config.Workers.OutboxInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.relay.Drain(ctx); err != nil {
+				app.logger.Error("Failed to publish outbox events", "error", err)
+			}
+		}
+	}
+}
+
+// refreshRates reads the exchange rates periodically, so they are fetched
+// from the exchanger and pushed to the streaming clients as soon as the
+// cached rates expire, even when no client asks for them.
+func (app *App) refreshRates(ctx context.Context) {
+	ticker := time.NewTicker(app.config.Workers.RatesInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := app.wallet.GetExchangeRates(ctx); err != nil {
+				app.logger.Error("Failed to refresh exchange rates", "error", err)
+			}
+		}
+	}
+}
+
+// purgeIdempotencyKeys deletes the idempotency keys whose responses are no
+// longer replayed, so the table doesn't grow with every request.
+func (app *App) purgeIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(app.config.Workers.IdempotencyPurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := idempotency.PurgeExpired(ctx, app.idempotencyKeys)
+			if err != nil {
+				app.logger.Error("Failed to purge idempotency keys", "error", err)
+				continue
+			}
+			if n > 0 {
+				app.logger.Info("Purged expired idempotency keys", "count", n)
+			}
+		}
+	}
+}
+
+// deliverWebhooks sends the webhook deliveries due for sending, batch after
+// batch until none is left.
+func (app *App) deliverWebhooks(ctx context.Context) {
+	ticker := time.NewTicker(app.config.Workers.WebhookInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := app.webhooks.DeliverDue(ctx)
+				if err != nil {
+					app.logger.Error("Failed to deliver webhooks", "error", err)
+				}
+				if err != nil || n == 0 {
+					break
+				}
+			}
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	"log/slog"
 	"time"
+	"wallet/internal/clients/resilience"
 	"wallet/internal/domain/wallet"
 )
 
@@ -19,7 +20,9 @@ type Client struct {
 	log *slog.Logger
 }
 
-func New(log *slog.Logger, addr string, timeOut time.Duration, retries uint) (*Client, error) {
+// New connects to the service. Calls go through the guard, which fails
+// them fast while the service is unhealthy or overloaded.
+func New(log *slog.Logger, addr string, timeOut time.Duration, retries uint, guard *resilience.Guard) (*Client, error) {
 	const op = "clients.auth.New"
 	retryOpts := []grpcretry.CallOption{
 		grpcretry.WithCodes(codes.NotFound, codes.Aborted, codes.DeadlineExceeded),
@@ -33,6 +36,7 @@ func New(log *slog.Logger, addr string, timeOut time.Duration, retries uint) (*C
 		addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithChainUnaryInterceptor(
+			guard.UnaryClientInterceptor(),
 			grpclog.UnaryClientInterceptor(InterceptorLogger(log), logOpts...),
 			grpcretry.UnaryClientInterceptor(retryOpts...),
 		),
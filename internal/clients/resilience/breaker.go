@@ -0,0 +1,142 @@
+// Package resilience keeps a sick upstream from cascading into the callers:
+// a circuit breaker fails calls fast while the upstream keeps failing and a
+// bulkhead limits the number of calls in flight.
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	// StateClosed lets all calls through.
+	StateClosed State = iota
+	// StateOpen fails all calls until the open timeout passes.
+	StateOpen
+	// StateHalfOpen lets a few trial calls through to decide whether to close
+	// or open again.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before trial calls.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial calls let through at once while
+	// half-open; all of them must succeed to close the breaker.
+	HalfOpenRequests int
+}
+
+type Breaker struct {
+	cfg      BreakerConfig
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	// trials is the number of trial calls in flight, successes the number of
+	// those that succeeded since the breaker became half-open.
+	trials    int
+	successes int
+}
+
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.HalfOpenRequests < 1 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &Breaker{cfg: cfg}
+}
+
+// Allow reports ErrOpen if the call must fail fast. Otherwise the caller
+// must report the outcome of the call to done.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if time.Now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return nil, ErrOpen
+		}
+		b.state, b.trials, b.successes = StateHalfOpen, 0, 0
+	}
+	if b.state == StateHalfOpen {
+		if b.trials >= b.cfg.HalfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.trials++
+		return b.doneTrial, nil
+	}
+	return b.done, nil
+}
+
+func (b *Breaker) done(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateClosed {
+		// The breaker opened while the call was in flight.
+		return
+	}
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.cfg.FailureThreshold {
+		b.open()
+	}
+}
+
+func (b *Breaker) doneTrial(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateHalfOpen {
+		return
+	}
+	b.trials--
+	if !success {
+		b.open()
+		return
+	}
+	b.successes++
+	if b.successes >= b.cfg.HalfOpenRequests {
+		b.state, b.failures = StateClosed, 0
+	}
+}
+
+func (b *Breaker) open() {
+	b.state, b.openedAt = StateOpen, time.Now()
+}
+
+// State returns the state of the breaker. An open breaker whose timeout has
+// passed is reported as half-open, as the next call is a trial.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
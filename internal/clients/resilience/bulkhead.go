@@ -0,0 +1,59 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBulkheadFull = errors.New("too many calls in flight")
+
+// Bulkhead limits the number of concurrent calls to an upstream, so a hung
+// upstream can't tie up more than that many goroutines of the callers.
+type Bulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead lets maxConcurrent calls in flight at once. A call waits up to
+// maxWait for a free slot.
+func NewBulkhead(maxConcurrent int, maxWait time.Duration) *Bulkhead {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &Bulkhead{slots: make(chan struct{}, maxConcurrent), maxWait: maxWait}
+}
+
+// Acquire takes a slot, which must be given back with release. It reports
+// ErrBulkheadFull if no slot frees up in time.
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-b.slots }
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if b.maxWait <= 0 {
+		return nil, ErrBulkheadFull
+	}
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrBulkheadFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the number of calls in flight.
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// Capacity returns the maximum number of calls in flight.
+func (b *Bulkhead) Capacity() int {
+	return cap(b.slots)
+}
@@ -0,0 +1,82 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Guard protects the calls to an upstream with a circuit breaker and a
+// bulkhead.
+type Guard struct {
+	name     string
+	breaker  *Breaker
+	bulkhead *Bulkhead
+}
+
+func NewGuard(name string, breaker *Breaker, bulkhead *Bulkhead) *Guard {
+	return &Guard{
+		name:     name,
+		breaker:  breaker,
+		bulkhead: bulkhead,
+	}
+}
+
+func (g *Guard) Name() string {
+	return g.name
+}
+
+// Health is the state of the protection of an upstream.
+type Health struct {
+	State    string `json:"state" enums:"closed,open,half-open"`
+	InFlight int    `json:"in_flight"`
+	Capacity int    `json:"capacity"`
+}
+
+func (g *Guard) Health() Health {
+	return Health{
+		State:    g.breaker.State().String(),
+		InFlight: g.bulkhead.InFlight(),
+		Capacity: g.bulkhead.Capacity(),
+	}
+}
+
+// UnaryClientInterceptor must come before the retry interceptor in the
+// chain, so a call counts once however many times it is retried and the
+// retries don't take extra slots of the bulkhead. Calls refused by the
+// breaker fail with codes.Unavailable, calls refused by the bulkhead with
+// codes.ResourceExhausted.
+func (g *Guard) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		release, err := g.bulkhead.Acquire(ctx)
+		if err != nil {
+			if errors.Is(err, ErrBulkheadFull) {
+				return status.Errorf(codes.ResourceExhausted, "%s: %v", g.name, err)
+			}
+			return status.FromContextError(err).Err()
+		}
+		defer release()
+
+		done, err := g.breaker.Allow()
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "%s: %v", g.name, err)
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(!IsFailure(err))
+		return err
+	}
+}
+
+// IsFailure reports whether the error of a call tells that the upstream is
+// unhealthy. Errors such as NotFound or InvalidArgument are answers of a
+// healthy upstream, and a call canceled by the caller tells nothing.
+func IsFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,33 @@
+package resilience
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type HealthResponse struct {
+	// Status is "degraded" while the breaker of any upstream isn't closed.
+	Status       string            `json:"status" enums:"ok,degraded"`
+	Dependencies map[string]Health `json:"dependencies"`
+}
+
+// HealthHandler godoc
+// @Summary      Service health
+// @Description  State of the circuit breakers and bulkheads of the upstream services. The service keeps serving while an upstream is down, so the status code is 200 even when degraded.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  HealthResponse
+// @Router       /health/ [get]
+func HealthHandler(guards ...*Guard) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		res := HealthResponse{Status: "ok", Dependencies: make(map[string]Health, len(guards))}
+		for _, g := range guards {
+			h := g.Health()
+			if h.State != StateClosed.String() {
+				res.Status = "degraded"
+			}
+			res.Dependencies[g.Name()] = h
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
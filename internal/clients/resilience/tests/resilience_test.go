@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+	"wallet/internal/clients/resilience"
+)
+
+func TestBreaker(t *testing.T) {
+	b := resilience.NewBreaker(resilience.BreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+		HalfOpenRequests: 1,
+	})
+	call := func(success bool) error {
+		done, err := b.Allow()
+		if err != nil {
+			return err
+		}
+		done(success)
+		return nil
+	}
+
+	for _, success := range []bool{false, true, false} {
+		if err := call(success); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.State() != resilience.StateClosed {
+		t.Fatal("a success must reset the count of failures")
+	}
+	if err := call(false); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != resilience.StateOpen {
+		t.Fatalf("want open after 2 consecutive failures, got %s", b.State())
+	}
+	if err := call(true); !errors.Is(err, resilience.ErrOpen) {
+		t.Fatalf("want ErrOpen, got %v", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if b.State() != resilience.StateHalfOpen {
+		t.Fatalf("want half-open after the timeout, got %s", b.State())
+	}
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.Allow(); !errors.Is(err, resilience.ErrOpen) {
+		t.Fatal("want a single trial call while half-open")
+	}
+	done(false)
+	if b.State() != resilience.StateOpen {
+		t.Fatalf("want open after a failed trial, got %s", b.State())
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err = call(true); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != resilience.StateClosed {
+		t.Fatalf("want closed after a successful trial, got %s", b.State())
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	b := resilience.NewBulkhead(2, 10*time.Millisecond)
+	ctx := context.Background()
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.Acquire(ctx); !errors.Is(err, resilience.ErrBulkheadFull) {
+		t.Fatalf("want ErrBulkheadFull, got %v", err)
+	}
+	if b.InFlight() != 2 {
+		t.Fatalf("want 2 calls in flight, got %d", b.InFlight())
+	}
+	release()
+	if _, err = b.Acquire(ctx); err != nil {
+		t.Fatalf("want a slot after release, got %v", err)
+	}
+}
+
+func TestGuardInterceptor(t *testing.T) {
+	guard := resilience.NewGuard("exchange",
+		resilience.NewBreaker(resilience.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}),
+		resilience.NewBulkhead(1, 0),
+	)
+	interceptor := guard.UnaryClientInterceptor()
+	calls := 0
+	invoke := func(err error) grpc.UnaryInvoker {
+		return func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			calls++
+			return err
+		}
+	}
+	ctx := context.Background()
+
+	err := interceptor(ctx, "/Get", nil, nil, nil, invoke(status.Error(codes.NotFound, "no rate")))
+	if status.Code(err) != codes.NotFound || guard.Health().State != "closed" {
+		t.Fatalf("NotFound must not open the breaker, got %v, %s", err, guard.Health().State)
+	}
+	_ = interceptor(ctx, "/Get", nil, nil, nil, invoke(status.Error(codes.Unavailable, "down")))
+	err = interceptor(ctx, "/Get", nil, nil, nil, invoke(nil))
+	if status.Code(err) != codes.Unavailable || calls != 2 {
+		t.Fatalf("want the call to fail fast, got %v after %d calls", err, calls)
+	}
+	if h := guard.Health(); h.State != "open" || h.InFlight != 0 || h.Capacity != 1 {
+		t.Fatalf("unexpected health %+v", h)
+	}
+}
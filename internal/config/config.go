@@ -3,6 +3,8 @@ package config
 import (
 	"github.com/joho/godotenv"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,7 +13,15 @@ type Config struct {
 	Storage StorageConfig
 	Cache   CacheConfig
 	Clients Clients
+	Workers WorkersConfig
+	Events  EventsConfig
+	Rates   RatesConfig
+	Tokens  TokensConfig
+	StepUp  StepUpConfig
 	Secret  string
+	// Admins are the ids of the users granted the admin role on top of the
+	// roles given by the auth service.
+	Admins []string
 }
 
 type CacheConfig struct {
@@ -34,21 +44,102 @@ type ServerConfig struct {
 	Port    string
 }
 
+type WorkersConfig struct {
+	// SnapshotInterval is how often balance snapshots are taken.
+	SnapshotInterval time.Duration
+	// OutboxInterval is how often the outbox is checked for new events.
+	OutboxInterval time.Duration
+	// WebhookInterval is how often due webhook deliveries are sent.
+	WebhookInterval time.Duration
+	// RatesInterval is how often the cached exchange rates are checked and,
+	// once expired, refreshed and pushed to the clients streaming rates.
+	RatesInterval time.Duration
+	// IdempotencyPurgeInterval is how often expired idempotency keys are
+	// deleted.
+	IdempotencyPurgeInterval time.Duration
+}
+
+type EventsConfig struct {
+	// Stream is the Redis stream domain events are published to.
+	Stream string
+}
+
+type TokensConfig struct {
+	// AccessTTL is the lifetime of access tokens.
+	AccessTTL time.Duration
+	// RefreshTTL is the lifetime of refresh tokens and of the sessions
+	// without a refresh.
+	RefreshTTL time.Duration
+	Issuer     string
+	Audience   string
+	// Leeway is the clock skew allowed when checking exp and nbf.
+	Leeway time.Duration
+	// JWKSSource is the file path or the URL of the JWKS document tokens are
+	// verified with. Tokens are signed with Secret when it is empty.
+	JWKSSource string
+	// JWKSRefreshInterval is how often the JWKS document is reloaded.
+	JWKSRefreshInterval time.Duration
+	// JWKSOverlap is how long keys dropped from the document are still
+	// accepted.
+	JWKSOverlap time.Duration
+	// SigningKeyFile is the PEM private key tokens are signed with when
+	// JWKSSource is set. Its public key is published under SigningKeyID.
+	SigningKeyFile string
+	SigningKeyID   string
+}
+
+type StepUpConfig struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string
+	// ChallengeTTL is how long large withdrawals wait for confirmation.
+	ChallengeTTL time.Duration
+	// NotifyStream is the Redis stream one-time codes are handed over to the
+	// notification service through.
+	NotifyStream string
+}
+
+type RatesConfig struct {
+	// MaxStaleness is how old the last known rate may be to be used while
+	// the exchanger is unavailable.
+	MaxStaleness time.Duration
+	// AllowStaleExchanges permits exchanges at rates not fresh from the
+	// exchanger.
+	AllowStaleExchanges bool
+}
+
 type Clients struct {
 	Auth     AuthClientConfig
 	Exchange ExchangeClientConfig
+	// Breaker sets up the circuit breakers of all clients.
+	Breaker BreakerConfig
+	// BulkheadMaxWait is how long a call waits for a free slot when the
+	// client has MaxConcurrent calls in flight.
+	BulkheadMaxWait time.Duration
+}
+
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens a
+	// breaker.
+	FailureThreshold int
+	// OpenTimeout is how long a breaker fails calls fast before trying again.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial calls that must succeed to
+	// close a breaker.
+	HalfOpenRequests int
 }
 
 type AuthClientConfig struct {
-	Address string
-	Timeout time.Duration
-	Retries uint
+	Address       string
+	Timeout       time.Duration
+	Retries       uint
+	MaxConcurrent int
 }
 
 type ExchangeClientConfig struct {
-	Address string
-	Timeout time.Duration
-	Retries uint
+	Address       string
+	Timeout       time.Duration
+	Retries       uint
+	MaxConcurrent int
 }
 
 func getEnvWithDefault(key, defaultValue string) string {
@@ -58,6 +149,50 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			panic(err)
+		}
+		return d
+	}
+	return defaultValue
+}
+
+func getIntWithDefault(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			panic(err)
+		}
+		return n
+	}
+	return defaultValue
+}
+
+func getBoolWithDefault(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			panic(err)
+		}
+		return b
+	}
+	return defaultValue
+}
+
+// splitList splits a comma separated list, skipping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func MustLoad(cfgPath string) *Config {
 	if cfgPath != "" {
 		err := godotenv.Load(cfgPath)
@@ -68,6 +203,7 @@ func MustLoad(cfgPath string) *Config {
 
 	config := Config{
 		Secret: getEnvWithDefault("SECRET", "secret"),
+		Admins: splitList(getEnvWithDefault("ADMIN_IDS", "")),
 		Server: ServerConfig{
 			Address: getEnvWithDefault("SERVER_ADDRESS", "0.0.0.0"),
 			Port:    getEnvWithDefault("SERVER_PORT", "8080"),
@@ -85,17 +221,58 @@ func MustLoad(cfgPath string) *Config {
 			Password: getEnvWithDefault("REDIS_PASSWORD", "redis"),
 			DB:       getEnvWithDefault("REDIS_DB", "0"),
 		},
+		Workers: WorkersConfig{
+			SnapshotInterval: getDurationWithDefault("BALANCE_SNAPSHOT_INTERVAL", time.Hour),
+			OutboxInterval:   getDurationWithDefault("OUTBOX_RELAY_INTERVAL", time.Second),
+			WebhookInterval:  getDurationWithDefault("WEBHOOK_DELIVERY_INTERVAL", 5*time.Second),
+			RatesInterval:    getDurationWithDefault("RATES_REFRESH_INTERVAL", 5*time.Second),
+
+			IdempotencyPurgeInterval: getDurationWithDefault("IDEMPOTENCY_PURGE_INTERVAL", time.Hour),
+		},
+		Events: EventsConfig{
+			Stream: getEnvWithDefault("EVENTS_STREAM", "wallet_events"),
+		},
+		Tokens: TokensConfig{
+			AccessTTL:  getDurationWithDefault("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTTL: getDurationWithDefault("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			Issuer:     getEnvWithDefault("JWT_ISSUER", "wallet"),
+			Audience:   getEnvWithDefault("JWT_AUDIENCE", "wallet-api"),
+			Leeway:     getDurationWithDefault("JWT_LEEWAY", 30*time.Second),
+
+			JWKSSource:          getEnvWithDefault("JWKS_SOURCE", ""),
+			JWKSRefreshInterval: getDurationWithDefault("JWKS_REFRESH_INTERVAL", 5*time.Minute),
+			JWKSOverlap:         getDurationWithDefault("JWKS_ROTATION_OVERLAP", time.Hour),
+			SigningKeyFile:      getEnvWithDefault("JWT_SIGNING_KEY_FILE", ""),
+			SigningKeyID:        getEnvWithDefault("JWT_SIGNING_KEY_ID", ""),
+		},
+		StepUp: StepUpConfig{
+			Issuer:       getEnvWithDefault("TOTP_ISSUER", "Wallet"),
+			ChallengeTTL: getDurationWithDefault("STEP_UP_CHALLENGE_TTL", 5*time.Minute),
+			NotifyStream: getEnvWithDefault("STEP_UP_NOTIFY_STREAM", "notifications"),
+		},
+		Rates: RatesConfig{
+			MaxStaleness:        getDurationWithDefault("RATES_MAX_STALENESS", 15*time.Minute),
+			AllowStaleExchanges: getBoolWithDefault("RATES_ALLOW_STALE_EXCHANGES", false),
+		},
 		Clients: Clients{
 			Auth: AuthClientConfig{
-				Address: getEnvWithDefault("AUTH_GRPC_ADDR", "auth:44045"),
-				Timeout: 5 * time.Second,
-				Retries: 5,
+				Address:       getEnvWithDefault("AUTH_GRPC_ADDR", "auth:44045"),
+				Timeout:       5 * time.Second,
+				Retries:       5,
+				MaxConcurrent: getIntWithDefault("AUTH_GRPC_MAX_CONCURRENT", 100),
 			},
 			Exchange: ExchangeClientConfig{
-				Address: getEnvWithDefault("EXCHANGE_GRPC_ADDR", "exchanger:44044"),
-				Timeout: 5 * time.Second,
-				Retries: 5,
+				Address:       getEnvWithDefault("EXCHANGE_GRPC_ADDR", "exchanger:44044"),
+				Timeout:       5 * time.Second,
+				Retries:       5,
+				MaxConcurrent: getIntWithDefault("EXCHANGE_GRPC_MAX_CONCURRENT", 100),
+			},
+			Breaker: BreakerConfig{
+				FailureThreshold: getIntWithDefault("GRPC_BREAKER_FAILURE_THRESHOLD", 5),
+				OpenTimeout:      getDurationWithDefault("GRPC_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+				HalfOpenRequests: getIntWithDefault("GRPC_BREAKER_HALF_OPEN_REQUESTS", 1),
 			},
+			BulkheadMaxWait: getDurationWithDefault("GRPC_BULKHEAD_MAX_WAIT", 100*time.Millisecond),
 		},
 	}
 
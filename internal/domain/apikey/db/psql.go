@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+	"wallet/internal/domain/apikey"
+)
+
+type Storage struct {
+	Client apikey.PsqlClient
+	logger *slog.Logger
+}
+
+func NewRepository(client apikey.PsqlClient, logger *slog.Logger) apikey.Storage {
+	return &Storage{client, logger}
+}
+
+const keyColumns = `id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+func scanKey(row pgx.Row, k *apikey.Key) error {
+	return row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.SecretHash, &k.Scopes,
+		&k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+}
+
+func (s *Storage) CreateKey(ctx context.Context, k apikey.Key) (apikey.Key, error) {
+	const op = "apikey.db.CreateKey"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `INSERT INTO api_key(user_id, name, prefix, secret_hash, scopes, expires_at)
+		  VALUES ($1, $2, $3, $4, $5, $6)
+		  RETURNING id, created_at`
+	err := s.Client.QueryRow(ctx, q, k.UserID, k.Name, k.Prefix, k.SecretHash, k.Scopes, k.ExpiresAt).
+		Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		log.Error(err.Error())
+		return k, err
+	}
+	return k, nil
+}
+
+func (s *Storage) CountActiveKeys(ctx context.Context, userID string) (int, error) {
+	const op = "apikey.db.CountActiveKeys"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT COUNT(*)
+		  FROM api_key
+		  WHERE user_id=$1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+	var n int
+	if err := s.Client.QueryRow(ctx, q, userID).Scan(&n); err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *Storage) GetKeys(ctx context.Context, userID string) ([]apikey.Key, error) {
+	const op = "apikey.db.GetKeys"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT ` + keyColumns + `
+		  FROM api_key
+		  WHERE user_id=$1
+		  ORDER BY created_at`
+	rows, err := s.Client.Query(ctx, q, userID)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]apikey.Key, 0)
+	for rows.Next() {
+		var k apikey.Key
+		if err = scanKey(rows, &k); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *Storage) GetKeyByPrefix(ctx context.Context, prefix string) (apikey.Key, error) {
+	const op = "apikey.db.GetKeyByPrefix"
+	log := s.logger.With(slog.String("op", op))
+
+	var k apikey.Key
+	err := scanKey(s.Client.QueryRow(ctx, `SELECT `+keyColumns+` FROM api_key WHERE prefix=$1`, prefix), &k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return k, apikey.ErrKeyNotFound
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return k, err
+	}
+	return k, nil
+}
+
+func (s *Storage) RevokeKey(ctx context.Context, userID, id string) (apikey.Key, error) {
+	const op = "apikey.db.RevokeKey"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `UPDATE api_key SET revoked_at = NOW()
+		  WHERE user_id=$1 AND id=$2 AND revoked_at IS NULL
+		  RETURNING ` + keyColumns
+	var k apikey.Key
+	err := scanKey(s.Client.QueryRow(ctx, q, userID, id), &k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return k, apikey.ErrKeyNotFound
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return k, err
+	}
+	return k, nil
+}
+
+func (s *Storage) TouchKey(ctx context.Context, id string, usedAt time.Time) error {
+	const op = "apikey.db.TouchKey"
+	log := s.logger.With(slog.String("op", op))
+
+	_, err := s.Client.Exec(ctx, `UPDATE api_key SET last_used_at = $2 WHERE id=$1`, id, usedAt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
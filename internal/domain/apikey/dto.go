@@ -0,0 +1,14 @@
+package apikey
+
+import "time"
+
+type CreateKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=64" example:"shop backend"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=balance:read deposit withdraw exchange"`
+	// ExpiresAt is optional, keys without it are valid until revoked.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type KeysResponse struct {
+	Keys []Key `json:"keys"`
+}
@@ -0,0 +1,8 @@
+package apikey
+
+import "errors"
+
+var ErrSmtWentWrong = errors.New("something went wrong")
+var ErrKeyNotFound = errors.New("api key not found")
+var ErrTooManyKeys = errors.New("too many api keys")
+var ErrInvalidExpiry = errors.New("expiry must be in the future")
@@ -0,0 +1,148 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+// CreateKeyHandler godoc
+// @Summary      Create API key
+// @Description  Issue a key for the backend of a merchant to call the API with in the X-API-Key header instead of a Bearer token. The key can only use the given scopes and is returned only once.
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      CreateKeyRequest  true  "API key"
+// @Success      201      {object}  CreatedKey
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or expiry in the past"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      409      {object}  map[string]string       "too many api keys"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/api-keys/ [post]
+func CreateKeyHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req CreateKeyRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		k, err := s.CreateKey(context.Background(), userID, req)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, k)
+	}
+}
+
+// GetKeysHandler godoc
+// @Summary      List API keys
+// @Description  Retrieve the API keys of the user, including expired and revoked ones, without their secrets
+// @Tags         api-keys
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      200  {object}  KeysResponse
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/api-keys/ [get]
+func GetKeysHandler(s Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		keys, err := s.GetKeys(context.Background(), userID)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, KeysResponse{Keys: keys})
+	}
+}
+
+// RevokeKeyHandler godoc
+// @Summary      Revoke API key
+// @Description  Stop the key from working right away
+// @Tags         api-keys
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        id   path      string  true  "API key id"
+// @Success      200  {object}  Key
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      404  {object}  map[string]string  "api key not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/api-keys/{id}/ [delete]
+func RevokeKeyHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		if v.Var(id, "uuid") != nil {
+			writeJSONError(c, ErrKeyNotFound)
+			return
+		}
+		k, err := s.RevokeKey(context.Background(), userID, id)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, k)
+	}
+}
+
+// currentUser returns the id of the authenticated user, responding with an
+// error if there is none.
+func currentUser(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return "", false
+	}
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+		return "", false
+	}
+	return userIDStr, true
+}
+
+func writeJSONError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTooManyKeys):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidExpiry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+	}
+}
@@ -0,0 +1,38 @@
+package apikey
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+	"wallet/internal/domain/auth"
+)
+
+type PsqlClient interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type Storage interface {
+	CreateKey(ctx context.Context, k Key) (Key, error)
+	// CountActiveKeys counts the user's keys neither revoked nor expired.
+	CountActiveKeys(ctx context.Context, userID string) (int, error)
+	GetKeys(ctx context.Context, userID string) ([]Key, error)
+	// GetKeyByPrefix returns ErrKeyNotFound if there is no key with the
+	// prefix.
+	GetKeyByPrefix(ctx context.Context, prefix string) (Key, error)
+	// RevokeKey returns ErrKeyNotFound unless the user has a key with the id
+	// that is not revoked yet.
+	RevokeKey(ctx context.Context, userID, id string) (Key, error)
+	TouchKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type Service interface {
+	// CreateKey returns the new key with its secret, which can't be read
+	// later.
+	CreateKey(ctx context.Context, userID string, req CreateKeyRequest) (CreatedKey, error)
+	GetKeys(ctx context.Context, userID string) ([]Key, error)
+	RevokeKey(ctx context.Context, userID, id string) (Key, error)
+	auth.APIKeyAuthenticator
+}
@@ -0,0 +1,27 @@
+package apikey
+
+import "time"
+
+// Key lets the backend of a merchant act on the user's wallet within the
+// scopes of the key. Only the hash of the secret is stored, the key itself
+// is returned once, when it is created.
+type Key struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix is the public part of the key that identifies it.
+	Prefix     string     `json:"prefix"`
+	SecretHash []byte     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// LastUsedAt is updated at most once per lastUsedResolution.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedKey is a new key with the key to send in the X-API-Key header.
+type CreatedKey struct {
+	Key
+	APIKey string `json:"api_key" example:"wk_1f0c8e2a9b7d4c63_5e..."`
+}
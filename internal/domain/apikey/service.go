@@ -0,0 +1,176 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"wallet/internal/domain/auth"
+)
+
+// Keys look like "wk_<prefix>_<secret>" with the prefix and the secret hex
+// encoded.
+const (
+	keyMark     = "wk"
+	prefixBytes = 8
+	secretBytes = 32
+)
+
+const (
+	maxKeys = 20
+	// lastUsedResolution limits how often the last use of a key is written.
+	lastUsedResolution = time.Minute
+)
+
+type ServiceAPIKey struct {
+	logger  *slog.Logger
+	storage Storage
+}
+
+func NewService(storage Storage, logger *slog.Logger) Service {
+	return &ServiceAPIKey{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+func (s *ServiceAPIKey) CreateKey(ctx context.Context, userID string, req CreateKeyRequest) (CreatedKey, error) {
+	const op = "apikey.CreateKey"
+	log := s.logger.With(slog.String("op", op))
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return CreatedKey{}, ErrInvalidExpiry
+	}
+	n, err := s.storage.CountActiveKeys(ctx, userID)
+	if err != nil {
+		log.Error(err.Error())
+		return CreatedKey{}, ErrSmtWentWrong
+	}
+	if n >= maxKeys {
+		return CreatedKey{}, ErrTooManyKeys
+	}
+	prefix, err := randomHex(prefixBytes)
+	if err != nil {
+		log.Error(err.Error())
+		return CreatedKey{}, ErrSmtWentWrong
+	}
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		log.Error(err.Error())
+		return CreatedKey{}, ErrSmtWentWrong
+	}
+	k, err := s.storage.CreateKey(ctx, Key{
+		UserID:     userID,
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: hashSecret(secret),
+		Scopes:     req.Scopes,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return CreatedKey{}, ErrSmtWentWrong
+	}
+	log.Info("api key created", slog.String("user_id", userID), slog.String("key_id", k.ID))
+	return CreatedKey{Key: k, APIKey: keyMark + "_" + prefix + "_" + secret}, nil
+}
+
+func (s *ServiceAPIKey) GetKeys(ctx context.Context, userID string) ([]Key, error) {
+	const op = "apikey.GetKeys"
+	log := s.logger.With(slog.String("op", op))
+
+	keys, err := s.storage.GetKeys(ctx, userID)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	return keys, nil
+}
+
+// RevokeKey stops the key from working right away. Revoked keys are kept to
+// show when they were used last.
+func (s *ServiceAPIKey) RevokeKey(ctx context.Context, userID, id string) (Key, error) {
+	const op = "apikey.RevokeKey"
+	log := s.logger.With(slog.String("op", op))
+
+	k, err := s.storage.RevokeKey(ctx, userID, id)
+	if err != nil {
+		return Key{}, domainError(log, err)
+	}
+	log.Info("api key revoked", slog.String("user_id", userID), slog.String("key_id", k.ID))
+	return k, nil
+}
+
+// Authenticate looks the key up by its prefix and compares the hash of its
+// secret in constant time.
+func (s *ServiceAPIKey) Authenticate(ctx context.Context, key string) (auth.Principal, error) {
+	const op = "apikey.Authenticate"
+	log := s.logger.With(slog.String("op", op))
+
+	prefix, secret, ok := parseKey(key)
+	if !ok {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	k, err := s.storage.GetKeyByPrefix(ctx, prefix)
+	if errors.Is(err, ErrKeyNotFound) {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return auth.Principal{}, ErrSmtWentWrong
+	}
+	if subtle.ConstantTimeCompare(hashSecret(secret), k.SecretHash) != 1 {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	now := time.Now()
+	if k.RevokedAt != nil || (k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)) {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= lastUsedResolution {
+		// The request is served even if the last use can't be recorded.
+		if err = s.storage.TouchKey(ctx, k.ID, now); err != nil {
+			log.Error(err.Error())
+		}
+	}
+	return auth.Principal{UserID: k.UserID, Scopes: k.Scopes, APIKeyID: k.ID}, nil
+}
+
+// parseKey splits the key into its prefix and secret.
+func parseKey(key string) (string, string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != keyMark ||
+		len(parts[1]) != 2*prefixBytes || len(parts[2]) != 2*secretBytes {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// hashSecret hashes the secret with SHA-256. Secrets are random, so unlike
+// passwords they don't need a slow hash.
+func hashSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// domainError passes errors meant for the client through as is and logs and
+// hides everything else behind ErrSmtWentWrong.
+func domainError(log *slog.Logger, err error) error {
+	if errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	log.Error(err.Error())
+	return ErrSmtWentWrong
+}
@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain/apikey"
+	"wallet/internal/domain/auth"
+	"wallet/pkg/logger"
+)
+
+const userID = "7a1d3c9e-4b2f-4e86-9c05-1f8e6d2b3a74"
+
+// memoryStorage keeps the keys in memory in place of Postgres.
+type memoryStorage struct {
+	mu      sync.Mutex
+	keys    []apikey.Key
+	touches int
+}
+
+func (m *memoryStorage) CreateKey(_ context.Context, k apikey.Key) (apikey.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(m.keys))
+	k.CreatedAt = time.Now()
+	m.keys = append(m.keys, k)
+	return k, nil
+}
+
+func (m *memoryStorage) CountActiveKeys(_ context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, k := range m.keys {
+		if k.UserID == userID && k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now())) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryStorage) GetKeys(_ context.Context, userID string) ([]apikey.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []apikey.Key
+	for _, k := range m.keys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (m *memoryStorage) GetKeyByPrefix(_ context.Context, prefix string) (apikey.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.keys {
+		if k.Prefix == prefix {
+			return k, nil
+		}
+	}
+	return apikey.Key{}, apikey.ErrKeyNotFound
+}
+
+func (m *memoryStorage) RevokeKey(_ context.Context, userID, id string) (apikey.Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, k := range m.keys {
+		if k.UserID == userID && k.ID == id && k.RevokedAt == nil {
+			now := time.Now()
+			m.keys[i].RevokedAt = &now
+			return m.keys[i], nil
+		}
+	}
+	return apikey.Key{}, apikey.ErrKeyNotFound
+}
+
+func (m *memoryStorage) TouchKey(_ context.Context, id string, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, k := range m.keys {
+		if k.ID == id {
+			m.keys[i].LastUsedAt = &usedAt
+			m.touches++
+		}
+	}
+	return nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	storage := &memoryStorage{}
+	s := apikey.NewService(storage, logger.SetupLogger(logger.Prod, ""))
+
+	created, err := s.CreateKey(ctx, userID, apikey.CreateKeyRequest{Name: "shop", Scopes: []string{auth.ScopeBalanceRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.APIKey, "wk_"+created.Prefix+"_") {
+		t.Fatalf("unexpected key %q", created.APIKey)
+	}
+
+	p, err := s.Authenticate(ctx, created.APIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.UserID != userID || p.APIKeyID != created.ID || !p.HasScope(auth.ScopeBalanceRead) || p.HasScope(auth.ScopeWithdraw) {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+	// The last use is recorded once per minute at most.
+	if _, err = s.Authenticate(ctx, created.APIKey); err != nil {
+		t.Fatal(err)
+	}
+	if storage.touches != 1 {
+		t.Fatalf("want the last use recorded once, got %d", storage.touches)
+	}
+
+	tampered := created.APIKey[:len(created.APIKey)-1] + "0"
+	if strings.HasSuffix(created.APIKey, "0") {
+		tampered = created.APIKey[:len(created.APIKey)-1] + "1"
+	}
+	for _, key := range []string{tampered, "wk_short_key", "Bearer " + created.APIKey} {
+		if _, err = s.Authenticate(ctx, key); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Fatalf("want %q rejected, got %v", key, err)
+		}
+	}
+
+	if _, err = s.RevokeKey(ctx, userID, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Authenticate(ctx, created.APIKey); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("want a revoked key rejected, got %v", err)
+	}
+	if _, err = s.RevokeKey(ctx, userID, created.ID); !errors.Is(err, apikey.ErrKeyNotFound) {
+		t.Fatalf("want ErrKeyNotFound revoking twice, got %v", err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	if _, err = s.CreateKey(ctx, userID, apikey.CreateKeyRequest{Name: "old", Scopes: []string{auth.ScopeDeposit}, ExpiresAt: &past}); !errors.Is(err, apikey.ErrInvalidExpiry) {
+		t.Fatalf("want ErrInvalidExpiry, got %v", err)
+	}
+	soon := time.Now().Add(time.Hour)
+	expiring, err := s.CreateKey(ctx, userID, apikey.CreateKeyRequest{Name: "soon", Scopes: []string{auth.ScopeDeposit}, ExpiresAt: &soon})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.keys[len(storage.keys)-1].ExpiresAt = &past
+	if _, err = s.Authenticate(ctx, expiring.APIKey); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("want an expired key rejected, got %v", err)
+	}
+}
+
+func TestTooManyKeys(t *testing.T) {
+	ctx := context.Background()
+	s := apikey.NewService(&memoryStorage{}, logger.SetupLogger(logger.Prod, ""))
+	req := apikey.CreateKeyRequest{Name: "shop", Scopes: []string{auth.ScopeBalanceRead}}
+
+	var created apikey.CreatedKey
+	var err error
+	for i := 0; i < 20; i++ {
+		if created, err = s.CreateKey(ctx, userID, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = s.CreateKey(ctx, userID, req); !errors.Is(err, apikey.ErrTooManyKeys) {
+		t.Fatalf("want ErrTooManyKeys, got %v", err)
+	}
+	// Revoking a key makes room for a new one.
+	if _, err = s.RevokeKey(ctx, userID, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.CreateKey(ctx, userID, req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	s := apikey.NewService(&memoryStorage{}, logger.SetupLogger(logger.Prod, ""))
+	created, err := s.CreateKey(context.Background(), userID, apikey.CreateKeyRequest{
+		Name:   "shop",
+		Scopes: []string{auth.ScopeBalanceRead, auth.ScopeDeposit},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	verifier := auth.NewHMACVerifier([]byte("secret"), "wallet", "wallet-api", 0)
+	authorized := auth.AuthorizationMiddleware(verifier, nil, s)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString("userID")) }
+	r.GET("/balance/", authorized, auth.RequireScope(auth.ScopeBalanceRead), ok)
+	r.POST("/withdraw/", authorized, auth.RequireScope(auth.ScopeWithdraw), ok)
+	r.POST("/api-keys/", authorized, auth.RequireScope(auth.ScopeAPIKeys), ok)
+	request := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(auth.HeaderAPIKey, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request(http.MethodGet, "/balance/", created.APIKey); w.Code != http.StatusOK || w.Body.String() != userID {
+		t.Fatalf("want the key accepted for its owner, got %d %q", w.Code, w.Body.String())
+	}
+	if w := request(http.MethodPost, "/withdraw/", created.APIKey); w.Code != http.StatusForbidden {
+		t.Fatalf("want 403 outside of the key's scopes, got %d", w.Code)
+	}
+	if w := request(http.MethodPost, "/api-keys/", created.APIKey); w.Code != http.StatusForbidden {
+		t.Fatalf("want keys unable to manage keys, got %d", w.Code)
+	}
+	if w := request(http.MethodGet, "/balance/", "wk_0000000000000000_"+strings.Repeat("0", 64)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("want 401 for an unknown key, got %d", w.Code)
+	}
+}
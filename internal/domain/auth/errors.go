@@ -0,0 +1,6 @@
+package auth
+
+import "errors"
+
+var ErrInvalidToken = errors.New("invalid token")
+var ErrSmtWentWrong = errors.New("something went wrong")
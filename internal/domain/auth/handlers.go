@@ -67,17 +67,17 @@ func Register(s ServiceAuth, wc WalletCreator, v *validator.Validate) func(c *gi
 
 // Login godoc
 // @Summary      User login
-// @Description  Authenticate a user and return a JWT token
+// @Description  Authenticate a user and return a short-lived access token with a refresh token
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request  body      LoginRequest  true  "Login request"
-// @Success      200      {object}  map[string]string       "JWT token"
+// @Success      200      {object}  TokenResponse
 // @Failure      400      {object}  map[string]interface{}  "Invalid request or validation failed"
 // @Failure      401      {object}  map[string]string       "Invalid username or password"
 // @Failure      500      {object}  map[string]string       "Internal server error"
 // @Router       /api/v1/auth/login/ [post]
-func Login(s ServiceAuth, v *validator.Validate) func(c *gin.Context) {
+func Login(s ServiceAuth, sessions SessionService, v *validator.Validate) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBind(&req); err != nil {
@@ -118,6 +118,82 @@ func Login(s ServiceAuth, v *validator.Validate) func(c *gin.Context) {
 			}
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"token": token})
+		res, err := sessions.Start(context.Background(), token)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// Refresh godoc
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for a new access token and refresh token. Every refresh token can be used once; using it again revokes the whole session.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      RefreshRequest  true  "Refresh request"
+// @Success      200      {object}  TokenResponse
+// @Failure      400      {object}  map[string]string  "invalid request"
+// @Failure      401      {object}  map[string]string  "invalid token"
+// @Failure      500      {object}  map[string]string  "something went wrong"
+// @Router       /api/v1/auth/refresh/ [post]
+func Refresh(sessions SessionService) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		res, err := sessions.Refresh(context.Background(), req.RefreshToken)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  Revoke the access token of the request right away and, if the refresh token is given, the session it belongs to
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string         true   "Bearer Token"  default(Bearer <token>)
+// @Param        request        body      LogoutRequest  false  "Logout request"
+// @Success      200      {object}  map[string]string  "Logged out"
+// @Failure      401      {object}  map[string]string  "invalid token"
+// @Failure      500      {object}  map[string]string  "something went wrong"
+// @Router       /api/v1/auth/logout/ [post]
+func Logout(sessions SessionService) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req LogoutRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBind(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+				return
+			}
+		}
+		p, ok := PrincipalFrom(c)
+		if !ok || p.TokenID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		if err := sessions.Logout(context.Background(), p.UserID, p.TokenID, p.ExpiresAt, req.RefreshToken); err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+func writeJSONError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
 	}
 }
@@ -1,12 +1,43 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ServiceAuth interface {
 	Register(ctx context.Context, wc WalletCreator, email, username, password string) (string, error)
 	Login(ctx context.Context, username, password string) (string, error)
 }
 
+type SessionService interface {
+	Start(ctx context.Context, loginToken string) (TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (TokenResponse, error)
+	Logout(ctx context.Context, userID, tokenID string, expiresAt time.Time, refreshToken string) error
+}
+
 type WalletCreator interface {
 	CreateUserWallet(ctx context.Context, userID string) error
 }
+
+type Cache interface {
+	GetValue(ctx context.Context, key string) (string, error)
+	SetValue(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	DeleteValue(ctx context.Context, key string) error
+	// PopValue returns the value and deletes the key in one step, so only
+	// one of concurrent callers gets it. A missing key yields "".
+	PopValue(ctx context.Context, key string) (string, error)
+}
+
+// Denylist tells whether a token was revoked before it expired.
+type Denylist interface {
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// APIKeyAuthenticator checks the API keys given in the HeaderAPIKey header.
+type APIKeyAuthenticator interface {
+	// Authenticate returns the principal of the key's owner with the scopes
+	// of the key, or ErrInvalidToken if the key is unknown, expired or
+	// revoked.
+	Authenticate(ctx context.Context, key string) (Principal, error)
+}
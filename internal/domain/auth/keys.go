@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits the refreshes triggered by tokens signed with an
+// unknown key, so forged kids can't flood the JWKS endpoint.
+const minRefreshInterval = 30 * time.Second
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// jwk is a JSON Web Key as defined by RFC 7517. Only the members of public
+// signing keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	key crypto.PublicKey
+	// alg, if set by the document, is the only algorithm the key verifies.
+	alg string
+	// retiredAt is when the key was dropped from the document, zero while it
+	// is there.
+	retiredAt time.Time
+}
+
+// KeySet is a cached JWKS document, read from a file or fetched over HTTP.
+// Keys dropped from the document are still accepted for the overlap, so
+// tokens signed just before a rotation keep working until they expire.
+type KeySet struct {
+	logger  *slog.Logger
+	source  string
+	client  *http.Client
+	overlap time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]publicKey
+	lastRefresh time.Time
+}
+
+// NewKeySet reads the document from source, an http(s) URL or a file path,
+// on Refresh.
+func NewKeySet(logger *slog.Logger, source string, overlap time.Duration) *KeySet {
+	return &KeySet{
+		logger:  logger,
+		source:  source,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		overlap: overlap,
+		keys:    make(map[string]publicKey),
+	}
+}
+
+// Refresh reloads the document. The cached keys are kept if it fails.
+func (ks *KeySet) Refresh(ctx context.Context) error {
+	const op = "auth.KeySet.Refresh"
+	log := ks.logger.With(slog.String("op", op))
+
+	ks.mu.Lock()
+	ks.lastRefresh = time.Now()
+	ks.mu.Unlock()
+
+	data, err := ks.read(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	fresh := make(map[string]publicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.Error("skipping key", slog.String("kid", k.Kid), slog.String("error", err.Error()))
+			continue
+		}
+		fresh[k.Kid] = publicKey{key: key, alg: k.Alg}
+	}
+
+	now := time.Now()
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	for kid, k := range ks.keys {
+		if _, ok := fresh[kid]; ok {
+			continue
+		}
+		if k.retiredAt.IsZero() {
+			k.retiredAt = now
+		}
+		if now.Sub(k.retiredAt) < ks.overlap {
+			fresh[kid] = k
+		}
+	}
+	ks.keys = fresh
+	return nil
+}
+
+// Run refreshes the key set every interval until ctx is done.
+func (ks *KeySet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = ks.Refresh(ctx)
+		}
+	}
+}
+
+// key returns the key with the id. An unknown id triggers a refresh, unless
+// the key set was refreshed a moment ago.
+func (ks *KeySet) key(kid string) (publicKey, error) {
+	ks.mu.RLock()
+	k, ok := ks.keys[kid]
+	recent := time.Since(ks.lastRefresh) < minRefreshInterval
+	ks.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+	if recent {
+		return publicKey{}, ErrUnknownKey
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := ks.Refresh(ctx); err != nil {
+		return publicKey{}, ErrUnknownKey
+	}
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if k, ok = ks.keys[kid]; !ok {
+		return publicKey{}, ErrUnknownKey
+	}
+	return k, nil
+}
+
+func (ks *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(ks.source, "http://") && !strings.HasPrefix(ks.source, "https://") {
+		return os.ReadFile(ks.source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || n.BitLen() < 2048 {
+			return nil, errors.New("weak RSA key")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC point")
+		}
+		// NewPublicKey rejects points that are not on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err = ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -1,15 +1,39 @@
 package auth
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"net/http"
 	"strings"
 )
 
-func AuthorizationMiddleware(secret []byte) gin.HandlerFunc {
+// HeaderAPIKey carries the API key of server-to-server requests.
+const HeaderAPIKey = "X-API-Key"
+
+// AuthorizationMiddleware accepts the access tokens issued by Sessions that
+// pass the verifier and are not revoked, or, without the Authorization
+// header, the API keys accepted by apiKeys. It sets the Principal of the
+// request and, for the handlers that only need the user, userID.
+func AuthorizationMiddleware(verifier *Verifier, denylist Denylist, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if key := c.GetHeader(HeaderAPIKey); authHeader == "" && key != "" && apiKeys != nil {
+			p, err := apiKeys.Authenticate(c.Request.Context(), key)
+			if errors.Is(err, ErrInvalidToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+				c.Abort()
+				return
+			}
+			c.Set(principalKey, p)
+			c.Set("userID", p.UserID)
+			c.Next()
+			return
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
 			c.Abort()
@@ -23,28 +47,48 @@ func AuthorizationMiddleware(secret []byte) gin.HandlerFunc {
 			return
 		}
 
-		tokenStr := parts[1]
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Проверяем метод подписи токена
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return secret, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := parseToken(verifier, parts[1], tokenTypeAccess)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
+		revoked, err := denylist.IsRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		p := newPrincipal(claims)
+		c.Set(principalKey, p)
+		c.Set("userID", p.UserID)
+		c.Next()
+	}
+}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			c.Set("userID", claims["id"])
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+// RequireScope lets through only the principals granted all of the scopes.
+// It must be used after AuthorizationMiddleware.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := PrincipalFrom(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 			c.Abort()
 			return
 		}
+		for _, scope := range scopes {
+			if !p.HasScope(scope) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
+				c.Abort()
+				return
+			}
+		}
 
 		c.Next()
 	}
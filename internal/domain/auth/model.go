@@ -10,3 +10,23 @@ type LoginRequest struct {
 	Username string `json:"username,required" example:"user123" binding:"required"`
 	Password string `json:"password,required" example:"password123" binding:"required"`
 }
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest may carry the refresh token of the session, which is revoked
+// along with the access token used for the request.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse is a short-lived access token, sent as the Bearer token, and
+// the refresh token to get the next pair with. Every refresh token can be
+// used once.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// Roles given to users by the auth service or by the configuration. A role
+// stands for the scopes listed in roleScopes.
+const (
+	// RoleSupport is for the support staff looking after user wallets.
+	RoleSupport = "support"
+	// RoleAdmin may do everything support can and manage the service.
+	RoleAdmin = "admin"
+)
+
+// Scopes checked by RequireScope.
+const (
+	// Scopes of the user's own wallet. Sessions are granted all of them, API
+	// keys the ones they are issued with.
+	ScopeBalanceRead = "balance:read"
+	ScopeDeposit     = "deposit"
+	ScopeWithdraw    = "withdraw"
+	ScopeExchange    = "exchange"
+	// ScopeWebhooks, ScopeAPIKeys and ScopeStepUp are granted to sessions
+	// only.
+	ScopeWebhooks = "webhooks"
+	ScopeAPIKeys  = "api_keys"
+	// ScopeStepUp allows to enrol and remove the second factor.
+	ScopeStepUp = "step_up"
+
+	// ScopeWalletsRead allows to view the wallet of any user.
+	ScopeWalletsRead = "wallets:read"
+	// ScopeWalletsFreeze allows to freeze and unfreeze wallets.
+	ScopeWalletsFreeze = "wallets:freeze"
+	// ScopeWalletsAdjust allows manual adjustments of wallet balances.
+	ScopeWalletsAdjust = "wallets:adjust"
+	// ScopeSettings allows to manage currencies and fee rules and to audit
+	// the ledger.
+	ScopeSettings = "settings"
+)
+
+var sessionScopes = []string{
+	ScopeBalanceRead, ScopeDeposit, ScopeWithdraw, ScopeExchange, ScopeWebhooks, ScopeAPIKeys, ScopeStepUp,
+}
+
+var roleScopes = map[string][]string{
+	RoleSupport: {ScopeWalletsRead, ScopeWalletsFreeze},
+	RoleAdmin:   {ScopeWalletsRead, ScopeWalletsFreeze, ScopeWalletsAdjust, ScopeSettings},
+}
+
+// principalKey is the key the principal is stored under in the gin context.
+const principalKey = "principal"
+
+// Principal is the authenticated user of a request.
+type Principal struct {
+	UserID string
+	Roles  []string
+	// Scopes are the scopes granted by the token and by the roles.
+	Scopes []string
+	// TokenID and ExpiresAt identify the access token of the request.
+	TokenID   string
+	ExpiresAt time.Time
+	// APIKeyID is set instead when the request is made with an API key.
+	APIKeyID string
+}
+
+func newPrincipal(claims tokenClaims) Principal {
+	p := Principal{
+		UserID:  claims.UserID,
+		Roles:   claims.Roles,
+		Scopes:  append(strings.Fields(claims.Scope), sessionScopes...),
+		TokenID: claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		p.ExpiresAt = claims.ExpiresAt.Time
+	}
+	for _, role := range claims.Roles {
+		p.Scopes = append(p.Scopes, roleScopes[role]...)
+	}
+	return p
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFrom returns the principal set by AuthorizationMiddleware.
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"log/slog"
+	"time"
+)
+
+// Types of the tokens issued by the service. Only access tokens are accepted
+// by AuthorizationMiddleware.
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+type tokenClaims struct {
+	UserID string `json:"id"`
+	Type   string `json:"typ"`
+	// Family is shared by all refresh tokens rotated from the same login.
+	Family string   `json:"fam,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+	// Scope is the space separated list of scopes granted on top of the
+	// ones of the roles.
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Sessions issues access and refresh tokens and revokes them. Its state is
+// kept in the cache:
+//   - refresh_family:<family> holds the session while it is alive;
+//   - refresh_token:<jti> holds the family of a refresh token not used yet;
+//   - revoked_token:<jti> denies an access token until it expires.
+type Sessions struct {
+	logger   *slog.Logger
+	cache    Cache
+	signer   *Signer
+	verifier *Verifier
+	cfg      SessionsConfig
+}
+
+type SessionsConfig struct {
+	// LoginSecret checks the HMAC tokens given by the auth service on login.
+	LoginSecret []byte
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+	// Admins are the ids of the users granted RoleAdmin on top of the roles
+	// given by the auth service.
+	Admins []string
+}
+
+// session is what the auth service granted on login. Refreshes issue the
+// tokens from it rather than from the claims of the refresh token, so the
+// roles of the configuration are applied anew every time.
+type session struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles,omitempty"`
+	Scope  string   `json:"scope,omitempty"`
+}
+
+// NewSessions signs the tokens with signer; verifier must accept them.
+func NewSessions(logger *slog.Logger, cache Cache, signer *Signer, verifier *Verifier, cfg SessionsConfig) *Sessions {
+	return &Sessions{
+		logger:   logger,
+		cache:    cache,
+		signer:   signer,
+		verifier: verifier,
+		cfg:      cfg,
+	}
+}
+
+// Start checks the token given by the auth service on login and opens a
+// session of its user with the roles and the scope the token grants.
+func (s *Sessions) Start(ctx context.Context, loginToken string) (TokenResponse, error) {
+	const op = "auth.Start"
+	log := s.logger.With(slog.String("op", op))
+
+	var claims jwt.MapClaims
+	if _, err := jwt.ParseWithClaims(loginToken, &claims, hmacKey(s.cfg.LoginSecret)); err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrInvalidToken
+	}
+	userID, ok := claims["id"].(string)
+	if !ok || userID == "" {
+		return TokenResponse{}, ErrInvalidToken
+	}
+	roles, err := stringList(claims["roles"])
+	if err != nil {
+		return TokenResponse{}, ErrInvalidToken
+	}
+	scope, _ := claims["scope"].(string)
+	family, err := newTokenID()
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	sess := session{UserID: userID, Roles: roles, Scope: scope}
+	data, err := json.Marshal(sess)
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	if err = s.cache.SetValue(ctx, familyKey(family), string(data), s.cfg.RefreshTTL); err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	return s.issue(ctx, log, sess, family)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens. Each refresh
+// token is accepted once: a second use means it has leaked, so the whole
+// session is revoked.
+func (s *Sessions) Refresh(ctx context.Context, refreshToken string) (TokenResponse, error) {
+	const op = "auth.Refresh"
+	log := s.logger.With(slog.String("op", op))
+
+	claims, err := s.parse(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	family, err := s.cache.PopValue(ctx, refreshKey(claims.ID))
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	if family == "" {
+		log.Warn("refresh token reused, revoking the session", slog.String("user_id", claims.UserID))
+		if err = s.cache.DeleteValue(ctx, familyKey(claims.Family)); err != nil {
+			log.Error(err.Error())
+		}
+		return TokenResponse{}, ErrInvalidToken
+	}
+	data, err := s.cache.GetValue(ctx, familyKey(family))
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	if data == "" {
+		return TokenResponse{}, ErrInvalidToken
+	}
+	// Sessions started when only the user id was kept fail here and have to
+	// log in again.
+	var sess session
+	if err = json.Unmarshal([]byte(data), &sess); err != nil || sess.UserID != claims.UserID {
+		return TokenResponse{}, ErrInvalidToken
+	}
+	return s.issue(ctx, log, sess, family)
+}
+
+// Logout revokes the access token until it expires and, if a refresh token
+// of the user is given, the session it belongs to. Other access tokens of
+// the session stay valid until they expire, which is at most AccessTTL.
+func (s *Sessions) Logout(ctx context.Context, userID, tokenID string, expiresAt time.Time, refreshToken string) error {
+	const op = "auth.Logout"
+	log := s.logger.With(slog.String("op", op))
+
+	var claims tokenClaims
+	if refreshToken != "" {
+		c, err := s.parse(refreshToken, tokenTypeRefresh)
+		if err != nil {
+			return err
+		}
+		if c.UserID != userID {
+			return ErrInvalidToken
+		}
+		claims = c
+	}
+	if ttl := time.Until(expiresAt); ttl > 0 {
+		if err := s.cache.SetValue(ctx, revokedKey(tokenID), "1", ttl); err != nil {
+			log.Error(err.Error())
+			return ErrSmtWentWrong
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	if err := s.cache.DeleteValue(ctx, familyKey(claims.Family)); err != nil {
+		log.Error(err.Error())
+		return ErrSmtWentWrong
+	}
+	if err := s.cache.DeleteValue(ctx, refreshKey(claims.ID)); err != nil {
+		log.Error(err.Error())
+		return ErrSmtWentWrong
+	}
+	return nil
+}
+
+func (s *Sessions) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	v, err := s.cache.GetValue(ctx, revokedKey(tokenID))
+	if err != nil {
+		return false, err
+	}
+	return v != "", nil
+}
+
+// issue signs a pair of tokens of the session. The configured admins get
+// RoleAdmin here, so it is granted on the next refresh after they are added
+// and taken away on the next refresh after they are removed.
+func (s *Sessions) issue(ctx context.Context, log *slog.Logger, sess session, family string) (TokenResponse, error) {
+	roles := s.roles(sess.UserID, sess.Roles)
+	now := time.Now()
+	accessID, err := newTokenID()
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	refreshID, err := newTokenID()
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	access, err := s.signer.sign(tokenClaims{
+		UserID: sess.UserID,
+		Type:   tokenTypeAccess,
+		Roles:  roles,
+		Scope:  sess.Scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        accessID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.cfg.AccessTTL)),
+		},
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	refresh, err := s.signer.sign(tokenClaims{
+		UserID: sess.UserID,
+		Type:   tokenTypeRefresh,
+		Family: family,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        refreshID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.cfg.RefreshTTL)),
+		},
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	if err = s.cache.SetValue(ctx, refreshKey(refreshID), family, s.cfg.RefreshTTL); err != nil {
+		log.Error(err.Error())
+		return TokenResponse{}, ErrSmtWentWrong
+	}
+	return TokenResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(s.cfg.AccessTTL / time.Second),
+	}, nil
+}
+
+// roles adds RoleAdmin to the roles granted by the auth service if the user
+// is one of the configured admins.
+func (s *Sessions) roles(userID string, roles []string) []string {
+	for _, role := range roles {
+		if role == RoleAdmin {
+			return roles
+		}
+	}
+	for _, id := range s.cfg.Admins {
+		if id == userID {
+			return append(roles[:len(roles):len(roles)], RoleAdmin)
+		}
+	}
+	return roles
+}
+
+func (s *Sessions) parse(token, tokenType string) (tokenClaims, error) {
+	return parseToken(s.verifier, token, tokenType)
+}
+
+func newTokenID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// stringList reads a claim holding a list of strings. A missing claim is an
+// empty list.
+func stringList(claim interface{}) ([]string, error) {
+	if claim == nil {
+		return nil, nil
+	}
+	items, ok := claim.([]interface{})
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	res := make([]string, len(items))
+	for i, item := range items {
+		if res[i], ok = item.(string); !ok {
+			return nil, ErrInvalidToken
+		}
+	}
+	return res, nil
+}
+
+func familyKey(family string) string {
+	return "refresh_family:" + family
+}
+
+func refreshKey(tokenID string) string {
+	return "refresh_token:" + tokenID
+}
+
+func revokedKey(tokenID string) string {
+	return "revoked_token:" + tokenID
+}
@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"wallet/internal/domain/auth"
+	"wallet/pkg/logger"
+)
+
+type testKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+func newTestKeys(t *testing.T) []testKey {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []testKey{
+		{kid: "rsa-1", method: jwt.SigningMethodRS256, private: rsaKey},
+		{kid: "ec-1", method: jwt.SigningMethodES256, private: ecKey},
+		{kid: "ed-1", method: jwt.SigningMethodEdDSA, private: edKey},
+	}
+}
+
+func jwksDocument(t *testing.T, keys ...testKey) []byte {
+	t.Helper()
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	var doc struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for _, k := range keys {
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			doc.Keys = append(doc.Keys, map[string]string{
+				"kty": "RSA", "kid": k.kid, "use": "sig", "alg": "RS256",
+				"n": enc(pub.N.Bytes()), "e": enc(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			doc.Keys = append(doc.Keys, map[string]string{
+				"kty": "EC", "kid": k.kid, "crv": "P-256",
+				"x": enc(pub.X.FillBytes(make([]byte, 32))), "y": enc(pub.Y.FillBytes(make([]byte, 32))),
+			})
+		case ed25519.PublicKey:
+			doc.Keys = append(doc.Keys, map[string]string{"kty": "OKP", "kid": k.kid, "crv": "Ed25519", "x": enc(pub)})
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func accessToken(t *testing.T, k testKey, edit func(jwt.MapClaims)) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"id":  userID,
+		"typ": "access",
+		"jti": "4f6c9a1e2b7d",
+		"iss": "wallet",
+		"aud": "wallet-api",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+type noDenylist struct{}
+
+func (noDenylist) IsRevoked(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func newRouter(verifier *auth.Verifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me/", auth.AuthorizationMiddleware(verifier, noDenylist{}, nil), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("userID"))
+	})
+	return r
+}
+
+func status(r *gin.Engine, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/me/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(t, keys...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ks := auth.NewKeySet(logger.SetupLogger(logger.Prod, ""), path, time.Hour)
+	if err := ks.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	r := newRouter(auth.NewJWKSVerifier(ks, "wallet", "wallet-api", 0))
+
+	for _, k := range keys {
+		if code := status(r, accessToken(t, k, nil)); code != http.StatusOK {
+			t.Fatalf("%s: want the token accepted, got %d", k.kid, code)
+		}
+	}
+
+	rejected := map[string]string{
+		"wrong issuer":       accessToken(t, keys[0], func(c jwt.MapClaims) { c["iss"] = "someone" }),
+		"wrong audience":     accessToken(t, keys[0], func(c jwt.MapClaims) { c["aud"] = "other-api" }),
+		"not yet valid":      accessToken(t, keys[1], func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Minute).Unix() }),
+		"expired":            accessToken(t, keys[1], func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }),
+		"without exp":        accessToken(t, keys[2], func(c jwt.MapClaims) { delete(c, "exp") }),
+		"unknown kid":        accessToken(t, testKey{kid: "rsa-2", method: keys[0].method, private: keys[0].private}, nil),
+		"alg of another key": accessToken(t, testKey{kid: "ec-1", method: jwt.SigningMethodRS256, private: keys[0].private}, nil),
+	}
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id": userID, "typ": "access", "jti": "1", "iss": "wallet", "aud": "wallet-api",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected["HMAC"] = hmacToken
+	for name, token := range rejected {
+		if code := status(r, token); code != http.StatusUnauthorized {
+			t.Fatalf("%s: want 401, got %d", name, code)
+		}
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	keys := newTestKeys(t)
+	doc := jwksDocument(t, keys[0])
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(doc)
+	}))
+	defer srv.Close()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	overlapping := auth.NewKeySet(log, srv.URL, time.Hour)
+	strict := auth.NewKeySet(log, srv.URL, 0)
+	for _, ks := range []*auth.KeySet{overlapping, strict} {
+		if err := ks.Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldToken := accessToken(t, keys[0], nil)
+
+	// The old key is replaced by a new one.
+	doc = jwksDocument(t, keys[2])
+	for _, ks := range []*auth.KeySet{overlapping, strict} {
+		if err := ks.Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newToken := accessToken(t, keys[2], nil)
+	r := newRouter(auth.NewJWKSVerifier(overlapping, "wallet", "wallet-api", 0))
+	if status(r, oldToken) != http.StatusOK || status(r, newToken) != http.StatusOK {
+		t.Fatal("want both keys accepted during the overlap")
+	}
+	r = newRouter(auth.NewJWKSVerifier(strict, "wallet", "wallet-api", 0))
+	if status(r, oldToken) != http.StatusUnauthorized || status(r, newToken) != http.StatusOK {
+		t.Fatal("want only the new key accepted without an overlap")
+	}
+}
+
+func TestKeySignerSessions(t *testing.T) {
+	keys := newTestKeys(t)
+	der, err := x509.MarshalPKCS8PrivateKey(keys[1].private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := auth.NewKeySigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), keys[1].kid, "wallet", "wallet-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(path, jwksDocument(t, keys[1]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	log := logger.SetupLogger(logger.Prod, "")
+	ks := auth.NewKeySet(log, path, time.Hour)
+	if err = ks.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	verifier := auth.NewJWKSVerifier(ks, "wallet", "wallet-api", 0)
+	sessions := auth.NewSessions(log, newMemoryCache(), signer, verifier, auth.SessionsConfig{
+		LoginSecret: []byte(secret),
+		AccessTTL:   time.Minute,
+		RefreshTTL:  time.Hour,
+	})
+
+	tokens, err := sessions.Start(context.Background(), loginToken(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := status(newRouter(verifier), tokens.Token); code != http.StatusOK {
+		t.Fatalf("want the issued token accepted, got %d", code)
+	}
+	if _, err = sessions.Refresh(context.Background(), tokens.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wallet/internal/domain/auth"
+	"wallet/pkg/logger"
+)
+
+const adminID = "5d2f8b61-0c4e-4a97-b3d1-8e6f2a7c9b05"
+
+func TestRequireScope(t *testing.T) {
+	ctx := context.Background()
+	verifier := auth.NewHMACVerifier([]byte(secret), "wallet", "wallet-api", 0)
+	signer := auth.NewHMACSigner([]byte(secret), "wallet", "wallet-api")
+	cache := newMemoryCache()
+	cfg := auth.SessionsConfig{
+		LoginSecret: []byte(secret),
+		AccessTTL:   time.Minute,
+		RefreshTTL:  time.Hour,
+		Admins:      []string{adminID},
+	}
+	sessions := auth.NewSessions(logger.SetupLogger(logger.Prod, ""), cache, signer, verifier, cfg)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authorized := auth.AuthorizationMiddleware(verifier, sessions, nil)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/wallets/", authorized, auth.RequireScope(auth.ScopeWalletsRead), ok)
+	r.POST("/adjustments/", authorized, auth.RequireScope(auth.ScopeWalletsAdjust), ok)
+	r.POST("/exports/", authorized, auth.RequireScope("reports:export"), ok)
+	status := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	login := func(claims jwt.MapClaims) auth.TokenResponse {
+		res, err := sessions.Start(ctx, mustSign(t, claims))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	user := login(jwt.MapClaims{"id": userID})
+	if code := status(http.MethodGet, "/wallets/", user.Token); code != http.StatusForbidden {
+		t.Fatalf("want 403 for a user without roles, got %d", code)
+	}
+
+	support := login(jwt.MapClaims{"id": userID, "roles": []string{auth.RoleSupport}, "scope": "reports:export"})
+	if code := status(http.MethodGet, "/wallets/", support.Token); code != http.StatusOK {
+		t.Fatalf("want support to view wallets, got %d", code)
+	}
+	if code := status(http.MethodPost, "/adjustments/", support.Token); code != http.StatusForbidden {
+		t.Fatalf("want 403 for support adjusting balances, got %d", code)
+	}
+	if code := status(http.MethodPost, "/exports/", support.Token); code != http.StatusOK {
+		t.Fatalf("want the scope of the login token granted, got %d", code)
+	}
+	// The roles and the scope survive a refresh.
+	refreshed, err := sessions.Refresh(ctx, support.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := status(http.MethodPost, "/exports/", refreshed.Token); code != http.StatusOK {
+		t.Fatalf("want the scope kept after refresh, got %d", code)
+	}
+
+	admin := login(jwt.MapClaims{"id": adminID})
+	if code := status(http.MethodPost, "/adjustments/", admin.Token); code != http.StatusOK {
+		t.Fatalf("want a configured admin to adjust balances, got %d", code)
+	}
+	// The role is taken away on the next refresh after the admin is removed
+	// from the configuration.
+	cfg.Admins = nil
+	restarted := auth.NewSessions(logger.SetupLogger(logger.Prod, ""), cache, signer, verifier, cfg)
+	demoted, err := restarted.Refresh(ctx, admin.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := status(http.MethodPost, "/adjustments/", demoted.Token); code != http.StatusForbidden {
+		t.Fatalf("want 403 for a removed admin after refresh, got %d", code)
+	}
+	if code := status(http.MethodGet, "/wallets/", demoted.Token); code != http.StatusForbidden {
+		t.Fatalf("want the admin scopes gone after refresh, got %d", code)
+	}
+
+	if code := status(http.MethodGet, "/wallets/", ""); code != http.StatusUnauthorized {
+		t.Fatalf("want 401 without a token, got %d", code)
+	}
+	if _, err = sessions.Start(ctx, mustSign(t, jwt.MapClaims{"id": userID, "roles": "admin"})); err == nil {
+		t.Fatal("want roles that are not a list rejected")
+	}
+}
+
+func mustSign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
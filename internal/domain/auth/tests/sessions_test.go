@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain/auth"
+	"wallet/pkg/logger"
+)
+
+const (
+	secret = "test-secret"
+	userID = "0b7e3f52-9c1a-4d6e-8f20-5a3c7d9e1b46"
+)
+
+func newSessions(t *testing.T) (*auth.Sessions, *auth.Verifier) {
+	t.Helper()
+	verifier := auth.NewHMACVerifier([]byte(secret), "wallet", "wallet-api", 0)
+	signer := auth.NewHMACSigner([]byte(secret), "wallet", "wallet-api")
+	return auth.NewSessions(logger.SetupLogger(logger.Prod, ""), newMemoryCache(), signer, verifier, auth.SessionsConfig{
+		LoginSecret: []byte(secret),
+		AccessTTL:   time.Minute,
+		RefreshTTL:  time.Hour,
+	}), verifier
+}
+
+// loginToken is a token as given by the auth service.
+func loginToken(t *testing.T) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":  userID,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	sessions, _ := newSessions(t)
+	first, err := sessions.Start(ctx, loginToken(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ExpiresIn != 60 {
+		t.Fatalf("want the access token to expire in 60s, got %d", first.ExpiresIn)
+	}
+	if _, err = sessions.Refresh(ctx, first.Token); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatal("an access token must not be accepted as a refresh token")
+	}
+
+	second, err := sessions.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("want a new refresh token")
+	}
+	// Reusing a refresh token revokes the session, so the token given in
+	// exchange for it stops working too.
+	if _, err = sessions.Refresh(ctx, first.RefreshToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("want ErrInvalidToken on reuse, got %v", err)
+	}
+	if _, err = sessions.Refresh(ctx, second.RefreshToken); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("want the session revoked after reuse, got %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	sessions, verifier := newSessions(t)
+	tokens, err := sessions.Start(ctx, loginToken(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authorized := auth.AuthorizationMiddleware(verifier, sessions, nil)
+	r.GET("/me/", authorized, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("userID"))
+	})
+	r.POST("/logout/", authorized, auth.Logout(sessions))
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/me/", tokens.Token); w.Code != http.StatusOK || w.Body.String() != userID {
+		t.Fatalf("want the user id, got %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/me/", tokens.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Fatalf("a refresh token must not be accepted as an access token, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/me/", loginToken(t)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("the token of the auth service must not be accepted, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/logout/", tokens.Token); w.Code != http.StatusOK {
+		t.Fatalf("want logout to succeed, got %d %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/me/", tokens.Token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("want the token revoked after logout, got %d", w.Code)
+	}
+}
+
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string]string)}
+}
+
+func (c *memoryCache) GetValue(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key], nil
+}
+
+func (c *memoryCache) SetValue(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *memoryCache) DeleteValue(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *memoryCache) PopValue(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value := c.values[key]
+	delete(c.values, key)
+	return value, nil
+}
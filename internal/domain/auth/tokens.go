@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+// asymmetricMethods are the algorithms accepted with a JWKS.
+var asymmetricMethods = []string{"RS256", "ES256", "EdDSA"}
+
+// Signer signs the tokens issued by the service and sets their issuer and
+// audience.
+type Signer struct {
+	method   jwt.SigningMethod
+	key      interface{}
+	kid      string
+	issuer   string
+	audience string
+}
+
+// NewHMACSigner signs with the shared secret. Every holder of the secret can
+// mint tokens, so it is meant for setups without a JWKS.
+func NewHMACSigner(secret []byte, issuer, audience string) *Signer {
+	return &Signer{method: jwt.SigningMethodHS256, key: secret, issuer: issuer, audience: audience}
+}
+
+// NewKeySigner signs with the PEM encoded private key, whose public key must
+// be published in the JWKS under kid. RSA keys sign with RS256, P-256 keys
+// with ES256 and Ed25519 keys with EdDSA.
+func NewKeySigner(pemData []byte, kid, issuer, audience string) (*Signer, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Signer{key: key, kid: kid, issuer: issuer, audience: audience}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s.method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported")
+		}
+		s.method = jwt.SigningMethodES256
+	case ed25519.PrivateKey:
+		s.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return s, nil
+}
+
+func (s *Signer) sign(claims tokenClaims) (string, error) {
+	claims.Issuer = s.issuer
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.kid != "" {
+		token.Header["kid"] = s.kid
+	}
+	return token.SignedString(s.key)
+}
+
+// Verifier checks the signature of tokens and their iss, aud, exp and nbf
+// claims.
+type Verifier struct {
+	keys     *KeySet
+	secret   []byte
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// NewJWKSVerifier accepts RS256, ES256 and EdDSA tokens signed with a key of
+// the key set, selected by the kid header.
+func NewJWKSVerifier(keys *KeySet, issuer, audience string, leeway time.Duration) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer, audience: audience, leeway: leeway}
+}
+
+// NewHMACVerifier accepts HS256 tokens signed with the shared secret.
+func NewHMACVerifier(secret []byte, issuer, audience string, leeway time.Duration) *Verifier {
+	return &Verifier{secret: secret, issuer: issuer, audience: audience, leeway: leeway}
+}
+
+func (v *Verifier) parse(token string, claims jwt.Claims) error {
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithLeeway(v.leeway)}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+	if v.keys == nil {
+		opts = append(opts, jwt.WithValidMethods([]string{"HS256"}))
+		_, err := jwt.ParseWithClaims(token, claims, hmacKey(v.secret), opts...)
+		return err
+	}
+	opts = append(opts, jwt.WithValidMethods(asymmetricMethods))
+	_, err := jwt.ParseWithClaims(token, claims, v.key, opts...)
+	return err
+}
+
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrUnknownKey
+	}
+	k, err := v.keys.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if k.alg != "" && k.alg != token.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return k.key, nil
+}
+
+// parseToken checks a token issued by Sessions and its type.
+func parseToken(v *Verifier, token, tokenType string) (tokenClaims, error) {
+	var claims tokenClaims
+	if err := v.parse(token, &claims); err != nil || claims.Type != tokenType || claims.ID == "" || claims.UserID == "" {
+		return tokenClaims{}, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func hmacKey(secret []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		// Проверяем метод подписи токена
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return secret, nil
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+	"wallet/internal/domain/idempotency"
+)
+
+type Storage struct {
+	Client idempotency.PsqlClient
+	logger *slog.Logger
+}
+
+func NewRepository(client idempotency.PsqlClient, logger *slog.Logger) idempotency.Storage {
+	return &Storage{client, logger}
+}
+
+func (s *Storage) Reserve(ctx context.Context, r idempotency.Record, ttl, lockTimeout time.Duration) (idempotency.Record, bool, error) {
+	const op = "idempotency.db.Reserve"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `INSERT INTO idempotency_key(user_id, key, fingerprint)
+		  VALUES ($1, $2, $3)
+		  ON CONFLICT (user_id, key) DO UPDATE SET
+		          fingerprint = EXCLUDED.fingerprint,
+		          status_code = NULL,
+		          response_body = NULL,
+		          created_at = NOW()
+		  WHERE idempotency_key.created_at < NOW() - make_interval(secs => $4)
+		     OR (idempotency_key.status_code IS NULL
+		         AND idempotency_key.created_at < NOW() - make_interval(secs => $5))
+		  RETURNING key`
+
+	var key string
+	err := s.Client.QueryRow(ctx, q, r.UserID, r.Key, r.Fingerprint, ttl.Seconds(), lockTimeout.Seconds()).Scan(&key)
+	if err == nil {
+		return idempotency.Record{}, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Error(err.Error())
+		return idempotency.Record{}, false, err
+	}
+
+	q = `SELECT fingerprint,
+       			COALESCE(status_code, 0),
+       			COALESCE(response_body, ''::BYTEA)
+		 FROM idempotency_key WHERE user_id=$1 AND key=$2`
+
+	existing := idempotency.Record{UserID: r.UserID, Key: r.Key}
+	err = s.Client.QueryRow(ctx, q, r.UserID, r.Key).Scan(&existing.Fingerprint, &existing.StatusCode, &existing.Body)
+	if err != nil {
+		log.Error(err.Error())
+		return idempotency.Record{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (s *Storage) Complete(ctx context.Context, r idempotency.Record) error {
+	const op = "idempotency.db.Complete"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `UPDATE idempotency_key SET
+                  status_code = $1,
+                  response_body = $2
+		  WHERE user_id=$3 AND key=$4`
+
+	_, err := s.Client.Exec(ctx, q, r.StatusCode, r.Body, r.UserID, r.Key)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) Release(ctx context.Context, userID, key string) error {
+	const op = "idempotency.db.Release"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `DELETE FROM idempotency_key WHERE user_id=$1 AND key=$2 AND status_code IS NULL`
+	_, err := s.Client.Exec(ctx, q, userID, key)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) DeleteExpired(ctx context.Context, ttl time.Duration) (int64, error) {
+	const op = "idempotency.db.DeleteExpired"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `DELETE FROM idempotency_key WHERE created_at < NOW() - make_interval(secs => $1)`
+	tag, err := s.Client.Exec(ctx, q, ttl.Seconds())
+	if err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
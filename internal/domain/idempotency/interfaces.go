@@ -0,0 +1,25 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+type PsqlClient interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type Storage interface {
+	// Reserve claims the key for a new request. If the key is already taken
+	// by a live record, that record is returned with ok set to false. Records
+	// older than ttl, and unfinished ones older than lockTimeout, are replaced.
+	Reserve(ctx context.Context, r Record, ttl, lockTimeout time.Duration) (existing Record, ok bool, err error)
+	Complete(ctx context.Context, r Record) error
+	Release(ctx context.Context, userID, key string) error
+	// DeleteExpired deletes the records older than ttl and returns their
+	// number.
+	DeleteExpired(ctx context.Context, ttl time.Duration) (int64, error)
+}
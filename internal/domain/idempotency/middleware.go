@@ -0,0 +1,143 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// keyTTL is how long a completed response is replayed for a key.
+	keyTTL = 24 * time.Hour
+	// lockTimeout is how long an unfinished request keeps its key; after that
+	// the request is assumed to have died and the key may be used again.
+	lockTimeout = time.Minute
+)
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Middleware makes a handler idempotent for requests carrying an
+// Idempotency-Key header. The first response for a key is stored and replayed
+// for later requests with the same key and body; reusing the key with a
+// different request is rejected. Keys are scoped to the authenticated user, so
+// the middleware must run after auth.AuthorizationMiddleware. Requests without
+// the header are passed through unchanged.
+func Middleware(s Storage, logger *slog.Logger) gin.HandlerFunc {
+	const op = "idempotency.Middleware"
+	log := logger.With(slog.String("op", op))
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+		userID, ok := c.Get("userID")
+		userIDStr, isStr := userID.(string)
+		if !ok || !isStr {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The path, not the route, is fingerprinted: the same body sent to
+		// another resource is another request.
+		record := Record{
+			UserID:      userIDStr,
+			Key:         key,
+			Fingerprint: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+		}
+		existing, ok, err := s.Reserve(context.Background(), record, keyTTL, lockTimeout)
+		if err != nil {
+			log.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "something went wrong"})
+			c.Abort()
+			return
+		}
+		if !ok {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case !existing.Completed():
+				c.JSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header(HeaderReplayed, "true")
+				c.Data(existing.StatusCode, "application/json; charset=utf-8", existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// A 500 may come after the operation was committed, so it is replayed
+		// like any other response rather than letting a retry apply the
+		// operation twice. Handlers answer 503 only when nothing was done,
+		// that and a response the handler didn't write free the key instead.
+		if c.Writer.Status() == http.StatusServiceUnavailable || !c.Writer.Written() {
+			if err = s.Release(context.Background(), record.UserID, record.Key); err != nil {
+				log.Error(err.Error())
+			}
+			return
+		}
+		record.StatusCode = c.Writer.Status()
+		record.Body = recorder.body.Bytes()
+		if err = s.Complete(context.Background(), record); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
+
+// PurgeExpired deletes the keys older than keyTTL, whose responses are no
+// longer replayed.
+func PurgeExpired(ctx context.Context, s Storage) (int64, error) {
+	return s.DeleteExpired(ctx, keyTTL)
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,15 @@
+package idempotency
+
+// Record is a stored idempotency key. A record without a status code belongs
+// to a request that is still being processed.
+type Record struct {
+	UserID      string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Body        []byte
+}
+
+func (r Record) Completed() bool {
+	return r.StatusCode != 0
+}
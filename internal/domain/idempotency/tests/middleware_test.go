@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain/idempotency"
+	"wallet/pkg/logger"
+)
+
+const userID = "7a1d3c9e-4b2f-4e86-9c05-1f8e6d2b3a74"
+
+// memoryStorage keeps the keys in memory in place of Postgres.
+type memoryStorage struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+	created map[string]time.Time
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{records: make(map[string]idempotency.Record), created: make(map[string]time.Time)}
+}
+
+func (m *memoryStorage) Reserve(_ context.Context, r idempotency.Record, ttl, _ time.Duration) (idempotency.Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := r.UserID + r.Key
+	if existing, ok := m.records[k]; ok && time.Since(m.created[k]) < ttl {
+		return existing, false, nil
+	}
+	m.records[k] = r
+	m.created[k] = time.Now()
+	return idempotency.Record{}, true, nil
+}
+
+func (m *memoryStorage) Complete(_ context.Context, r idempotency.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[r.UserID+r.Key] = r
+	return nil
+}
+
+func (m *memoryStorage) Release(_ context.Context, userID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.records[userID+key].Completed() {
+		delete(m.records, userID+key)
+	}
+	return nil
+}
+
+func (m *memoryStorage) DeleteExpired(_ context.Context, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for k, at := range m.created {
+		if time.Since(at) >= ttl {
+			delete(m.records, k)
+			delete(m.created, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	storage := newMemoryStorage()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	setUser := func(c *gin.Context) { c.Set("userID", userID) }
+	calls := 0
+	r.POST("/deposit/", setUser, idempotency.Middleware(storage, logger.SetupLogger(logger.Prod, "")), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+	// silent stands for a handler missing an error mapping.
+	r.POST("/silent/", setUser, idempotency.Middleware(storage, logger.SetupLogger(logger.Prod, "")), func(c *gin.Context) {
+		calls++
+	})
+	// failing stands for a handler failing after the operation was committed.
+	r.POST("/failing/", setUser, idempotency.Middleware(storage, logger.SetupLogger(logger.Prod, "")), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "something went wrong"})
+	})
+	r.POST("/unavailable/", setUser, idempotency.Middleware(storage, logger.SetupLogger(logger.Prod, "")), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "exchange rates are unavailable"})
+	})
+	r.POST("/admin/wallets/:user_id/adjustments/", setUser, idempotency.Middleware(storage, logger.SetupLogger(logger.Prod, "")), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"user_id": c.Param("user_id")})
+	})
+	request := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(idempotency.HeaderKey, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := request("/deposit/", "a", `{"amount":"10"}`)
+	replay := request("/deposit/", "a", `{"amount":"10"}`)
+	if calls != 1 || replay.Body.String() != first.Body.String() || replay.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Fatalf("want the first response replayed, got %d calls and %q", calls, replay.Body.String())
+	}
+	if w := request("/deposit/", "a", `{"amount":"20"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("want 422 for a key reused with another body, got %d", w.Code)
+	}
+
+	request("/silent/", "b", `{}`)
+	request("/silent/", "b", `{}`)
+	if calls != 3 {
+		t.Fatalf("want an empty response not replayed, got %d calls", calls)
+	}
+
+	request("/failing/", "c", `{}`)
+	if w := request("/failing/", "c", `{}`); calls != 4 || w.Code != http.StatusInternalServerError ||
+		w.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Fatalf("want a 500 replayed, got %d calls and %d", calls, w.Code)
+	}
+	request("/unavailable/", "d", `{}`)
+	request("/unavailable/", "d", `{}`)
+	if calls != 6 {
+		t.Fatalf("want a 503 not replayed, got %d calls", calls)
+	}
+
+	// The route is the same for every user, the path is not.
+	adjust := `{"amount":"10","currency":"USD","reason":"refund"}`
+	request("/admin/wallets/1/adjustments/", "e", adjust)
+	if w := request("/admin/wallets/2/adjustments/", "e", adjust); calls != 7 || w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("want 422 for a key reused for another user, got %d calls and %d", calls, w.Code)
+	}
+
+	n, err := idempotency.PurgeExpired(context.Background(), storage)
+	if err != nil || n != 0 {
+		t.Fatalf("want no fresh key purged, got %d %v", n, err)
+	}
+}
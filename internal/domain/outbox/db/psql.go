@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"wallet/internal/domain/outbox"
+)
+
+type Storage struct {
+	Client outbox.PsqlClient
+	logger *slog.Logger
+}
+
+func NewRepository(client outbox.PsqlClient, logger *slog.Logger) outbox.Storage {
+	return &Storage{client, logger}
+}
+
+func (s *Storage) WithinTransaction(ctx context.Context, fn func(outbox.Storage) error) error {
+	const op = "outbox.db.WithinTransaction"
+	log := s.logger.With(slog.String("op", op))
+
+	tx, err := s.Client.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = fn(&Storage{Client: tx, logger: s.logger}); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) GetPendingEvents(ctx context.Context, limit int) ([]outbox.Event, error) {
+	const op = "outbox.db.GetPendingEvents"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT id, type, aggregate_id, payload, created_at
+		  FROM outbox_event
+		  WHERE published_at IS NULL
+		  ORDER BY seq
+		  LIMIT $1
+		  FOR UPDATE SKIP LOCKED`
+
+	rows, err := s.Client.Query(ctx, q, limit)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]outbox.Event, 0, limit)
+	for rows.Next() {
+		var e outbox.Event
+		if err = rows.Scan(&e.ID, &e.Type, &e.AggregateID, &e.Payload, &e.CreatedAt); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *Storage) MarkPublished(ctx context.Context, ids []string) error {
+	const op = "outbox.db.MarkPublished"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `UPDATE outbox_event SET published_at = NOW() WHERE id = ANY($1::UUID[])`
+	if _, err := s.Client.Exec(ctx, q, ids); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
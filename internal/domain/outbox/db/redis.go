@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"time"
+	"wallet/internal/domain/outbox"
+)
+
+// streamMaxLen caps the stream, Redis trims the oldest entries beyond it.
+const streamMaxLen = 100000
+
+// RedisPublisher appends events to a Redis stream, one entry per event.
+type RedisPublisher struct {
+	logger *slog.Logger
+	Client *redis.Client
+	stream string
+}
+
+func NewRedisPublisher(logger *slog.Logger, c *redis.Client, stream string) outbox.EventPublisher {
+	return &RedisPublisher{
+		logger: logger,
+		Client: c,
+		stream: stream,
+	}
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, e outbox.Event) error {
+	const op = "outbox.db.redis.Publish"
+	log := p.logger.With(slog.String("op", op))
+
+	err := p.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":           e.ID,
+			"type":         e.Type,
+			"aggregate_id": e.AggregateID,
+			"payload":      string(e.Payload),
+			"created_at":   e.CreatedAt.Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
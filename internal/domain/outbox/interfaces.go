@@ -0,0 +1,29 @@
+package outbox
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PsqlClient interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// EventPublisher delivers events to other services.
+type EventPublisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+type Storage interface {
+	// WithinTransaction runs fn against a storage bound to a single database
+	// transaction, committing it if fn returns nil and rolling it back otherwise.
+	WithinTransaction(ctx context.Context, fn func(Storage) error) error
+	// GetPendingEvents returns up to limit unpublished events in the order
+	// they were written and locks them until the end of the surrounding
+	// transaction. Events locked by another relay are skipped.
+	GetPendingEvents(ctx context.Context, limit int) ([]Event, error)
+	MarkPublished(ctx context.Context, ids []string) error
+}
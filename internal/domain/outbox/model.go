@@ -0,0 +1,28 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is a domain event written to the outbox in the same database
+// transaction as the change it describes. AggregateID is the id of the wallet
+// the event belongs to. Events may be published more than once, consumers
+// should use ID to drop duplicates.
+type Event struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// NewEvent builds an event with the payload encoded as JSON. The id and the
+// creation time are assigned when the event is stored.
+func NewEvent(eventType, aggregateID string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, AggregateID: aggregateID, Payload: data}, nil
+}
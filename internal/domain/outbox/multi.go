@@ -0,0 +1,21 @@
+package outbox
+
+import "context"
+
+type multiPublisher []EventPublisher
+
+// MultiPublisher publishes every event to all of the publishers in turn. If
+// one of them fails the event stays in the outbox and is published to all of
+// them again, so every publisher must tolerate duplicates.
+func MultiPublisher(publishers ...EventPublisher) EventPublisher {
+	return multiPublisher(publishers)
+}
+
+func (m multiPublisher) Publish(ctx context.Context, e Event) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
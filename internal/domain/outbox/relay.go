@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Relay moves events from the outbox to the publisher.
+type Relay struct {
+	storage   Storage
+	publisher EventPublisher
+	logger    *slog.Logger
+	batchSize int
+}
+
+func NewRelay(storage Storage, publisher EventPublisher, logger *slog.Logger, batchSize int) *Relay {
+	return &Relay{
+		storage:   storage,
+		publisher: publisher,
+		logger:    logger,
+		batchSize: batchSize,
+	}
+}
+
+// PublishPending publishes a batch of pending events in the order they were
+// written and returns how many were published. It stops at the first event
+// the publisher fails on, so that later events don't overtake it; the events
+// published before that are marked as such and the rest are retried on the
+// next call.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	const op = "outbox.PublishPending"
+	log := r.logger.With(slog.String("op", op))
+
+	var published []string
+	var publishErr error
+	err := r.storage.WithinTransaction(ctx, func(st Storage) error {
+		events, err := st.GetPendingEvents(ctx, r.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if publishErr = r.publisher.Publish(ctx, e); publishErr != nil {
+				log.Error(publishErr.Error(), slog.String("event_id", e.ID))
+				break
+			}
+			published = append(published, e.ID)
+		}
+		if len(published) == 0 {
+			return nil
+		}
+		return st.MarkPublished(ctx, published)
+	})
+	if err != nil {
+		// Events published before the failure will be published again.
+		return 0, err
+	}
+	return len(published), publishErr
+}
+
+// Drain publishes pending events batch after batch until the outbox is empty
+// or publishing fails.
+func (r *Relay) Drain(ctx context.Context) error {
+	for {
+		n, err := r.PublishPending(ctx)
+		if err != nil {
+			return err
+		}
+		if n < r.batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+)
+
+const (
+	// balanceChannelPrefix is followed by the user id in the names of the
+	// channels of balance updates.
+	balanceChannelPrefix = "balance_updates:"
+	ratesChannel         = "exchange_rates"
+)
+
+func balanceChannel(userID string) string {
+	return balanceChannelPrefix + userID
+}
+
+// Broker publishes messages to Redis and passes the messages published by
+// all replicas to the local hub.
+type Broker struct {
+	logger *slog.Logger
+	Client *redis.Client
+	hub    *Hub
+}
+
+func NewBroker(logger *slog.Logger, c *redis.Client, hub *Hub) *Broker {
+	return &Broker{
+		logger: logger,
+		Client: c,
+		hub:    hub,
+	}
+}
+
+// Publish sends v encoded as JSON to the clients of the user.
+func (b *Broker) Publish(ctx context.Context, userID string, v interface{}) error {
+	const op = "realtime.Publish"
+	log := b.logger.With(slog.String("op", op))
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if err = b.Client.Publish(ctx, balanceChannel(userID), data).Err(); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// PublishRates sends the refreshed exchange rates encoded as JSON to the
+// clients streaming rates.
+func (b *Broker) PublishRates(ctx context.Context, v interface{}) error {
+	const op = "realtime.PublishRates"
+	log := b.logger.With(slog.String("op", op))
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if err = b.Client.Publish(ctx, ratesChannel, data).Err(); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// Run listens to the channels of all users and to the rates channel until
+// ctx is done. The Redis client reconnects by itself if the connection
+// breaks; messages published in the meantime are lost.
+func (b *Broker) Run(ctx context.Context) {
+	const op = "realtime.Run"
+	log := b.logger.With(slog.String("op", op))
+
+	sub := b.Client.PSubscribe(ctx, balanceChannelPrefix+"*", ratesChannel)
+	defer func() {
+		if err := sub.Close(); err != nil {
+			log.Error(err.Error())
+		}
+	}()
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			b.hub.Dispatch(msg.Channel, []byte(msg.Payload))
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"wallet/internal/domain/wallet"
+)
+
+// keepAliveInterval is how often a comment is sent on an idle stream, so
+// that proxies don't close the connection.
+const keepAliveInterval = 15 * time.Second
+
+type BalanceGetter interface {
+	GetBalance(ctx context.Context, userID string) (wallet.Wallet, error)
+}
+
+type RatesGetter interface {
+	GetExchangeRates(ctx context.Context) (wallet.ExchangeRateResponse, error)
+}
+
+// RatesUpdate is the payload of the "rates" event. Rates are keyed by pair,
+// e.g. "USD/EUR", and given in the form of the exchange endpoint.
+type RatesUpdate struct {
+	Rates     map[string]float32 `json:"rates"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// StreamBalanceHandler godoc
+// @Summary      Stream balance updates
+// @Description  Server-Sent Events stream of the user's balance. The first "balance" event carries the current balances, every following one the new balances and the transactions of the operation that changed them. Updates not read in time are dropped, reconnecting clients get the current balance again.
+// @Tags         wallet
+// @Produce      text/event-stream
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      200  {object}  wallet.BalanceUpdate
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/wallet/stream/ [get]
+func StreamBalanceHandler(hub *Hub, s BalanceGetter) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		userIDStr, ok := userID.(string)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": wallet.ErrSmtWentWrong.Error()})
+			return
+		}
+
+		// Subscribing first makes sure no update is missed between reading
+		// the balance and listening for changes.
+		updates, unsubscribe := hub.Subscribe(balanceChannel(userIDStr))
+		defer unsubscribe()
+		w, err := s.GetBalance(c.Request.Context(), userIDStr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": wallet.ErrSmtWentWrong.Error()})
+			return
+		}
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.SSEvent("balance", wallet.BalanceUpdate{Balances: w.Balances})
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+		c.Stream(func(out io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case msg := <-updates:
+				c.SSEvent("balance", string(msg))
+			case <-keepAlive.C:
+				_, _ = io.WriteString(out, ": keep-alive\n\n")
+			}
+			return true
+		})
+	}
+}
+
+// StreamRatesHandler godoc
+// @Summary      Stream exchange rates
+// @Description  Server-Sent Events stream of exchange rates. The first "rates" event carries the current rates, every following one is sent when the rates are refreshed from the exchanger and at least one of the subscribed pairs changed. Without pairs all pairs of the known currencies are streamed.
+// @Tags         exchange
+// @Produce      text/event-stream
+// @Param        Authorization  header    string  true   "Bearer Token"  default(Bearer <token>)
+// @Param        pairs          query     string  false  "Comma separated currency pairs"  example(USD/EUR,EUR/RUB)
+// @Success      200  {object}  RatesUpdate
+// @Failure      400  {object}  map[string]string  "invalid currency pair"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/exchange/rates/stream/ [get]
+func StreamRatesHandler(hub *Hub, s RatesGetter) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		updates, unsubscribe := hub.Subscribe(ratesChannel)
+		defer unsubscribe()
+		current, err := s.GetExchangeRates(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": wallet.ErrSmtWentWrong.Error()})
+			return
+		}
+
+		var pairs [][2]string
+		if q := c.Query("pairs"); q != "" {
+			for _, p := range strings.Split(q, ",") {
+				from, to, ok := wallet.ParsePair(p)
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid currency pair " + p})
+					return
+				}
+				if _, ok = wallet.PairRate(current.Rates, from, to); !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "unknown currency pair " + p})
+					return
+				}
+				pairs = append(pairs, [2]string{from, to})
+			}
+		} else {
+			pairs = allPairs(current.Rates)
+		}
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		last := pairRates(current.Rates, pairs)
+		c.SSEvent("rates", RatesUpdate{Rates: last, UpdatedAt: time.Now().UTC()})
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+		c.Stream(func(out io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case msg := <-updates:
+				var res wallet.ExchangeRateResponse
+				if err := json.Unmarshal(msg, &res); err != nil {
+					return true
+				}
+				rates := pairRates(res.Rates, pairs)
+				if !changed(last, rates) {
+					return true
+				}
+				last = rates
+				c.SSEvent("rates", RatesUpdate{Rates: rates, UpdatedAt: time.Now().UTC()})
+			case <-keepAlive.C:
+				_, _ = io.WriteString(out, ": keep-alive\n\n")
+			}
+			return true
+		})
+	}
+}
+
+// allPairs returns every ordered pair of the currencies of rates.
+func allPairs(rates map[string]float32) [][2]string {
+	codes := make([]string, 0, len(rates))
+	for code := range rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	var pairs [][2]string
+	for _, from := range codes {
+		for _, to := range codes {
+			if from != to {
+				pairs = append(pairs, [2]string{from, to})
+			}
+		}
+	}
+	return pairs
+}
+
+// pairRates returns the rates of the pairs; pairs missing from rates are
+// left out.
+func pairRates(rates map[string]float32, pairs [][2]string) map[string]float32 {
+	res := make(map[string]float32, len(pairs))
+	for _, p := range pairs {
+		if rate, ok := wallet.PairRate(rates, p[0], p[1]); ok {
+			res[p[0]+"/"+p[1]] = rate
+		}
+	}
+	return res
+}
+
+func changed(prev, next map[string]float32) bool {
+	if len(prev) != len(next) {
+		return true
+	}
+	for pair, rate := range next {
+		if prevRate, ok := prev[pair]; !ok || prevRate != rate {
+			return true
+		}
+	}
+	return false
+}
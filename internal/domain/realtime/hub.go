@@ -0,0 +1,55 @@
+// Package realtime streams balance and exchange rate updates to connected
+// clients. Updates are published to Redis, so they reach clients connected to
+// any replica of the service.
+package realtime
+
+import "sync"
+
+// subscriberBuffer is the number of messages queued for a client. Messages
+// for a client that doesn't keep up are dropped.
+const subscriberBuffer = 16
+
+// Hub fans messages out to the clients connected to this replica. Clients
+// subscribe to topics, which are the names of the Redis channels.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan []byte]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[chan []byte]struct{})}
+}
+
+// Subscribe returns a channel receiving the messages of the topic and a
+// function that stops the subscription.
+func (h *Hub) Subscribe(topic string) (<-chan []byte, func()) {
+	ch := make(chan []byte, subscriberBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[chan []byte]struct{})
+	}
+	h.subscribers[topic][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[topic], ch)
+		if len(h.subscribers[topic]) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+}
+
+// Dispatch passes the message to every subscriber of the topic without
+// waiting for slow ones.
+func (h *Hub) Dispatch(topic string, msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[topic] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"testing"
+	"wallet/internal/domain/realtime"
+)
+
+func TestHub(t *testing.T) {
+	hub := realtime.NewHub()
+	first, unsubscribeFirst := hub.Subscribe("alice")
+	second, unsubscribeSecond := hub.Subscribe("alice")
+	other, unsubscribeOther := hub.Subscribe("bob")
+	defer unsubscribeSecond()
+	defer unsubscribeOther()
+
+	hub.Dispatch("alice", []byte("1"))
+	for _, ch := range []<-chan []byte{first, second} {
+		select {
+		case msg := <-ch:
+			if string(msg) != "1" {
+				t.Fatalf("want message 1, got %s", msg)
+			}
+		default:
+			t.Fatal("message wasn't delivered to every subscriber")
+		}
+	}
+	select {
+	case msg := <-other:
+		t.Fatalf("message %s was delivered to another topic", msg)
+	default:
+	}
+
+	unsubscribeFirst()
+	hub.Dispatch("alice", []byte("2"))
+	select {
+	case msg := <-first:
+		t.Fatalf("message %s was delivered after unsubscribing", msg)
+	default:
+	}
+
+	// A subscriber that doesn't read must not block the others.
+	for i := 0; i < 100; i++ {
+		hub.Dispatch("alice", []byte("3"))
+	}
+	hub.Dispatch("bob", []byte("4"))
+	if msg := <-other; string(msg) != "4" {
+		t.Fatalf("want message 4, got %s", msg)
+	}
+}
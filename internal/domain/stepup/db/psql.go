@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"wallet/internal/domain/stepup"
+)
+
+type Storage struct {
+	Client stepup.PsqlClient
+	logger *slog.Logger
+}
+
+func NewRepository(client stepup.PsqlClient, logger *slog.Logger) stepup.Storage {
+	return &Storage{client, logger}
+}
+
+func (s *Storage) SaveTOTP(ctx context.Context, t stepup.TOTP) error {
+	const op = "stepup.db.SaveTOTP"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `INSERT INTO totp_secret(user_id, secret)
+		  VALUES ($1, $2)
+		  ON CONFLICT (user_id) DO UPDATE
+		  SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+		  WHERE totp_secret.confirmed_at IS NULL`
+	tag, err := s.Client.Exec(ctx, q, t.UserID, t.Secret)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return stepup.ErrTOTPEnrolled
+	}
+	return nil
+}
+
+func (s *Storage) GetTOTP(ctx context.Context, userID string) (stepup.TOTP, error) {
+	const op = "stepup.db.GetTOTP"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT user_id, secret, last_step, confirmed_at, created_at
+		  FROM totp_secret
+		  WHERE user_id=$1`
+	var t stepup.TOTP
+	err := s.Client.QueryRow(ctx, q, userID).Scan(&t.UserID, &t.Secret, &t.LastStep, &t.ConfirmedAt, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, stepup.ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return t, err
+	}
+	return t, nil
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	const op = "stepup.db.UseTOTPStep"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `UPDATE totp_secret
+		  SET last_step = $2, confirmed_at = COALESCE(confirmed_at, NOW())
+		  WHERE user_id=$1 AND last_step < $2`
+	tag, err := s.Client.Exec(ctx, q, userID, step)
+	if err != nil {
+		log.Error(err.Error())
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Storage) DeleteTOTP(ctx context.Context, userID string) error {
+	const op = "stepup.db.DeleteTOTP"
+	log := s.logger.With(slog.String("op", op))
+
+	_, err := s.Client.Exec(ctx, `DELETE FROM totp_secret WHERE user_id=$1`, userID)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
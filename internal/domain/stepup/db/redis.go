@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"time"
+	"wallet/internal/domain/stepup"
+)
+
+// streamMaxLen caps the stream, Redis trims the oldest entries beyond it.
+const streamMaxLen = 10000
+
+// StreamNotifier hands one-time codes and security notices over to the
+// notification service through a Redis stream, which delivers them by email
+// or SMS.
+type StreamNotifier struct {
+	logger *slog.Logger
+	Client *redis.Client
+	stream string
+}
+
+func NewStreamNotifier(logger *slog.Logger, c *redis.Client, stream string) stepup.Notifier {
+	return &StreamNotifier{
+		logger: logger,
+		Client: c,
+		stream: stream,
+	}
+}
+
+func (n *StreamNotifier) SendCode(ctx context.Context, userID, code string, expiresAt time.Time) error {
+	const op = "stepup.db.redis.SendCode"
+	log := n.logger.With(slog.String("op", op))
+
+	err := n.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: n.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":       "step_up_code",
+			"user_id":    userID,
+			"code":       code,
+			"expires_at": expiresAt.Format(time.RFC3339),
+		},
+	}).Err()
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (n *StreamNotifier) NotifyTOTPEnrolled(ctx context.Context, userID string) error {
+	const op = "stepup.db.redis.NotifyTOTPEnrolled"
+	log := n.logger.With(slog.String("op", op))
+
+	err := n.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: n.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":    "totp_enrolled",
+			"user_id": userID,
+		},
+	}).Err()
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package stepup
+
+type CodeRequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6" example:"123456"`
+}
+
+type ConfirmTOTPRequest struct {
+	ChallengeID string `json:"challenge_id" validate:"required,uuid"`
+	// OTPCode is the one-time code sent for the challenge of the enrolment.
+	OTPCode string `json:"otp_code" validate:"required,numeric,len=6" example:"654321"`
+	Code    string `json:"code" validate:"required,numeric,len=6" example:"123456"`
+}
@@ -0,0 +1,9 @@
+package stepup
+
+import "errors"
+
+var ErrSmtWentWrong = errors.New("something went wrong")
+var ErrTOTPNotEnrolled = errors.New("totp is not enrolled")
+var ErrTOTPEnrolled = errors.New("totp is already enrolled")
+var ErrInvalidCode = errors.New("invalid confirmation code")
+var ErrChallengeNotFound = errors.New("challenge has expired or was already used")
@@ -0,0 +1,171 @@
+package stepup
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+// EnrolTOTPHandler godoc
+// @Summary      Enrol authenticator app
+// @Description  Generate a TOTP secret (RFC 6238) to add to an authenticator app. Large withdrawals are confirmed with its codes once the first code is confirmed along with the one-time code sent for the returned challenge. Enrolling again replaces an unconfirmed secret.
+// @Tags         step-up
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      201  {object}  Enrolment
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      409  {object}  map[string]string  "totp is already enrolled"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/step-up/totp/ [post]
+func EnrolTOTPHandler(s Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		e, err := s.EnrolTOTP(context.Background(), userID)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, e)
+	}
+}
+
+// ConfirmTOTPHandler godoc
+// @Summary      Confirm authenticator app
+// @Description  Complete the enrolment with the current code of the authenticator app and the one-time code sent for the challenge of the enrolment. The user is notified of the new authenticator app.
+// @Tags         step-up
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      ConfirmTOTPRequest  true  "Challenge, one-time code and TOTP code"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid code"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      404      {object}  map[string]string       "totp is not enrolled"
+// @Failure      409      {object}  map[string]string       "totp is already enrolled"
+// @Failure      410      {object}  map[string]string       "challenge has expired or was already used"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/step-up/totp/confirm/ [post]
+func ConfirmTOTPHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req ConfirmTOTPRequest
+		if !bindRequest(c, v, &req) {
+			return
+		}
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		if err := s.ConfirmTOTP(context.Background(), userID, req.ChallengeID, req.OTPCode, req.Code); err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Authenticator app enrolled"})
+	}
+}
+
+// DisableTOTPHandler godoc
+// @Summary      Remove authenticator app
+// @Description  Remove the authenticator app given its current code. Large withdrawals are then confirmed with one-time codes.
+// @Tags         step-up
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      CodeRequest  true  "TOTP code"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid code"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      404      {object}  map[string]string       "totp is not enrolled"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/step-up/totp/ [delete]
+func DisableTOTPHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		code, userID, ok := bindCode(c, v)
+		if !ok {
+			return
+		}
+		if err := s.DisableTOTP(context.Background(), userID, code); err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Authenticator app removed"})
+	}
+}
+
+// bindCode reads the code of the request and the authenticated user,
+// responding with an error if either is missing.
+func bindCode(c *gin.Context, v *validator.Validate) (string, string, bool) {
+	var req CodeRequest
+	if !bindRequest(c, v, &req) {
+		return "", "", false
+	}
+	userID, ok := currentUser(c)
+	if !ok {
+		return "", "", false
+	}
+	return req.Code, userID, true
+}
+
+// bindRequest reads and validates the body of the request into req,
+// responding with an error if it is invalid.
+func bindRequest(c *gin.Context, v *validator.Validate, req interface{}) bool {
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return false
+	}
+
+	if err := v.Struct(req); err != nil {
+		var validationErrors validator.ValidationErrors
+		errors.As(err, &validationErrors)
+		invalidFields := make([]string, len(validationErrors))
+
+		for i, fieldError := range validationErrors {
+			invalidFields[i] = fieldError.Field()
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Validation failed",
+			"fields": invalidFields,
+		})
+		return false
+	}
+	return true
+}
+
+// currentUser returns the id of the authenticated user, responding with an
+// error if there is none.
+func currentUser(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return "", false
+	}
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+		return "", false
+	}
+	return userIDStr, true
+}
+
+func writeJSONError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTOTPNotEnrolled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTOTPEnrolled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrChallengeNotFound):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+	}
+}
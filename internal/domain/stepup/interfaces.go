@@ -0,0 +1,64 @@
+package stepup
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+type PsqlClient interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type Storage interface {
+	// SaveTOTP replaces the unconfirmed TOTP of the user. It returns
+	// ErrTOTPEnrolled if the user has a confirmed one.
+	SaveTOTP(ctx context.Context, t TOTP) error
+	// GetTOTP returns ErrTOTPNotEnrolled if the user has no TOTP.
+	GetTOTP(ctx context.Context, userID string) (TOTP, error)
+	// UseTOTPStep records the time step of an accepted code and confirms the
+	// TOTP. It returns false if a code of the step or of a later one was
+	// accepted already, so every code is accepted once.
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID string) error
+}
+
+type Cache interface {
+	GetValue(ctx context.Context, key string) (string, error)
+	SetValue(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	DeleteValue(ctx context.Context, key string) error
+	// PopValue returns the value and deletes the key in one step, so only
+	// one of concurrent callers gets it. A missing key yields "".
+	PopValue(ctx context.Context, key string) (string, error)
+}
+
+// Notifier delivers one-time codes to users, e.g. by email or SMS.
+type Notifier interface {
+	SendCode(ctx context.Context, userID, code string, expiresAt time.Time) error
+	// NotifyTOTPEnrolled tells the user over the same channel that an
+	// authenticator app now confirms their operations.
+	NotifyTOTPEnrolled(ctx context.Context, userID string) error
+}
+
+type Service interface {
+	// EnrolTOTP starts the enrolment of an authenticator app, replacing an
+	// unconfirmed one, and sends a one-time code to the user. The TOTP is
+	// used once ConfirmTOTP accepts its code along with the one-time code.
+	EnrolTOTP(ctx context.Context, userID string) (Enrolment, error)
+	// ConfirmTOTP confirms the TOTP given the one-time code of the
+	// enrolment challenge and a code of the TOTP, and notifies the user.
+	ConfirmTOTP(ctx context.Context, userID, challengeID, otpCode, code string) error
+	// DisableTOTP removes the TOTP of the user, given one of its codes.
+	DisableTOTP(ctx context.Context, userID, code string) error
+	// CreateChallenge asks the user to confirm an operation. Without a
+	// method, TOTP is used if the user has enrolled it and a one-time code
+	// otherwise.
+	CreateChallenge(ctx context.Context, userID string, method Method) (Challenge, error)
+	// VerifyChallenge consumes the challenge if the code answers it. It
+	// returns ErrChallengeNotFound if the challenge has expired, was used or
+	// got too many wrong codes.
+	VerifyChallenge(ctx context.Context, userID, challengeID, code string) error
+}
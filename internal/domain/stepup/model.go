@@ -0,0 +1,42 @@
+package stepup
+
+import "time"
+
+// Method is the way the user answers a challenge.
+type Method string
+
+const (
+	// MethodTOTP is a code of an authenticator app enrolled with EnrolTOTP.
+	MethodTOTP Method = "totp"
+	// MethodOTP is a one-time code sent to the user by the Notifier.
+	MethodOTP Method = "otp"
+)
+
+// Challenge asks the user to confirm an operation with a code until
+// ExpiresAt.
+type Challenge struct {
+	ID        string    `json:"challenge_id"`
+	Method    Method    `json:"method" swaggertype:"string" enums:"totp,otp"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TOTP is the authenticator app of a user. It answers challenges only once
+// it is confirmed with a first code.
+type TOTP struct {
+	UserID string
+	Secret []byte
+	// LastStep is the time step of the last accepted code, codes of this
+	// step and earlier ones are rejected.
+	LastStep    int64
+	ConfirmedAt *time.Time
+	CreatedAt   time.Time
+}
+
+// Enrolment is the secret to add to an authenticator app, returned once, and
+// the one-time code challenge to answer along with its first code.
+type Enrolment struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	// URI is the otpauth URI to show as a QR code.
+	URI       string    `json:"uri" example:"otpauth://totp/wallet:7a1d3c9e-4b2f-4e86-9c05-1f8e6d2b3a74?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=wallet"`
+	Challenge Challenge `json:"challenge"`
+}
@@ -0,0 +1,328 @@
+package stepup
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+)
+
+// maxAttempts is the number of wrong codes after which a challenge is
+// dropped.
+const maxAttempts = 5
+
+// Config sets up the step-up confirmation.
+type Config struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string
+	// ChallengeTTL is how long a challenge can be answered.
+	ChallengeTTL time.Duration
+}
+
+type ServiceStepUp struct {
+	logger   *slog.Logger
+	storage  Storage
+	cache    Cache
+	notifier Notifier
+	cfg      Config
+}
+
+func NewService(storage Storage, logger *slog.Logger, cache Cache, notifier Notifier, cfg Config) Service {
+	return &ServiceStepUp{
+		storage:  storage,
+		logger:   logger,
+		cache:    cache,
+		notifier: notifier,
+		cfg:      cfg,
+	}
+}
+
+// challengeRecord is a challenge as it is kept in the cache. CodeHash is the
+// SHA-256 of the one-time code, TOTP challenges are checked against the
+// user's secret.
+type challengeRecord struct {
+	Method    Method    `json:"method"`
+	CodeHash  []byte    `json:"code_hash,omitempty"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *ServiceStepUp) EnrolTOTP(ctx context.Context, userID string) (Enrolment, error) {
+	const op = "stepup.EnrolTOTP"
+	log := s.logger.With(slog.String("op", op))
+
+	secret := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		log.Error(err.Error())
+		return Enrolment{}, ErrSmtWentWrong
+	}
+	if err := s.storage.SaveTOTP(ctx, TOTP{UserID: userID, Secret: secret}); err != nil {
+		return Enrolment{}, domainError(log, err)
+	}
+	// The bearer token alone doesn't enrol a TOTP, the user also answers a
+	// one-time code sent over the channel they already have.
+	ch, err := s.CreateChallenge(ctx, userID, MethodOTP)
+	if err != nil {
+		return Enrolment{}, err
+	}
+	return Enrolment{
+		Secret:    encodeSecret(secret),
+		URI:       otpauthURI(s.cfg.Issuer, userID, secret),
+		Challenge: ch,
+	}, nil
+}
+
+func (s *ServiceStepUp) ConfirmTOTP(ctx context.Context, userID, challengeID, otpCode, code string) error {
+	const op = "stepup.ConfirmTOTP"
+	log := s.logger.With(slog.String("op", op))
+
+	t, err := s.storage.GetTOTP(ctx, userID)
+	if err != nil {
+		return domainError(log, err)
+	}
+	if t.ConfirmedAt != nil {
+		return ErrTOTPEnrolled
+	}
+	// A mistyped TOTP code doesn't use up the challenge.
+	if _, ok := matchTOTP(t.Secret, code, time.Now(), t.LastStep); !ok {
+		return ErrInvalidCode
+	}
+	// While the TOTP is unconfirmed, only one-time code challenges can be
+	// answered, so the challenge proves the factor the user had before.
+	if err = s.VerifyChallenge(ctx, userID, challengeID, otpCode); err != nil {
+		return err
+	}
+	if err = s.useTOTP(ctx, t, code); err != nil {
+		return domainError(log, err)
+	}
+	log.Info("totp enrolled", slog.String("user_id", userID))
+	if err = s.notifier.NotifyTOTPEnrolled(ctx, userID); err != nil {
+		log.Error(err.Error())
+	}
+	return nil
+}
+
+func (s *ServiceStepUp) DisableTOTP(ctx context.Context, userID, code string) error {
+	const op = "stepup.DisableTOTP"
+	log := s.logger.With(slog.String("op", op))
+
+	t, err := s.storage.GetTOTP(ctx, userID)
+	if err != nil {
+		return domainError(log, err)
+	}
+	if t.ConfirmedAt == nil {
+		return ErrTOTPNotEnrolled
+	}
+	if err = s.useTOTP(ctx, t, code); err != nil {
+		return domainError(log, err)
+	}
+	if err = s.storage.DeleteTOTP(ctx, userID); err != nil {
+		return domainError(log, err)
+	}
+	log.Info("totp disabled", slog.String("user_id", userID))
+	return nil
+}
+
+func (s *ServiceStepUp) CreateChallenge(ctx context.Context, userID string, method Method) (Challenge, error) {
+	const op = "stepup.CreateChallenge"
+	log := s.logger.With(slog.String("op", op))
+
+	if method != MethodOTP {
+		enrolled, err := s.totpEnrolled(ctx, userID)
+		if err != nil {
+			return Challenge{}, domainError(log, err)
+		}
+		switch {
+		case enrolled:
+			method = MethodTOTP
+		case method == MethodTOTP:
+			return Challenge{}, ErrTOTPNotEnrolled
+		default:
+			method = MethodOTP
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		log.Error(err.Error())
+		return Challenge{}, ErrSmtWentWrong
+	}
+	ch := Challenge{ID: id, Method: method, ExpiresAt: time.Now().Add(s.cfg.ChallengeTTL).UTC()}
+	r := challengeRecord{Method: method, ExpiresAt: ch.ExpiresAt}
+	var code string
+	if method == MethodOTP {
+		if code, err = randomCode(); err != nil {
+			log.Error(err.Error())
+			return Challenge{}, ErrSmtWentWrong
+		}
+		r.CodeHash = hashCode(code)
+	}
+	if err = s.saveChallenge(ctx, userID, id, r); err != nil {
+		log.Error(err.Error())
+		return Challenge{}, ErrSmtWentWrong
+	}
+	if method == MethodOTP {
+		if err = s.notifier.SendCode(ctx, userID, code, ch.ExpiresAt); err != nil {
+			log.Error(err.Error())
+			_ = s.cache.DeleteValue(ctx, challengeKey(userID, id))
+			return Challenge{}, ErrSmtWentWrong
+		}
+	}
+	return ch, nil
+}
+
+func (s *ServiceStepUp) VerifyChallenge(ctx context.Context, userID, challengeID, code string) error {
+	const op = "stepup.VerifyChallenge"
+	log := s.logger.With(slog.String("op", op))
+
+	key := challengeKey(userID, challengeID)
+	data, err := s.cache.GetValue(ctx, key)
+	if err != nil {
+		log.Error(err.Error())
+		return ErrSmtWentWrong
+	}
+	if data == "" {
+		return ErrChallengeNotFound
+	}
+	var r challengeRecord
+	if err = json.Unmarshal([]byte(data), &r); err != nil {
+		log.Error(err.Error())
+		return ErrSmtWentWrong
+	}
+	if !time.Now().Before(r.ExpiresAt) {
+		return ErrChallengeNotFound
+	}
+
+	switch r.Method {
+	case MethodTOTP:
+		// The TOTP may have been disabled, or replaced by an unconfirmed one,
+		// since the challenge was created.
+		var t TOTP
+		t, err = s.storage.GetTOTP(ctx, userID)
+		if err == nil && t.ConfirmedAt == nil {
+			err = ErrTOTPNotEnrolled
+		}
+		if err == nil {
+			err = s.useTOTP(ctx, t, code)
+		}
+	default:
+		if subtle.ConstantTimeCompare(hashCode(code), r.CodeHash) != 1 {
+			err = ErrInvalidCode
+		}
+	}
+	if errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrTOTPNotEnrolled) {
+		// Concurrent wrong codes may be counted once, the attempts only need
+		// to stop guessing within the lifetime of the challenge.
+		r.Attempts++
+		if r.Attempts >= maxAttempts {
+			_ = s.cache.DeleteValue(ctx, key)
+		} else if err = s.saveChallenge(ctx, userID, challengeID, r); err != nil {
+			log.Error(err.Error())
+		}
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return domainError(log, err)
+	}
+	// Only one of concurrent confirmations gets the challenge.
+	data, err = s.cache.PopValue(ctx, key)
+	if err != nil {
+		log.Error(err.Error())
+		return ErrSmtWentWrong
+	}
+	if data == "" {
+		return ErrChallengeNotFound
+	}
+	return nil
+}
+
+// useTOTP accepts the code if it is a current code of the TOTP that was not
+// used before, and confirms the TOTP.
+func (s *ServiceStepUp) useTOTP(ctx context.Context, t TOTP, code string) error {
+	step, ok := matchTOTP(t.Secret, code, time.Now(), t.LastStep)
+	if !ok {
+		return ErrInvalidCode
+	}
+	ok, err := s.storage.UseTOTPStep(ctx, t.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *ServiceStepUp) totpEnrolled(ctx context.Context, userID string) (bool, error) {
+	t, err := s.storage.GetTOTP(ctx, userID)
+	if errors.Is(err, ErrTOTPNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return t.ConfirmedAt != nil, nil
+}
+
+// saveChallenge stores the challenge until it expires.
+func (s *ServiceStepUp) saveChallenge(ctx context.Context, userID, id string, r challengeRecord) error {
+	ttl := time.Until(r.ExpiresAt)
+	if ttl <= 0 {
+		return s.cache.DeleteValue(ctx, challengeKey(userID, id))
+	}
+	jsonData, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.cache.SetValue(ctx, challengeKey(userID, id), string(jsonData), ttl)
+}
+
+// challengeKey scopes challenges to their user, so a challenge id of another
+// user is reported as not found.
+func challengeKey(userID, challengeID string) string {
+	return fmt.Sprintf("step_up_challenge:%s:%s", userID, challengeID)
+}
+
+// randomCode returns a one-time code of totpDigits digits.
+func randomCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(totpModulus))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", totpDigits, n.Int64()), nil
+}
+
+func hashCode(code string) []byte {
+	sum := sha256.Sum256([]byte(code))
+	return sum[:]
+}
+
+// newID returns a random (version 4) UUID.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// domainError passes errors meant for the client through as is and logs and
+// hides everything else behind ErrSmtWentWrong.
+func domainError(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, ErrTOTPNotEnrolled),
+		errors.Is(err, ErrTOTPEnrolled),
+		errors.Is(err, ErrInvalidCode),
+		errors.Is(err, ErrChallengeNotFound):
+		return err
+	}
+	log.Error(err.Error())
+	return ErrSmtWentWrong
+}
@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain/stepup"
+	"wallet/pkg/logger"
+)
+
+const userID = "7a1d3c9e-4b2f-4e86-9c05-1f8e6d2b3a74"
+
+// memoryStorage keeps the TOTP secrets in memory in place of Postgres.
+type memoryStorage struct {
+	mu    sync.Mutex
+	totps map[string]stepup.TOTP
+}
+
+func (m *memoryStorage) SaveTOTP(_ context.Context, t stepup.TOTP) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := m.totps[t.UserID]; ok && current.ConfirmedAt != nil {
+		return stepup.ErrTOTPEnrolled
+	}
+	t.CreatedAt = time.Now()
+	m.totps[t.UserID] = t
+	return nil
+}
+
+func (m *memoryStorage) GetTOTP(_ context.Context, userID string) (stepup.TOTP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.totps[userID]
+	if !ok {
+		return stepup.TOTP{}, stepup.ErrTOTPNotEnrolled
+	}
+	return t, nil
+}
+
+func (m *memoryStorage) UseTOTPStep(_ context.Context, userID string, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.totps[userID]
+	if !ok || t.LastStep >= step {
+		return false, nil
+	}
+	t.LastStep = step
+	if t.ConfirmedAt == nil {
+		now := time.Now()
+		t.ConfirmedAt = &now
+	}
+	m.totps[userID] = t
+	return true, nil
+}
+
+func (m *memoryStorage) DeleteTOTP(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.totps, userID)
+	return nil
+}
+
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string]string)}
+}
+
+func (c *memoryCache) GetValue(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key], nil
+}
+
+func (c *memoryCache) SetValue(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *memoryCache) DeleteValue(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *memoryCache) PopValue(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value := c.values[key]
+	delete(c.values, key)
+	return value, nil
+}
+
+// memoryNotifier keeps the last code sent to every user and the users told
+// of an enrolled TOTP.
+type memoryNotifier struct {
+	mu       sync.Mutex
+	codes    map[string]string
+	enrolled map[string]bool
+}
+
+func (n *memoryNotifier) SendCode(_ context.Context, userID, code string, _ time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.codes[userID] = code
+	return nil
+}
+
+func (n *memoryNotifier) NotifyTOTPEnrolled(_ context.Context, userID string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.enrolled[userID] = true
+	return nil
+}
+
+func newService(ttl time.Duration) (stepup.Service, *memoryNotifier) {
+	notifier := &memoryNotifier{codes: make(map[string]string), enrolled: make(map[string]bool)}
+	s := stepup.NewService(
+		&memoryStorage{totps: make(map[string]stepup.TOTP)},
+		logger.SetupLogger(logger.Prod, ""),
+		newMemoryCache(),
+		notifier,
+		stepup.Config{Issuer: "Wallet", ChallengeTTL: ttl},
+	)
+	return s, notifier
+}
+
+// TestTOTPCode checks the SHA-1 test vectors of RFC 6238, truncated to six
+// digits.
+func TestTOTPCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		if got := stepup.TOTPCode(secret, time.Unix(unix, 0)); got != want {
+			t.Errorf("code at %d: want %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestTOTPEnrolment(t *testing.T) {
+	ctx := context.Background()
+	s, notifier := newService(time.Minute)
+
+	e, err := s.EnrolTOTP(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(e.URI, "otpauth://totp/") || !strings.Contains(e.URI, "secret="+e.Secret) {
+		t.Fatalf("unexpected uri %q", e.URI)
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(e.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp := notifier.codes[userID]
+	if e.Challenge.Method != stepup.MethodOTP || otp == "" {
+		t.Fatalf("want a one-time code sent for the enrolment, got %+v", e.Challenge)
+	}
+
+	// An unconfirmed TOTP doesn't answer challenges.
+	ch, err := s.CreateChallenge(ctx, userID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Method != stepup.MethodOTP || notifier.codes[userID] == "" {
+		t.Fatalf("want a one-time code before confirmation, got %+v", ch)
+	}
+	if _, err = s.CreateChallenge(ctx, userID, stepup.MethodTOTP); !errors.Is(err, stepup.ErrTOTPNotEnrolled) {
+		t.Fatalf("want ErrTOTPNotEnrolled, got %v", err)
+	}
+
+	now := time.Now()
+	if err = s.ConfirmTOTP(ctx, userID, e.Challenge.ID, otp, wrongCode(stepup.TOTPCode(secret, now))); !errors.Is(err, stepup.ErrInvalidCode) {
+		t.Fatalf("want ErrInvalidCode, got %v", err)
+	}
+	// The bearer of the token alone, without the one-time code, can't
+	// confirm a TOTP of their own.
+	if err = s.ConfirmTOTP(ctx, userID, e.Challenge.ID, wrongCode(otp), stepup.TOTPCode(secret, now)); !errors.Is(err, stepup.ErrInvalidCode) {
+		t.Fatalf("want ErrInvalidCode for a wrong one-time code, got %v", err)
+	}
+	if err = s.ConfirmTOTP(ctx, userID, ch.ID, "", stepup.TOTPCode(secret, now)); !errors.Is(err, stepup.ErrInvalidCode) {
+		t.Fatalf("want ErrInvalidCode without the one-time code, got %v", err)
+	}
+	if notifier.enrolled[userID] {
+		t.Fatal("want no notice before the TOTP is confirmed")
+	}
+	if err = s.ConfirmTOTP(ctx, userID, e.Challenge.ID, otp, stepup.TOTPCode(secret, now.Add(-30*time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	if !notifier.enrolled[userID] {
+		t.Fatal("want the user notified of the enrolled TOTP")
+	}
+	if _, err = s.EnrolTOTP(ctx, userID); !errors.Is(err, stepup.ErrTOTPEnrolled) {
+		t.Fatalf("want ErrTOTPEnrolled, got %v", err)
+	}
+
+	ch, err = s.CreateChallenge(ctx, userID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Method != stepup.MethodTOTP {
+		t.Fatalf("want a TOTP challenge once enrolled, got %s", ch.Method)
+	}
+	// The code used for the confirmation and the codes before it are spent.
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, stepup.TOTPCode(secret, now.Add(-30*time.Second))); !errors.Is(err, stepup.ErrInvalidCode) {
+		t.Fatalf("want a used code rejected, got %v", err)
+	}
+	if err = s.VerifyChallenge(ctx, "00000000-0000-0000-0000-000000000000", ch.ID, stepup.TOTPCode(secret, now)); !errors.Is(err, stepup.ErrChallengeNotFound) {
+		t.Fatalf("want the challenge of another user not found, got %v", err)
+	}
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, stepup.TOTPCode(secret, now)); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, stepup.TOTPCode(secret, now.Add(30*time.Second))); !errors.Is(err, stepup.ErrChallengeNotFound) {
+		t.Fatalf("want an answered challenge consumed, got %v", err)
+	}
+
+	if err = s.DisableTOTP(ctx, userID, stepup.TOTPCode(secret, now.Add(30*time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.CreateChallenge(ctx, userID, stepup.MethodTOTP); !errors.Is(err, stepup.ErrTOTPNotEnrolled) {
+		t.Fatalf("want ErrTOTPNotEnrolled once disabled, got %v", err)
+	}
+}
+
+func TestOneTimeCode(t *testing.T) {
+	ctx := context.Background()
+	s, notifier := newService(time.Minute)
+
+	ch, err := s.CreateChallenge(ctx, userID, stepup.MethodOTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := notifier.codes[userID]
+	if len(code) != 6 {
+		t.Fatalf("unexpected code %q", code)
+	}
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, wrongCode(code)); !errors.Is(err, stepup.ErrInvalidCode) {
+		t.Fatalf("want ErrInvalidCode, got %v", err)
+	}
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, code); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, code); !errors.Is(err, stepup.ErrChallengeNotFound) {
+		t.Fatalf("want the code accepted once, got %v", err)
+	}
+
+	// The challenge is dropped after too many wrong codes.
+	ch, err = s.CreateChallenge(ctx, userID, stepup.MethodOTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code = notifier.codes[userID]
+	for i := 0; i < 5; i++ {
+		if err = s.VerifyChallenge(ctx, userID, ch.ID, wrongCode(code)); !errors.Is(err, stepup.ErrInvalidCode) {
+			t.Fatalf("want ErrInvalidCode, got %v", err)
+		}
+	}
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, code); !errors.Is(err, stepup.ErrChallengeNotFound) {
+		t.Fatalf("want the challenge dropped, got %v", err)
+	}
+}
+
+func TestChallengeExpiry(t *testing.T) {
+	ctx := context.Background()
+	s, notifier := newService(10 * time.Millisecond)
+
+	ch, err := s.CreateChallenge(ctx, userID, stepup.MethodOTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err = s.VerifyChallenge(ctx, userID, ch.ID, notifier.codes[userID]); !errors.Is(err, stepup.ErrChallengeNotFound) {
+		t.Fatalf("want an expired challenge rejected, got %v", err)
+	}
+}
+
+// wrongCode returns a code different from the given one.
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "000001"
+	}
+	return "000000"
+}
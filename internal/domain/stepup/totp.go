@@ -0,0 +1,78 @@
+package stepup
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// TOTP parameters of RFC 6238 as supported by common authenticator apps.
+const (
+	totpDigits = 6
+	// totpModulus is 10^totpDigits.
+	totpModulus = 1000000
+	totpPeriod  = 30
+	// totpSkew is the number of time steps before and after the current one
+	// whose codes are accepted, to allow for clock drift.
+	totpSkew        = 1
+	totpSecretBytes = 20
+)
+
+// TOTPCode returns the code of the secret for the instant, computed with
+// HMAC-SHA1 as in RFC 6238.
+func TOTPCode(secret []byte, t time.Time) string {
+	return hotp(secret, totpStep(t))
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// hotp computes the code of the counter as in RFC 4226.
+func hotp(secret []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulus)
+}
+
+// matchTOTP returns the time step the code belongs to if it is a code of
+// the secret within totpSkew steps of now and is later than lastStep.
+func matchTOTP(secret []byte, code string, now time.Time, lastStep int64) (int64, bool) {
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// encodeSecret encodes the secret in unpadded base32, the format
+// authenticator apps expect.
+func encodeSecret(secret []byte) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+}
+
+// otpauthURI builds the key URI authenticator apps read from QR codes.
+func otpauthURI(issuer, account string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", encodeSecret(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + q.Encode()
+}
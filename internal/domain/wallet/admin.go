@@ -0,0 +1,244 @@
+package wallet
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// adminActionsLimit is the number of the latest admin actions returned for a
+// wallet.
+const adminActionsLimit = 100
+
+func (s *ServiceWallet) GetWallet(ctx context.Context, operatorID, userID string) (Wallet, error) {
+	const op = "wallet.GetWallet"
+	log := s.logger.With(slog.String("op", op))
+
+	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	if err = s.recordView(ctx, log, operatorID, w, AdminActionViewWallet); err != nil {
+		return Wallet{}, err
+	}
+	if err = s.addZeroBalances(ctx, &w); err != nil {
+		return Wallet{}, err
+	}
+	return w, nil
+}
+
+func (s *ServiceWallet) GetWalletTransactions(ctx context.Context, operatorID, userID string, filter TransactionFilter) ([]Transaction, *TransactionCursor, error) {
+	const op = "wallet.GetWalletTransactions"
+	log := s.logger.With(slog.String("op", op))
+
+	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, domainError(log, err)
+	}
+	if err = s.recordView(ctx, log, operatorID, w, AdminActionViewTransactions); err != nil {
+		return nil, nil, err
+	}
+	return s.GetTransactions(ctx, userID, filter)
+}
+
+// recordView logs the view of the wallet by the operator. Nothing is shown
+// unless the view is recorded.
+func (s *ServiceWallet) recordView(ctx context.Context, log *slog.Logger, operatorID string, w Wallet, action string) error {
+	err := s.storage.AddAdminAction(ctx, AdminAction{
+		OperatorUUID: operatorID,
+		Action:       action,
+		WalletUUID:   w.UUID,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return ErrSmtWentWrong
+	}
+	return nil
+}
+
+func (s *ServiceWallet) FreezeWallet(ctx context.Context, operatorID, userID, reason string) (Wallet, error) {
+	const op = "wallet.FreezeWallet"
+	log := s.logger.With(slog.String("op", op))
+
+	var w Wallet
+	err := s.storage.WithinTransaction(ctx, func(st Storage) error {
+		var err error
+		if w, err = st.LockWalletByUserID(ctx, userID); err != nil {
+			return err
+		}
+		if w.Frozen != nil {
+			return ErrWalletFrozen
+		}
+		w.Frozen = &Freeze{OperatorUUID: operatorID, Reason: reason, FrozenAt: time.Now().UTC()}
+		if err = st.SetWalletFreeze(ctx, w.UUID, w.Frozen); err != nil {
+			return err
+		}
+		err = st.AddAdminAction(ctx, AdminAction{
+			OperatorUUID: operatorID,
+			Action:       AdminActionFreeze,
+			WalletUUID:   w.UUID,
+			Reason:       reason,
+		})
+		if err != nil {
+			return err
+		}
+		return addEvent(ctx, st, EventWalletFrozen, w.UUID, WalletFreezeEvent{
+			WalletUUID:   w.UUID,
+			UserUUID:     userID,
+			OperatorUUID: operatorID,
+			Reason:       reason,
+		})
+	})
+	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	log.Info("wallet frozen", slog.String("operator_id", operatorID), slog.String("user_id", userID))
+	if err = s.addZeroBalances(ctx, &w); err != nil {
+		log.Error(err.Error())
+	}
+	return w, nil
+}
+
+func (s *ServiceWallet) UnfreezeWallet(ctx context.Context, operatorID, userID, reason string) (Wallet, error) {
+	const op = "wallet.UnfreezeWallet"
+	log := s.logger.With(slog.String("op", op))
+
+	var w Wallet
+	err := s.storage.WithinTransaction(ctx, func(st Storage) error {
+		var err error
+		if w, err = st.LockWalletByUserID(ctx, userID); err != nil {
+			return err
+		}
+		if w.Frozen == nil {
+			return ErrWalletNotFrozen
+		}
+		w.Frozen = nil
+		if err = st.SetWalletFreeze(ctx, w.UUID, nil); err != nil {
+			return err
+		}
+		err = st.AddAdminAction(ctx, AdminAction{
+			OperatorUUID: operatorID,
+			Action:       AdminActionUnfreeze,
+			WalletUUID:   w.UUID,
+			Reason:       reason,
+		})
+		if err != nil {
+			return err
+		}
+		return addEvent(ctx, st, EventWalletUnfrozen, w.UUID, WalletFreezeEvent{
+			WalletUUID:   w.UUID,
+			UserUUID:     userID,
+			OperatorUUID: operatorID,
+			Reason:       reason,
+		})
+	})
+	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	log.Info("wallet unfrozen", slog.String("operator_id", operatorID), slog.String("user_id", userID))
+	if err = s.addZeroBalances(ctx, &w); err != nil {
+		log.Error(err.Error())
+	}
+	return w, nil
+}
+
+// AdjustBalance posts the amount to the wallet against the adjustment
+// account. Adjustments are allowed for frozen wallets and in disabled
+// currencies, but can't make the balance negative.
+func (s *ServiceWallet) AdjustBalance(ctx context.Context, operatorID, userID string, amount Money, reason string) (Wallet, error) {
+	const op = "wallet.AdjustBalance"
+	log := s.logger.With(slog.String("op", op))
+
+	if amount.Minor == 0 {
+		return Wallet{}, ErrInvalidAmountOrCurrency
+	}
+	c, err := s.currency(ctx, amount.Currency)
+	if err != nil {
+		return Wallet{}, err
+	}
+	var w Wallet
+	var postings []Transaction
+	err = s.storage.WithinTransaction(ctx, func(st Storage) error {
+		var err error
+		if w, err = st.LockWalletByUserID(ctx, userID); err != nil {
+			return err
+		}
+		if w.Balance(c).Add(amount).IsNegative() {
+			return ErrNotEnoughFunds
+		}
+		entries := []Transaction{
+			{WalletUUID: w.UUID, Type: TransactionAdjustment, Currency: amount.Currency, Amount: amount},
+			{AccountType: AccountAdjustment, Type: TransactionAdjustment, Currency: amount.Currency, Amount: amount.Neg()},
+		}
+		if postings, err = applyTransactions(ctx, st, &w, entries); err != nil {
+			return err
+		}
+		err = st.AddAdminAction(ctx, AdminAction{
+			OperatorUUID:  operatorID,
+			Action:        AdminActionAdjust,
+			WalletUUID:    w.UUID,
+			OperationUUID: postings[0].OperationUUID,
+			Amount:        &amount,
+			Reason:        reason,
+		})
+		if err != nil {
+			return err
+		}
+		return addEvent(ctx, st, EventAdjusted, w.UUID, AdjustedEvent{
+			WalletUUID:    w.UUID,
+			UserUUID:      userID,
+			OperationUUID: postings[0].OperationUUID,
+			OperatorUUID:  operatorID,
+			Amount:        amount,
+			Currency:      amount.Currency,
+			Balance:       w.Balance(c),
+			Reason:        reason,
+		})
+	})
+	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	log.Info("balance adjusted",
+		slog.String("operator_id", operatorID),
+		slog.String("user_id", userID),
+		slog.String("amount", amount.String()),
+		slog.String("currency", amount.Currency),
+	)
+	if err = s.addZeroBalances(ctx, &w); err != nil {
+		// The adjustment is committed, it is not reported as failed for the
+		// zero balances missing from the response.
+		log.Error(err.Error())
+	}
+	s.notifyBalance(ctx, log, userID, w, postings)
+
+	return w, nil
+}
+
+func (s *ServiceWallet) GetAdminActions(ctx context.Context, userID string) ([]AdminAction, error) {
+	const op = "wallet.GetAdminActions"
+	log := s.logger.With(slog.String("op", op))
+
+	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	if err != nil {
+		return nil, domainError(log, err)
+	}
+	actions, err := s.storage.GetAdminActions(ctx, w.UUID, adminActionsLimit)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	return actions, nil
+}
+
+// lockActiveWallet locks the wallet like LockWalletByUserID and returns
+// ErrWalletFrozen if it is frozen.
+func lockActiveWallet(ctx context.Context, st Storage, userID string) (Wallet, error) {
+	w, err := st.LockWalletByUserID(ctx, userID)
+	if err != nil {
+		return Wallet{}, err
+	}
+	if w.Frozen != nil {
+		return Wallet{}, ErrWalletFrozen
+	}
+	return w, nil
+}
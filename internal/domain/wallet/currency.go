@@ -0,0 +1,202 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	currenciesCacheKey = "currencies"
+	currenciesCacheTTL = time.Minute
+	// maxPrecision is the scale of the NUMERIC columns amounts are stored in.
+	maxPrecision = 8
+)
+
+// GetCurrencies returns the currency registry. It is read from the currency
+// table and cached, so a new currency becomes available within
+// currenciesCacheTTL without a restart.
+func (s *ServiceWallet) GetCurrencies(ctx context.Context) ([]Currency, error) {
+	const op = "wallet.GetCurrencies"
+	log := s.logger.With(slog.String("op", op))
+
+	cached, _ := s.cache.GetValue(ctx, currenciesCacheKey)
+	if cached != "" {
+		var currencies []Currency
+		if err := json.Unmarshal([]byte(cached), &currencies); err == nil {
+			return currencies, nil
+		}
+	}
+	currencies, err := s.storage.GetCurrencies(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	jsonData, err := json.Marshal(currencies)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	_ = s.cache.SetValue(ctx, currenciesCacheKey, string(jsonData), currenciesCacheTTL)
+	return currencies, nil
+}
+
+func (s *ServiceWallet) ParseAmount(ctx context.Context, amount Decimal, currency string) (Money, error) {
+	c, err := s.currency(ctx, currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return ParseMoney(string(amount), c)
+}
+
+// currency looks the code up in the registry. Unknown codes are reported as
+// ErrInvalidAmountOrCurrency.
+func (s *ServiceWallet) currency(ctx context.Context, code string) (Currency, error) {
+	currencies, err := s.GetCurrencies(ctx)
+	if err != nil {
+		return Currency{}, err
+	}
+	c, ok := findCurrency(currencies, code)
+	if !ok {
+		return Currency{}, ErrInvalidAmountOrCurrency
+	}
+	return c, nil
+}
+
+// addZeroBalances adds a zero balance for every enabled currency the wallet
+// has never held, so clients always see the full list of currencies.
+// Balances in disabled currencies are kept as they are.
+func (s *ServiceWallet) addZeroBalances(ctx context.Context, w *Wallet) error {
+	currencies, err := s.GetCurrencies(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range currencies {
+		if c.Enabled {
+			w.setBalance(w.Balance(c))
+		}
+	}
+	return nil
+}
+
+func (s *ServiceWallet) CreateCurrency(ctx context.Context, c Currency) (Currency, error) {
+	const op = "wallet.CreateCurrency"
+	log := s.logger.With(slog.String("op", op))
+
+	if err := validateCurrency(c); err != nil {
+		return Currency{}, err
+	}
+	if err := s.storage.CreateCurrency(ctx, c); err != nil {
+		if errors.Is(err, ErrCurrencyExists) {
+			return Currency{}, err
+		}
+		log.Error(err.Error())
+		return Currency{}, ErrSmtWentWrong
+	}
+	return s.reloadCurrency(ctx, c.Code)
+}
+
+// UpdateCurrency replaces the properties of the currency except for its
+// enabled flag. The precision can't be lowered, as existing balances and
+// ledger entries may already use all of its digits.
+func (s *ServiceWallet) UpdateCurrency(ctx context.Context, c Currency) (Currency, error) {
+	const op = "wallet.UpdateCurrency"
+	log := s.logger.With(slog.String("op", op))
+
+	if err := validateCurrency(c); err != nil {
+		return Currency{}, err
+	}
+	currencies, err := s.storage.GetCurrencies(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return Currency{}, ErrSmtWentWrong
+	}
+	current, ok := findCurrency(currencies, c.Code)
+	if !ok {
+		return Currency{}, ErrCurrencyNotFound
+	}
+	if c.Precision < current.Precision {
+		return Currency{}, ErrInvalidCurrency
+	}
+	if err = s.storage.UpdateCurrency(ctx, c); err != nil {
+		if errors.Is(err, ErrCurrencyNotFound) {
+			return Currency{}, err
+		}
+		log.Error(err.Error())
+		return Currency{}, ErrSmtWentWrong
+	}
+	return s.reloadCurrency(ctx, c.Code)
+}
+
+// SetCurrencyEnabled enables or disables the currency. Deposits and exchanges
+// in a disabled currency are rejected, existing balances stay readable.
+func (s *ServiceWallet) SetCurrencyEnabled(ctx context.Context, code string, enabled bool) (Currency, error) {
+	const op = "wallet.SetCurrencyEnabled"
+	log := s.logger.With(slog.String("op", op))
+
+	if err := s.storage.SetCurrencyEnabled(ctx, code, enabled); err != nil {
+		if errors.Is(err, ErrCurrencyNotFound) {
+			return Currency{}, err
+		}
+		log.Error(err.Error())
+		return Currency{}, ErrSmtWentWrong
+	}
+	return s.reloadCurrency(ctx, code)
+}
+
+// reloadCurrency drops the cached registry after a change and returns the
+// currency as it is stored now.
+func (s *ServiceWallet) reloadCurrency(ctx context.Context, code string) (Currency, error) {
+	_ = s.cache.DeleteValue(ctx, currenciesCacheKey)
+	currencies, err := s.GetCurrencies(ctx)
+	if err != nil {
+		return Currency{}, err
+	}
+	c, ok := findCurrency(currencies, code)
+	if !ok {
+		return Currency{}, ErrCurrencyNotFound
+	}
+	return c, nil
+}
+
+func findCurrency(currencies []Currency, code string) (Currency, bool) {
+	for _, c := range currencies {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Currency{}, false
+}
+
+func validateCurrency(c Currency) error {
+	if c.Precision < 0 || c.Precision > maxPrecision {
+		return ErrInvalidCurrency
+	}
+	switch c.Rounding {
+	case RoundHalfEven, RoundHalfUp, RoundDown:
+	default:
+		return ErrInvalidCurrency
+	}
+	var min, max Money
+	var err error
+	if c.MinAmount != "" {
+		if min, err = ParseMoney(string(c.MinAmount), c); err != nil || !min.IsPositive() {
+			return ErrInvalidCurrency
+		}
+	}
+	if c.MaxAmount != "" {
+		if max, err = ParseMoney(string(c.MaxAmount), c); err != nil || !max.IsPositive() {
+			return ErrInvalidCurrency
+		}
+	}
+	if c.MinAmount != "" && c.MaxAmount != "" && max.Sub(min).IsNegative() {
+		return ErrInvalidCurrency
+	}
+	if c.ConfirmationThreshold != "" {
+		if threshold, err := ParseMoney(string(c.ConfirmationThreshold), c); err != nil || !threshold.IsPositive() {
+			return ErrInvalidCurrency
+		}
+	}
+	return nil
+}
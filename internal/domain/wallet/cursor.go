@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// encodeCursor turns a cursor into an opaque URL-safe token for clients.
+func encodeCursor(c TransactionCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (TransactionCursor, error) {
+	var c TransactionCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err = json.Unmarshal(data, &c); err != nil || len(c.ID) != 36 {
+		return c, ErrInvalidCursor
+	}
+	switch c.SortBy {
+	case SortByCreatedAt:
+		_, err = time.Parse(time.RFC3339Nano, c.Value)
+	case SortByAmount:
+		_, err = strconv.ParseFloat(c.Value, 64)
+	default:
+		return c, ErrInvalidCursor
+	}
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -2,10 +2,28 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"wallet/internal/domain/outbox"
 	wallet2 "wallet/internal/domain/wallet"
 )
 
+// uniqueViolation is the PostgreSQL error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// Conflict targets matching the partial unique indexes of user and system
+// accounts.
+const (
+	userAccountConflict   = "(wallet_id, currency) WHERE type = 'user'"
+	systemAccountConflict = "(type, currency) WHERE type <> 'user'"
+)
+
 type Storage struct {
 	Client wallet2.PsqlClient
 	logger *slog.Logger
@@ -15,62 +33,955 @@ func NewRepository(client wallet2.PsqlClient, logger *slog.Logger) wallet2.Stora
 	return &Storage{client, logger}
 }
 
-func (s *Storage) CreateWallet(ctx context.Context, userID string) error {
+func (s *Storage) CreateWallet(ctx context.Context, userID string) (string, error) {
 	const op = "wallet.db.CreateWallet"
 	log := s.logger.With(slog.String("op", op))
 
-	q := `INSERT INTO wallet(
-                    user_id,
-                    balance_eur,
-                    balance_usd,
-                    balance_rub) 
-		  VALUES ($1, $2, $3, $4)`
-	_, err := s.Client.Exec(ctx, q, userID, 0, 0, 0)
+	q := `INSERT INTO wallet(user_id) VALUES ($1) RETURNING id`
+	var id string
+	err := s.Client.QueryRow(ctx, q, userID).Scan(&id)
 	if err != nil {
 		log.Error(err.Error())
-		return err
+		return "", err
 	}
-	return nil
+	return id, nil
 }
 
 func (s *Storage) GetWalletByUserID(ctx context.Context, UserID string) (wallet2.Wallet, error) {
 	const op = "wallet.db.GetWalletByUserID"
 	log := s.logger.With(slog.String("op", op))
 
-	q := `SELECT id, 
-       			 balance_eur, 
-       			 balance_usd, 
-       			 balance_rub 
-		  FROM wallet WHERE user_id=$1`
+	w, err := s.getWallet(ctx, UserID, false)
+	if err != nil {
+		log.Error(err.Error())
+		return w, err
+	}
+	return w, nil
+}
+
+func (s *Storage) LockWalletByUserID(ctx context.Context, userID string) (wallet2.Wallet, error) {
+	const op = "wallet.db.LockWalletByUserID"
+	log := s.logger.With(slog.String("op", op))
+
+	w, err := s.getWallet(ctx, userID, true)
+	if err != nil {
+		log.Error(err.Error())
+		return w, err
+	}
+	return w, nil
+}
+
+// getWallet reads the wallet with the balances it holds. With forUpdate the
+// wallet row is locked, which serializes all balance changes of the wallet.
+func (s *Storage) getWallet(ctx context.Context, userID string, forUpdate bool) (wallet2.Wallet, error) {
+	q := `SELECT id, frozen_by::TEXT, freeze_reason, frozen_at FROM wallet WHERE user_id=$1`
+	if forUpdate {
+		q += " FOR UPDATE"
+	}
+
+	w := wallet2.Wallet{UserUUID: userID, Balances: make(map[string]wallet2.Money)}
+	var frozenBy, reason *string
+	var frozenAt *time.Time
+	err := s.Client.QueryRow(ctx, q, userID).Scan(&w.UUID, &frozenBy, &reason, &frozenAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, wallet2.ErrWalletNotFound
+	}
+	if err != nil {
+		return w, err
+	}
+	if frozenAt != nil {
+		w.Frozen = &wallet2.Freeze{FrozenAt: *frozenAt}
+		if frozenBy != nil {
+			w.Frozen.OperatorUUID = *frozenBy
+		}
+		if reason != nil {
+			w.Frozen.Reason = *reason
+		}
+	}
+
+	q = `SELECT c.code,
+       			c.precision,
+       			c.rounding,
+       			a.balance::TEXT
+		 FROM account a
+		 JOIN currency c ON c.code = a.currency
+		 WHERE a.wallet_id=$1 AND a.type='user'`
+
+	rows, err := s.Client.Query(ctx, q, w.UUID)
+	if err != nil {
+		return w, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c wallet2.Currency
+		var amount string
+		if err = rows.Scan(&c.Code, &c.Precision, &c.Rounding, &amount); err != nil {
+			return w, err
+		}
+		if w.Balances[c.Code], err = wallet2.ParseMoney(amount, c); err != nil {
+			return w, err
+		}
+	}
+	return w, rows.Err()
+}
+
+// GetWalletByUserIDAt reads the wallet with the balances it had at the given
+// instant, starting from the latest snapshot of each account not later than
+// that and adding the postings made after the snapshot.
+func (s *Storage) GetWalletByUserIDAt(ctx context.Context, userID string, at time.Time) (wallet2.Wallet, error) {
+	const op = "wallet.db.GetWalletByUserIDAt"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT id FROM wallet WHERE user_id=$1`
+
+	w := wallet2.Wallet{UserUUID: userID, Balances: make(map[string]wallet2.Money)}
+	err := s.Client.QueryRow(ctx, q, userID).Scan(&w.UUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, wallet2.ErrWalletNotFound
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return w, err
+	}
+
+	q = `SELECT c.code,
+       			c.precision,
+       			c.rounding,
+       			(COALESCE(sn.balance, 0) + COALESCE((
+       			    SELECT SUM(t.amount)
+       			    FROM wallet_transaction t
+       			    WHERE t.account_id = a.id
+       			      AND t.created_at > COALESCE(sn.taken_at, '-infinity')
+       			      AND t.created_at <= $2), 0))::TEXT
+		 FROM account a
+		 JOIN currency c ON c.code = a.currency
+		 LEFT JOIN LATERAL (
+		     SELECT taken_at, balance
+		     FROM account_snapshot
+		     WHERE account_id = a.id AND taken_at <= $2
+		     ORDER BY taken_at DESC
+		     LIMIT 1) sn ON TRUE
+		 WHERE a.wallet_id=$1 AND a.type='user'`
 
-	var w wallet2.Wallet
-	err := s.Client.QueryRow(ctx, q, UserID).Scan(&w.UUID, &w.BalanceEUR, &w.BalanceUSD, &w.BalanceRUB)
+	rows, err := s.Client.Query(ctx, q, w.UUID, at)
 	if err != nil {
 		log.Error(err.Error())
 		return w, err
 	}
-	w.UserUUID = UserID
+	defer rows.Close()
+
+	for rows.Next() {
+		var c wallet2.Currency
+		var amount string
+		if err = rows.Scan(&c.Code, &c.Precision, &c.Rounding, &amount); err != nil {
+			log.Error(err.Error())
+			return w, err
+		}
+		if w.Balances[c.Code], err = wallet2.ParseMoney(amount, c); err != nil {
+			log.Error(err.Error())
+			return w, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return w, err
+	}
 	return w, nil
 }
 
-func (s *Storage) UpdateWallet(ctx context.Context, userID string, wallet wallet2.Wallet) error {
-	const op = "wallet.db.UpdateWallet"
+// CreateSnapshots stores the balances of all accounts as of the given
+// instant, computed from the previous snapshot and the postings since then.
+// Taking a snapshot for the same instant twice is a no-op.
+func (s *Storage) CreateSnapshots(ctx context.Context, at time.Time) error {
+	const op = "wallet.db.CreateSnapshots"
 	log := s.logger.With(slog.String("op", op))
 
-	q := `UPDATE wallet SET 
-                  balance_eur = $1, 
-                  balance_usd = $2, 
-                  balance_rub = $3
-		  WHERE user_id=$4`
+	q := `INSERT INTO account_snapshot(account_id, taken_at, balance)
+		  SELECT a.id,
+		         $1,
+		         COALESCE(sn.balance, 0) + COALESCE((
+		             SELECT SUM(t.amount)
+		             FROM wallet_transaction t
+		             WHERE t.account_id = a.id
+		               AND t.created_at > COALESCE(sn.taken_at, '-infinity')
+		               AND t.created_at <= $1), 0)
+		  FROM account a
+		  LEFT JOIN LATERAL (
+		      SELECT taken_at, balance
+		      FROM account_snapshot
+		      WHERE account_id = a.id AND taken_at <= $1
+		      ORDER BY taken_at DESC
+		      LIMIT 1) sn ON TRUE
+		  ON CONFLICT (account_id, taken_at) DO NOTHING`
 
-	_, err := s.Client.Exec(ctx, q, wallet.BalanceEUR, wallet.BalanceUSD, wallet.BalanceRUB, userID)
+	if _, err := s.Client.Exec(ctx, q, at); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) WithinTransaction(ctx context.Context, fn func(wallet2.Storage) error) error {
+	const op = "wallet.db.WithinTransaction"
+	log := s.logger.With(slog.String("op", op))
+
+	tx, err := s.Client.Begin(ctx)
 	if err != nil {
 		log.Error(err.Error())
 		return err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = fn(&Storage{Client: tx, logger: s.logger}); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return err
+	}
 	return nil
 }
 
+// GetUserIDByRecipient looks up the id of the user described by the
+// recipient. It returns wallet.ErrInvalidRecipient if there is no such user.
+func (s *Storage) GetUserIDByRecipient(ctx context.Context, r wallet2.Recipient) (string, error) {
+	const op = "wallet.db.GetUserIDByRecipient"
+	log := s.logger.With(slog.String("op", op))
+
+	var column, value string
+	switch {
+	case r.UserID != "":
+		column, value = "id", r.UserID
+	case r.Username != "":
+		column, value = "username", r.Username
+	case r.Email != "":
+		column, value = "email", r.Email
+	default:
+		return "", wallet2.ErrInvalidRecipient
+	}
+	q := fmt.Sprintf(`SELECT id FROM "user" WHERE %s = $1`, column)
+
+	var userID string
+	err := s.Client.QueryRow(ctx, q, value).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", wallet2.ErrInvalidRecipient
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return "", err
+	}
+	return userID, nil
+}
+
+func (s *Storage) GetCurrencies(ctx context.Context) ([]wallet2.Currency, error) {
+	const op = "wallet.db.GetCurrencies"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT code,
+       			 name,
+       			 symbol,
+       			 precision,
+       			 rounding,
+       			 COALESCE(min_amount::TEXT, ''),
+       			 COALESCE(max_amount::TEXT, ''),
+       			 exchangeable,
+       			 enabled,
+       			 COALESCE(confirmation_threshold::TEXT, '')
+		  FROM currency
+		  ORDER BY code`
+
+	rows, err := s.Client.Query(ctx, q)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var currencies []wallet2.Currency
+	for rows.Next() {
+		var c wallet2.Currency
+		var min, max, threshold string
+		err = rows.Scan(&c.Code, &c.Name, &c.Symbol, &c.Precision, &c.Rounding, &min, &max, &c.Exchangeable, &c.Enabled, &threshold)
+		if err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		// Limits are stored with the scale of the column, trim them to the
+		// precision of the currency.
+		if c.MinAmount, err = currencyAmount(min, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		if c.MaxAmount, err = currencyAmount(max, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		if c.ConfirmationThreshold, err = currencyAmount(threshold, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		currencies = append(currencies, c)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return currencies, nil
+}
+
+func (s *Storage) GetCurrencyRates(ctx context.Context) (map[string]float32, error) {
+	const op = "wallet.db.GetCurrencyRates"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT code, rate::DOUBLE PRECISION FROM currency WHERE rate > 0`
+	rows, err := s.Client.Query(ctx, q)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make(map[string]float32)
+	for rows.Next() {
+		var code string
+		var rate float64
+		if err = rows.Scan(&code, &rate); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		rates[code] = float32(rate)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (s *Storage) CreateCurrency(ctx context.Context, c wallet2.Currency) error {
+	const op = "wallet.db.CreateCurrency"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `INSERT INTO currency(code, name, symbol, precision, rounding, min_amount, max_amount, exchangeable, enabled,
+		                     confirmation_threshold)
+		  VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::NUMERIC, NULLIF($7, '')::NUMERIC, $8, $9, NULLIF($10, '')::NUMERIC)`
+
+	_, err := s.Client.Exec(ctx, q, c.Code, c.Name, c.Symbol, c.Precision, c.Rounding,
+		string(c.MinAmount), string(c.MaxAmount), c.Exchangeable, c.Enabled, string(c.ConfirmationThreshold))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return wallet2.ErrCurrencyExists
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) UpdateCurrency(ctx context.Context, c wallet2.Currency) error {
+	const op = "wallet.db.UpdateCurrency"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `UPDATE currency
+		  SET name = $2,
+		      symbol = $3,
+		      precision = $4,
+		      rounding = $5,
+		      min_amount = NULLIF($6, '')::NUMERIC,
+		      max_amount = NULLIF($7, '')::NUMERIC,
+		      exchangeable = $8,
+		      confirmation_threshold = NULLIF($9, '')::NUMERIC
+		  WHERE code = $1`
+
+	tag, err := s.Client.Exec(ctx, q, c.Code, c.Name, c.Symbol, c.Precision, c.Rounding,
+		string(c.MinAmount), string(c.MaxAmount), c.Exchangeable, string(c.ConfirmationThreshold))
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return wallet2.ErrCurrencyNotFound
+	}
+	return nil
+}
+
+func (s *Storage) SetCurrencyEnabled(ctx context.Context, code string, enabled bool) error {
+	const op = "wallet.db.SetCurrencyEnabled"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `UPDATE currency SET enabled = $2 WHERE code = $1`
+
+	tag, err := s.Client.Exec(ctx, q, code, enabled)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return wallet2.ErrCurrencyNotFound
+	}
+	return nil
+}
+
+// GetFeeRules returns the fee tiers of all pairs, ordered by pair and tier.
+func (s *Storage) GetFeeRules(ctx context.Context) ([]wallet2.FeeRule, error) {
+	const op = "wallet.db.GetFeeRules"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT r.from_currency,
+       			 r.to_currency,
+       			 c.precision,
+       			 r.min_amount::TEXT,
+       			 r.spread_percent::TEXT,
+       			 r.fixed_fee::TEXT,
+       			 r.min_fee::TEXT
+		  FROM exchange_fee_rule r
+		  JOIN currency c ON c.code = r.from_currency
+		  ORDER BY r.from_currency, r.to_currency, r.min_amount`
+
+	rows, err := s.Client.Query(ctx, q)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []wallet2.FeeRule
+	for rows.Next() {
+		var r wallet2.FeeRule
+		var c wallet2.Currency
+		var min, percent, fixed, minFee string
+		err = rows.Scan(&r.FromCurrency, &r.ToCurrency, &c.Precision, &min, &percent, &fixed, &minFee)
+		if err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		c.Code = r.FromCurrency
+		r.SpreadPercent = wallet2.Decimal(trimZeros(percent))
+		if r.MinAmount, err = currencyAmount(min, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		if r.FixedFee, err = currencyAmount(fixed, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		if r.MinFee, err = currencyAmount(minFee, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *Storage) ReplaceFeeRules(ctx context.Context, fromCurrency, toCurrency string, rules []wallet2.FeeRule) error {
+	const op = "wallet.db.ReplaceFeeRules"
+	log := s.logger.With(slog.String("op", op))
+
+	tx, err := s.Client.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := `DELETE FROM exchange_fee_rule WHERE from_currency = $1 AND to_currency = $2`
+	if _, err = tx.Exec(ctx, q, fromCurrency, toCurrency); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	q = `INSERT INTO exchange_fee_rule(from_currency, to_currency, min_amount, spread_percent, fixed_fee, min_fee)
+		 VALUES ($1, $2, $3::NUMERIC, $4::NUMERIC, $5::NUMERIC, $6::NUMERIC)`
+	for _, r := range rules {
+		_, err = tx.Exec(ctx, q, fromCurrency, toCurrency,
+			string(r.MinAmount), string(r.SpreadPercent), string(r.FixedFee), string(r.MinFee))
+		if err != nil {
+			log.Error(err.Error())
+			return err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func currencyAmount(value string, c wallet2.Currency) (wallet2.Decimal, error) {
+	if value == "" {
+		return "", nil
+	}
+	m, err := wallet2.ParseMoney(value, c)
+	if err != nil {
+		return "", err
+	}
+	return wallet2.Decimal(m.String()), nil
+}
+
+// trimZeros drops insignificant trailing zeros of a decimal, e.g. "0.5000".
+func trimZeros(value string) string {
+	if !strings.Contains(value, ".") {
+		return value
+	}
+	return strings.TrimSuffix(strings.TrimRight(value, "0"), ".")
+}
+
+// ApplyTransactions posts the given entries to the ledger and applies their
+// amounts to the account balances in a single database transaction (a
+// savepoint when called within WithinTransaction). Balances are only ever
+// changed by the amount of an entry written alongside. All entries share one
+// operation id, the database checks that they are balanced on commit.
+//
+// Every operation in a currency updates the same system account, so system
+// accounts are updated after the user accounts and in a fixed order, which
+// keeps concurrent operations from deadlocking on them.
+func (s *Storage) ApplyTransactions(ctx context.Context, entries []wallet2.Transaction) ([]wallet2.Transaction, error) {
+	const op = "wallet.db.ApplyTransactions"
+	log := s.logger.With(slog.String("op", op))
+
+	tx, err := s.Client.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var operationID string
+	if err = tx.QueryRow(ctx, `SELECT gen_random_uuid()`).Scan(&operationID); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := entries[order[i]], entries[order[j]]
+		if (a.AccountType == "") != (b.AccountType == "") {
+			return a.AccountType == ""
+		}
+		if a.AccountType != b.AccountType {
+			return a.AccountType < b.AccountType
+		}
+		return a.AccountType != "" && a.Currency < b.Currency
+	})
+
+	applied := make([]wallet2.Transaction, len(entries))
+	for _, i := range order {
+		e := entries[i]
+		accountType, conflict := wallet2.AccountUser, userAccountConflict
+		if e.AccountType != "" {
+			accountType, conflict = e.AccountType, systemAccountConflict
+		}
+		q := fmt.Sprintf(`WITH a AS (
+			  INSERT INTO account(type, wallet_id, currency, balance)
+			  VALUES ($1, NULLIF($2, '')::UUID, $3, $4)
+			  ON CONFLICT %s
+			  DO UPDATE SET balance = account.balance + EXCLUDED.balance
+			  RETURNING id, wallet_id, balance
+		  )
+		  INSERT INTO wallet_transaction(
+		              account_id,
+		              wallet_id,
+		              operation_id,
+		              type,
+		              currency,
+		              amount,
+		              balance_after,
+		              counterparty_wallet_id)
+		  SELECT id, wallet_id, $5, $6, $3, $4, balance, NULLIF($7, '')::UUID FROM a
+		  RETURNING id, balance_after::TEXT, created_at`, conflict)
+
+		e.OperationUUID = operationID
+		var balance string
+		err = tx.QueryRow(ctx, q, accountType, e.WalletUUID, e.Currency, e.Amount.String(), operationID, e.Type, e.CounterpartyWalletUUID).
+			Scan(&e.UUID, &balance, &e.CreatedAt)
+		if err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		currency := wallet2.Currency{Code: e.Currency, Precision: e.Amount.Precision}
+		if e.BalanceAfter, err = wallet2.ParseMoney(balance, currency); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		applied[i] = e
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return applied, nil
+}
+
+// GetAccountBalances sums the balances and the postings of the accounts by
+// type and currency.
+func (s *Storage) GetAccountBalances(ctx context.Context) ([]wallet2.AccountBalance, error) {
+	const op = "wallet.db.GetAccountBalances"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT a.type,
+       			 a.currency,
+       			 c.precision,
+       			 SUM(a.balance)::TEXT,
+       			 SUM(COALESCE(p.amount, 0))::TEXT
+		  FROM account a
+		  JOIN currency c ON c.code = a.currency
+		  LEFT JOIN (SELECT account_id, SUM(amount) AS amount
+		             FROM wallet_transaction
+		             GROUP BY account_id) p ON p.account_id = a.id
+		  GROUP BY a.type, a.currency, c.precision
+		  ORDER BY a.currency, a.type`
+
+	rows, err := s.Client.Query(ctx, q)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []wallet2.AccountBalance
+	for rows.Next() {
+		var b wallet2.AccountBalance
+		var c wallet2.Currency
+		var balance, postings string
+		if err = rows.Scan(&b.AccountType, &c.Code, &c.Precision, &balance, &postings); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		b.Currency = c.Code
+		if b.Balance, err = wallet2.ParseMoney(balance, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		if b.Postings, err = wallet2.ParseMoney(postings, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return balances, nil
+}
+
+// CountUnbalancedEntries returns the number of journal entries whose postings
+// don't sum to zero in some currency.
+func (s *Storage) CountUnbalancedEntries(ctx context.Context) (int, error) {
+	const op = "wallet.db.CountUnbalancedEntries"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT COUNT(DISTINCT operation_id)
+		  FROM (SELECT operation_id
+		        FROM wallet_transaction
+		        GROUP BY operation_id, currency
+		        HAVING SUM(amount) <> 0) u`
+
+	var n int
+	if err := s.Client.QueryRow(ctx, q).Scan(&n); err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetTransactions returns a page of the user's ledger entries matching the
+// filter, using keyset pagination over the sort key and the entry id.
+func (s *Storage) GetTransactions(ctx context.Context, userID string, filter wallet2.TransactionFilter) ([]wallet2.Transaction, error) {
+	const op = "wallet.db.GetTransactions"
+	log := s.logger.With(slog.String("op", op))
+
+	sortKey := "t.created_at"
+	if filter.SortBy == wallet2.SortByAmount {
+		sortKey = "ABS(t.amount)"
+	}
+	order, cmp := "ASC", ">"
+	if filter.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"w.user_id = $1"}
+	if filter.Currency != "" {
+		conds = append(conds, "t.currency = "+arg(filter.Currency))
+	}
+	if filter.Type != "" {
+		conds = append(conds, "t.type = "+arg(filter.Type))
+	}
+	if filter.MinAmount != "" {
+		conds = append(conds, "ABS(t.amount) >= "+arg(string(filter.MinAmount))+"::NUMERIC")
+	}
+	if filter.MaxAmount != "" {
+		conds = append(conds, "ABS(t.amount) <= "+arg(string(filter.MaxAmount))+"::NUMERIC")
+	}
+	if !filter.From.IsZero() {
+		conds = append(conds, "t.created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "t.created_at < "+arg(filter.To))
+	}
+	if filter.After != nil {
+		cast := "::TIMESTAMPTZ"
+		if filter.SortBy == wallet2.SortByAmount {
+			cast = "::NUMERIC"
+		}
+		conds = append(conds, fmt.Sprintf("(%s, t.id) %s (%s%s, %s::UUID)",
+			sortKey, cmp, arg(filter.After.Value), cast, arg(filter.After.ID)))
+	}
+
+	q := fmt.Sprintf(`SELECT t.id,
+       			 t.wallet_id,
+       			 t.operation_id,
+       			 t.type,
+       			 t.currency,
+       			 c.precision,
+       			 t.amount::TEXT,
+       			 t.balance_after::TEXT,
+       			 COALESCE(t.counterparty_wallet_id::TEXT, ''),
+       			 t.created_at
+		  FROM wallet_transaction t
+		  JOIN wallet w ON w.id = t.wallet_id
+		  JOIN currency c ON c.code = t.currency
+		  WHERE %s
+		  ORDER BY %s %s, t.id %s
+		  LIMIT %s`, strings.Join(conds, " AND "), sortKey, order, order, arg(filter.Limit))
+
+	rows, err := s.Client.Query(ctx, q, args...)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]wallet2.Transaction, 0, filter.Limit)
+	for rows.Next() {
+		var t wallet2.Transaction
+		var c wallet2.Currency
+		var amount, balance string
+		err = rows.Scan(
+			&t.UUID,
+			&t.WalletUUID,
+			&t.OperationUUID,
+			&t.Type,
+			&c.Code,
+			&c.Precision,
+			&amount,
+			&balance,
+			&t.CounterpartyWalletUUID,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		t.Currency = c.Code
+		if t.Amount, err = wallet2.ParseMoney(amount, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		if t.BalanceAfter, err = wallet2.ParseMoney(balance, c); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (s *Storage) AddEvent(ctx context.Context, e outbox.Event) error {
+	const op = "wallet.db.AddEvent"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `INSERT INTO outbox_event(type, aggregate_id, payload) VALUES ($1, $2, $3)`
+	_, err := s.Client.Exec(ctx, q, e.Type, e.AggregateID, []byte(e.Payload))
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) AddExchangeRates(ctx context.Context, rates []wallet2.ExchangeRate) error {
+	const op = "wallet.db.AddExchangeRates"
+	log := s.logger.With(slog.String("op", op))
+
+	from := make([]string, len(rates))
+	to := make([]string, len(rates))
+	values := make([]float64, len(rates))
+	fetchedAt := make([]time.Time, len(rates))
+	for i, r := range rates {
+		from[i], to[i], values[i], fetchedAt[i] = r.FromCurrency, r.ToCurrency, float64(r.Rate), r.FetchedAt
+	}
+	q := `INSERT INTO exchange_rate(from_currency, to_currency, rate, fetched_at)
+		  SELECT * FROM unnest($1::VARCHAR[], $2::VARCHAR[], $3::DOUBLE PRECISION[], $4::TIMESTAMPTZ[])`
+	if _, err := s.Client.Exec(ctx, q, from, to, values, fetchedAt); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) GetCandles(ctx context.Context, fromCurrency, toCurrency string, interval time.Duration, from, to time.Time) ([]wallet2.Candle, error) {
+	const op = "wallet.db.GetCandles"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `WITH r AS (
+			SELECT EXTRACT(EPOCH FROM fetched_at)::BIGINT / $3::BIGINT * $3::BIGINT AS bucket, id, rate, fetched_at
+			FROM exchange_rate
+			WHERE from_currency = $1 AND to_currency = $2 AND fetched_at >= $4 AND fetched_at < $5
+		  )
+		  SELECT to_timestamp(bucket),
+		         (array_agg(rate ORDER BY fetched_at, id))[1],
+		         MAX(rate),
+		         MIN(rate),
+		         (array_agg(rate ORDER BY fetched_at DESC, id DESC))[1],
+		         COUNT(*)
+		  FROM r
+		  GROUP BY bucket
+		  ORDER BY bucket`
+
+	rows, err := s.Client.Query(ctx, q, fromCurrency, toCurrency, int64(interval/time.Second), from, to)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candles []wallet2.Candle
+	for rows.Next() {
+		var c wallet2.Candle
+		var openRate, high, low, closeRate float64
+		if err = rows.Scan(&c.OpenTime, &openRate, &high, &low, &closeRate, &c.Count); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		c.OpenTime = c.OpenTime.UTC()
+		c.Open, c.High, c.Low, c.Close = float32(openRate), float32(high), float32(low), float32(closeRate)
+		candles = append(candles, c)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return candles, nil
+}
+
+// SetWalletFreeze freezes the wallet or, with a nil freeze, unfreezes it.
+func (s *Storage) SetWalletFreeze(ctx context.Context, walletID string, f *wallet2.Freeze) error {
+	const op = "wallet.db.SetWalletFreeze"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `UPDATE wallet SET frozen_at = NULL, frozen_by = NULL, freeze_reason = NULL WHERE id = $1`
+	args := []interface{}{walletID}
+	if f != nil {
+		q = `UPDATE wallet SET frozen_at = $2, frozen_by = $3, freeze_reason = $4 WHERE id = $1`
+		args = append(args, f.FrozenAt, f.OperatorUUID, f.Reason)
+	}
+	tag, err := s.Client.Exec(ctx, q, args...)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return wallet2.ErrWalletNotFound
+	}
+	return nil
+}
+
+func (s *Storage) AddAdminAction(ctx context.Context, a wallet2.AdminAction) error {
+	const op = "wallet.db.AddAdminAction"
+	log := s.logger.With(slog.String("op", op))
+
+	var currency, amount *string
+	if a.Amount != nil {
+		c, v := a.Amount.Currency, a.Amount.String()
+		currency, amount = &c, &v
+	}
+	q := `INSERT INTO admin_action(operator_id, action, wallet_id, operation_id, currency, amount, reason)
+		  VALUES ($1, $2, $3, NULLIF($4, '')::UUID, $5, $6::NUMERIC, $7)`
+	_, err := s.Client.Exec(ctx, q, a.OperatorUUID, a.Action, a.WalletUUID, a.OperationUUID, currency, amount, a.Reason)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// GetAdminActions returns the latest actions with the wallet, newest first.
+func (s *Storage) GetAdminActions(ctx context.Context, walletID string, limit int) ([]wallet2.AdminAction, error) {
+	const op = "wallet.db.GetAdminActions"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT a.id,
+       			 a.operator_id,
+       			 a.action,
+       			 a.wallet_id,
+       			 COALESCE(a.operation_id::TEXT, ''),
+       			 a.currency,
+       			 c.precision,
+       			 a.amount::TEXT,
+       			 a.reason,
+       			 a.created_at
+		  FROM admin_action a
+		  LEFT JOIN currency c ON c.code = a.currency
+		  WHERE a.wallet_id = $1
+		  ORDER BY a.created_at DESC, a.id
+		  LIMIT $2`
+
+	rows, err := s.Client.Query(ctx, q, walletID, limit)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []wallet2.AdminAction
+	for rows.Next() {
+		var a wallet2.AdminAction
+		var currency, amount *string
+		var precision *int
+		err = rows.Scan(&a.UUID, &a.OperatorUUID, &a.Action, &a.WalletUUID, &a.OperationUUID,
+			&currency, &precision, &amount, &a.Reason, &a.CreatedAt)
+		if err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		if amount != nil && currency != nil && precision != nil {
+			m, err := wallet2.ParseMoney(*amount, wallet2.Currency{Code: *currency, Precision: *precision})
+			if err != nil {
+				log.Error(err.Error())
+				return nil, err
+			}
+			a.Amount = &m
+		}
+		actions = append(actions, a)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return actions, nil
+}
+
 func (s *Storage) DeleteWallet(ctx context.Context, userID string) error {
 	const op = "wallet.db.DeleteWallet"
 	log := s.logger.With(slog.String("op", op))
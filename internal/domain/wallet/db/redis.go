@@ -47,3 +47,30 @@ func (c *Cache) SetValue(ctx context.Context, key string, value interface{}, ttl
 	}
 	return nil
 }
+
+func (c *Cache) DeleteValue(ctx context.Context, key string) error {
+	const op = "db.redis.DeleteValue"
+	log := c.logger.With(slog.String("op", op))
+
+	err := c.Client.Del(ctx, key).Err()
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) PopValue(ctx context.Context, key string) (string, error) {
+	const op = "db.redis.PopValue"
+	log := c.logger.With(slog.String("op", op))
+
+	val, err := c.Client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return "", err
+	}
+	return val, nil
+}
@@ -1,33 +1,203 @@
 package wallet
 
+import "time"
+
 type UpdateWalletRequest struct {
 	Amount   float64 `json:"amount" validate:"required"`
 	Currency float64 `json:"currency" validate:"required"`
 }
 
-type CurrenciesResponse struct {
-	EUR float32 `json:"EUR"`
-	USD float32 `json:"USD"`
-	RUB float32 `json:"RUB"`
+type BalanceRequest struct {
+	At time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type BalanceResponse struct {
+	Balance map[string]Money `json:"balance" swaggertype:"object,string" example:"USD:100.00"`
 }
 
 type ChangeBalanceRequest struct {
-	Amount   float32 `json:"amount"`
-	Currency string  `json:"currency" validate:"required,oneof=USD EUR RUB"`
+	Amount   Decimal `json:"amount" validate:"required" swaggertype:"string" example:"10.50"`
+	Currency string  `json:"currency" validate:"required,len=3"`
+}
+
+type WithdrawRequest struct {
+	ChangeBalanceRequest
+	// ConfirmationMethod is how to confirm a withdrawal above the threshold,
+	// TOTP if enrolled and a one-time code otherwise by default.
+	ConfirmationMethod string `json:"confirmation_method" validate:"omitempty,oneof=totp otp" example:"totp"`
+}
+
+type ConfirmWithdrawRequest struct {
+	ChallengeID string `json:"challenge_id" validate:"required,uuid"`
+	Code        string `json:"code" validate:"required,numeric,len=6" example:"123456"`
 }
 
 type ExchangeRateResponse struct {
-	Rates map[string]float32 `json:"rates"`
+	Rates  map[string]float32 `json:"rates"`
+	Origin *RateOrigin        `json:"origin,omitempty"`
+}
+
+// RateHistoryRequest selects the candles of Pair over [From, To). To
+// defaults to now, From to a day before To and Interval to 1h.
+type RateHistoryRequest struct {
+	Pair     string    `form:"pair" validate:"required" example:"USD/EUR"`
+	Interval string    `form:"interval" validate:"omitempty,oneof=1m 5m 15m 1h 4h 1d"`
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type RateHistoryResponse struct {
+	Pair     string   `json:"pair"`
+	Interval string   `json:"interval"`
+	Candles  []Candle `json:"candles"`
 }
 
+// ExchangeRequest either describes the exchange or refers to a quote given
+// by the quote endpoint, in which case the other fields are ignored.
 type ExchangeRequest struct {
-	FromCurrency string  `json:"from_currency" validate:"required,oneof=USD EUR RUB"`
-	ToCurrency   string  `json:"to_currency" validate:"required,oneof=USD EUR RUB"`
-	Amount       float32 `json:"amount"`
+	QuoteID      string  `json:"quote_id" validate:"omitempty,uuid"`
+	FromCurrency string  `json:"from_currency" validate:"required_without=QuoteID,omitempty,len=3"`
+	ToCurrency   string  `json:"to_currency" validate:"required_without=QuoteID,omitempty,len=3"`
+	Amount       Decimal `json:"amount" validate:"required_without=QuoteID" swaggertype:"string" example:"10.50"`
+}
+
+type QuoteRequest struct {
+	FromCurrency string  `json:"from_currency" validate:"required,len=3"`
+	ToCurrency   string  `json:"to_currency" validate:"required,len=3"`
+	Amount       Decimal `json:"amount" validate:"required" swaggertype:"string" example:"10.50"`
 }
 
 type ExchangeResponse struct {
-	Message         string             `json:"message"`
-	ExchangedAmount float32            `json:"exchanged_amount"`
-	NewBalance      map[string]float32 `json:"new_balance"`
+	Message         string           `json:"message"`
+	ExchangedAmount Money            `json:"exchanged_amount" swaggertype:"string"`
+	Fee             Fee              `json:"fee"`
+	Rate            float32          `json:"rate"`
+	RateOrigin      *RateOrigin      `json:"rate_origin,omitempty"`
+	NewBalance      map[string]Money `json:"new_balance" swaggertype:"object,string"`
+}
+
+type TransactionsRequest struct {
+	Cursor    string    `form:"cursor"`
+	Limit     int       `form:"limit" validate:"omitempty,min=1,max=100"`
+	Currency  string    `form:"currency" validate:"omitempty,len=3"`
+	Type      string    `form:"type" validate:"omitempty,oneof=deposit withdraw exchange transfer fee adjustment"`
+	MinAmount Decimal   `form:"min_amount" validate:"omitempty,numeric"`
+	MaxAmount Decimal   `form:"max_amount" validate:"omitempty,numeric"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort      string    `form:"sort" validate:"omitempty,oneof=created_at amount"`
+	Order     string    `form:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+type TransactionsResponse struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// StatementRequest selects the period [From, To) of a statement. An empty
+// Currency means all currencies, To defaults to now and Format to csv.
+type StatementRequest struct {
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00" validate:"required"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Currency string    `form:"currency" validate:"omitempty,len=3"`
+	Format   string    `form:"format" validate:"omitempty,oneof=csv pdf"`
+}
+
+type TransferRequest struct {
+	ToUserID   string  `json:"to_user_id" validate:"omitempty,uuid"`
+	ToUsername string  `json:"to_username"`
+	ToEmail    string  `json:"to_email" validate:"omitempty,email"`
+	Amount     Decimal `json:"amount" validate:"required" swaggertype:"string" example:"10.50"`
+	Currency   string  `json:"currency" validate:"required,len=3"`
+	// ConfirmationMethod is how to confirm a transfer above the threshold,
+	// as for withdrawals.
+	ConfirmationMethod string `json:"confirmation_method" validate:"omitempty,oneof=totp otp" example:"totp"`
+}
+
+type ConfirmTransferRequest struct {
+	ChallengeID string `json:"challenge_id" validate:"required,uuid"`
+	Code        string `json:"code" validate:"required,numeric,len=6" example:"123456"`
+}
+
+type UpdateCurrencyRequest struct {
+	Name         string  `json:"name" validate:"required,max=64" example:"US Dollar"`
+	Symbol       string  `json:"symbol" validate:"max=8" example:"$"`
+	Precision    *int    `json:"precision" validate:"required,min=0,max=8" example:"2"`
+	Rounding     string  `json:"rounding" validate:"omitempty,oneof=half_even half_up down" example:"half_even"`
+	MinAmount    Decimal `json:"min_amount" validate:"omitempty,numeric" swaggertype:"string" example:"0.01"`
+	MaxAmount    Decimal `json:"max_amount" validate:"omitempty,numeric" swaggertype:"string" example:"10000.00"`
+	Exchangeable *bool   `json:"exchangeable" example:"true"`
+	// ConfirmationThreshold is the amount withdrawals and transfers above
+	// which must be confirmed with a second factor.
+	ConfirmationThreshold Decimal `json:"confirmation_threshold" validate:"omitempty,numeric" swaggertype:"string" example:"1000.00"`
+}
+
+type CreateCurrencyRequest struct {
+	Code string `json:"code" validate:"required,len=3,uppercase" example:"USD"`
+	UpdateCurrencyRequest
+}
+
+// currency builds a registry entry from the request. Rounding defaults to
+// half_even and currencies are exchangeable unless stated otherwise.
+func (r UpdateCurrencyRequest) currency(code string) Currency {
+	c := Currency{
+		Code:         code,
+		Name:         r.Name,
+		Symbol:       r.Symbol,
+		Precision:    *r.Precision,
+		Rounding:     RoundingMode(r.Rounding),
+		MinAmount:    r.MinAmount,
+		MaxAmount:    r.MaxAmount,
+		Exchangeable: r.Exchangeable == nil || *r.Exchangeable,
+
+		ConfirmationThreshold: r.ConfirmationThreshold,
+	}
+	if c.Rounding == "" {
+		c.Rounding = RoundHalfEven
+	}
+	return c
+}
+
+type CurrenciesResponse struct {
+	Currencies []Currency `json:"currencies"`
+}
+
+type FeeTier struct {
+	MinAmount     Decimal `json:"min_amount" validate:"omitempty,numeric" swaggertype:"string" example:"0"`
+	SpreadPercent Decimal `json:"spread_percent" validate:"omitempty,numeric" swaggertype:"string" example:"0.5"`
+	FixedFee      Decimal `json:"fixed_fee" validate:"omitempty,numeric" swaggertype:"string" example:"0.10"`
+	MinFee        Decimal `json:"min_fee" validate:"omitempty,numeric" swaggertype:"string" example:"0.50"`
+}
+
+type FeeRulesRequest struct {
+	Tiers []FeeTier `json:"tiers" validate:"dive"`
+}
+
+type FeeRulesResponse struct {
+	Rules []FeeRule `json:"rules"`
+}
+
+type TrialBalanceResponse struct {
+	Accounts          []AccountBalance `json:"accounts"`
+	Totals            map[string]Money `json:"totals" swaggertype:"object,string"`
+	UnbalancedEntries int              `json:"unbalanced_entries"`
+	Balanced          bool             `json:"balanced"`
+}
+
+// FreezeRequest gives the reason to freeze or unfreeze a wallet, which is
+// kept in the admin action log.
+type FreezeRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// AdjustmentRequest credits a positive amount to the wallet or debits a
+// negative one.
+type AdjustmentRequest struct {
+	Amount   Decimal `json:"amount" validate:"required" swaggertype:"string" example:"-10.50"`
+	Currency string  `json:"currency" validate:"required,len=3"`
+	Reason   string  `json:"reason" validate:"required,max=500"`
+}
+
+type AdminActionsResponse struct {
+	Actions []AdminAction `json:"actions"`
 }
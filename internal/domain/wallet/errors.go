@@ -5,3 +5,21 @@ import "errors"
 var ErrInvalidAmountOrCurrency = errors.New("invalid amount or currency")
 var ErrSmtWentWrong = errors.New("something went wrong")
 var ErrNotEnoughFunds = errors.New("insufficient funds or invalid currencies")
+var ErrInvalidCursor = errors.New("invalid cursor")
+var ErrInvalidRecipient = errors.New("invalid recipient")
+var ErrInvalidCurrency = errors.New("invalid currency settings")
+var ErrCurrencyExists = errors.New("currency already exists")
+var ErrCurrencyNotFound = errors.New("currency not found")
+var ErrCurrencyDisabled = errors.New("currency is disabled")
+var ErrCurrencyNotExchangeable = errors.New("currency is not exchangeable")
+var ErrAmountOutOfRange = errors.New("amount is out of the allowed range")
+var ErrQuoteExpired = errors.New("quote has expired or was already used")
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+var ErrInvalidTime = errors.New("time must not be in the future")
+var ErrInvalidPeriod = errors.New("invalid period")
+var ErrRatesUnavailable = errors.New("exchange rates are unavailable")
+var ErrWalletNotFound = errors.New("wallet not found")
+var ErrWalletFrozen = errors.New("wallet is frozen")
+var ErrWalletNotFrozen = errors.New("wallet is not frozen")
+var ErrWithdrawalExpired = errors.New("withdrawal has expired or was already confirmed")
+var ErrTransferExpired = errors.New("transfer has expired or was already confirmed")
@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"context"
+	"wallet/internal/domain/outbox"
+)
+
+// Types of the domain events written to the outbox.
+const (
+	EventWalletCreated = "WalletCreated"
+	EventDeposited     = "Deposited"
+	EventWithdrawn     = "Withdrawn"
+	EventExchanged     = "Exchanged"
+	EventTransferred   = "Transferred"
+	// EventTransferReceived is the other side of Transferred, as seen by the
+	// recipient.
+	EventTransferReceived = "TransferReceived"
+	EventWalletFrozen     = "WalletFrozen"
+	EventWalletUnfrozen   = "WalletUnfrozen"
+	EventAdjusted         = "Adjusted"
+)
+
+type WalletCreatedEvent struct {
+	WalletUUID string `json:"wallet_uuid"`
+	UserUUID   string `json:"user_uuid"`
+}
+
+// FundsEvent is the payload of Deposited and Withdrawn events. Amount is
+// always positive, Balance is the balance in its currency after the operation.
+type FundsEvent struct {
+	WalletUUID    string `json:"wallet_uuid"`
+	UserUUID      string `json:"user_uuid"`
+	OperationUUID string `json:"operation_uuid"`
+	Amount        Money  `json:"amount"`
+	Currency      string `json:"currency"`
+	Balance       Money  `json:"balance"`
+}
+
+type ExchangedEvent struct {
+	WalletUUID      string           `json:"wallet_uuid"`
+	UserUUID        string           `json:"user_uuid"`
+	OperationUUID   string           `json:"operation_uuid"`
+	FromCurrency    string           `json:"from_currency"`
+	ToCurrency      string           `json:"to_currency"`
+	Amount          Money            `json:"amount"`
+	ExchangedAmount Money            `json:"exchanged_amount"`
+	Fee             Money            `json:"fee"`
+	Rate            float32          `json:"rate"`
+	RateSource      string           `json:"rate_source"`
+	Balances        map[string]Money `json:"balances"`
+}
+
+// TransferredEvent is the payload of Transferred events, written to the
+// sender's wallet. Balance is the sender's balance after the transfer.
+type TransferredEvent struct {
+	WalletUUID          string `json:"wallet_uuid"`
+	UserUUID            string `json:"user_uuid"`
+	OperationUUID       string `json:"operation_uuid"`
+	RecipientWalletUUID string `json:"recipient_wallet_uuid"`
+	RecipientUserUUID   string `json:"recipient_user_uuid"`
+	Amount              Money  `json:"amount"`
+	Currency            string `json:"currency"`
+	Balance             Money  `json:"balance"`
+}
+
+// TransferReceivedEvent is the payload of TransferReceived events, written to
+// the recipient's wallet. Balance is the recipient's balance after the
+// transfer; the events go to the owner of the wallet, so neither carries the
+// balance of the other side.
+type TransferReceivedEvent struct {
+	WalletUUID       string `json:"wallet_uuid"`
+	UserUUID         string `json:"user_uuid"`
+	OperationUUID    string `json:"operation_uuid"`
+	SenderWalletUUID string `json:"sender_wallet_uuid"`
+	SenderUserUUID   string `json:"sender_user_uuid"`
+	Amount           Money  `json:"amount"`
+	Currency         string `json:"currency"`
+	Balance          Money  `json:"balance"`
+}
+
+// WalletFreezeEvent is the payload of WalletFrozen and WalletUnfrozen events.
+type WalletFreezeEvent struct {
+	WalletUUID   string `json:"wallet_uuid"`
+	UserUUID     string `json:"user_uuid"`
+	OperatorUUID string `json:"operator_uuid"`
+	Reason       string `json:"reason"`
+}
+
+// AdjustedEvent describes a manual adjustment. Amount is signed: positive
+// amounts are credited to the wallet, negative ones are debited.
+type AdjustedEvent struct {
+	WalletUUID    string `json:"wallet_uuid"`
+	UserUUID      string `json:"user_uuid"`
+	OperationUUID string `json:"operation_uuid"`
+	OperatorUUID  string `json:"operator_uuid"`
+	Amount        Money  `json:"amount"`
+	Currency      string `json:"currency"`
+	Balance       Money  `json:"balance"`
+	Reason        string `json:"reason"`
+}
+
+// addEvent writes the event of the wallet to the outbox. It must be called
+// with the storage of the transaction that makes the change, so the event is
+// stored if and only if the change is.
+func addEvent(ctx context.Context, st Storage, eventType, walletID string, payload interface{}) error {
+	e, err := outbox.NewEvent(eventType, walletID, payload)
+	if err != nil {
+		return err
+	}
+	return st.AddEvent(ctx, e)
+}
@@ -0,0 +1,181 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"time"
+)
+
+const (
+	feeRulesCacheKey = "exchange_fee_rules"
+	feeRulesCacheTTL = time.Minute
+)
+
+// FeeRule is a tier of the exchange fee of a currency pair. It applies to
+// amounts from MinAmount up to the MinAmount of the next tier. Amounts are in
+// the source currency.
+type FeeRule struct {
+	FromCurrency  string  `json:"from_currency"`
+	ToCurrency    string  `json:"to_currency"`
+	MinAmount     Decimal `json:"min_amount" swaggertype:"string" example:"0"`
+	SpreadPercent Decimal `json:"spread_percent" swaggertype:"string" example:"0.5"`
+	FixedFee      Decimal `json:"fixed_fee" swaggertype:"string" example:"0.10"`
+	MinFee        Decimal `json:"min_fee" swaggertype:"string" example:"0.50"`
+}
+
+// Fee is the charge for an exchange in the source currency. Total is Spread
+// plus Fixed, raised to MinFee if lower.
+type Fee struct {
+	SpreadPercent Decimal `json:"spread_percent" swaggertype:"string"`
+	Spread        Money   `json:"spread" swaggertype:"string"`
+	Fixed         Money   `json:"fixed" swaggertype:"string"`
+	MinFee        Money   `json:"min_fee" swaggertype:"string"`
+	Total         Money   `json:"total" swaggertype:"string"`
+}
+
+// apply calculates the fee of the rule for amount in currency c.
+func (r FeeRule) apply(amount Money, c Currency) (Fee, error) {
+	spread, err := amount.Percent(r.SpreadPercent, c.Rounding)
+	if err != nil {
+		return Fee{}, err
+	}
+	fixed, err := ParseMoney(string(r.FixedFee), c)
+	if err != nil {
+		return Fee{}, err
+	}
+	minFee, err := ParseMoney(string(r.MinFee), c)
+	if err != nil {
+		return Fee{}, err
+	}
+	total := spread.Add(fixed)
+	if total.Sub(minFee).IsNegative() {
+		total = minFee
+	}
+	return Fee{SpreadPercent: r.SpreadPercent, Spread: spread, Fixed: fixed, MinFee: minFee, Total: total}, nil
+}
+
+// GetFeeRules returns the fee tiers of all currency pairs. Like the currency
+// registry they are cached for feeRulesCacheTTL.
+func (s *ServiceWallet) GetFeeRules(ctx context.Context) ([]FeeRule, error) {
+	const op = "wallet.GetFeeRules"
+	log := s.logger.With(slog.String("op", op))
+
+	cached, _ := s.cache.GetValue(ctx, feeRulesCacheKey)
+	if cached != "" {
+		var rules []FeeRule
+		if err := json.Unmarshal([]byte(cached), &rules); err == nil {
+			return rules, nil
+		}
+	}
+	rules, err := s.storage.GetFeeRules(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	jsonData, err := json.Marshal(rules)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	_ = s.cache.SetValue(ctx, feeRulesCacheKey, string(jsonData), feeRulesCacheTTL)
+	return rules, nil
+}
+
+// SetFeeRules replaces the fee tiers of the currency pair. With no tiers
+// exchanges of the pair are free.
+func (s *ServiceWallet) SetFeeRules(ctx context.Context, fromCurrency, toCurrency string, rules []FeeRule) ([]FeeRule, error) {
+	const op = "wallet.SetFeeRules"
+	log := s.logger.With(slog.String("op", op))
+
+	from, err := s.currency(ctx, fromCurrency)
+	if err != nil {
+		return nil, ErrInvalidCurrency
+	}
+	if _, err = s.currency(ctx, toCurrency); err != nil || fromCurrency == toCurrency {
+		return nil, ErrInvalidCurrency
+	}
+	tiers := make(map[int64]bool, len(rules))
+	for i := range rules {
+		r := &rules[i]
+		r.FromCurrency, r.ToCurrency = fromCurrency, toCurrency
+		min, err := validateFeeRule(r, from)
+		if err != nil {
+			return nil, err
+		}
+		if tiers[min.Minor] {
+			return nil, ErrInvalidCurrency
+		}
+		tiers[min.Minor] = true
+	}
+
+	if err = s.storage.ReplaceFeeRules(ctx, fromCurrency, toCurrency, rules); err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	_ = s.cache.DeleteValue(ctx, feeRulesCacheKey)
+	all, err := s.GetFeeRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pair := make([]FeeRule, 0, len(rules))
+	for _, r := range all {
+		if r.FromCurrency == fromCurrency && r.ToCurrency == toCurrency {
+			pair = append(pair, r)
+		}
+	}
+	return pair, nil
+}
+
+// validateFeeRule fills in zero defaults and checks the rule against the
+// source currency. It returns the lower bound of the tier.
+func validateFeeRule(r *FeeRule, from Currency) (Money, error) {
+	for _, d := range []*Decimal{&r.MinAmount, &r.SpreadPercent, &r.FixedFee, &r.MinFee} {
+		if *d == "" {
+			*d = "0"
+		}
+	}
+	percent, ok := new(big.Rat).SetString(string(r.SpreadPercent))
+	if !ok || !decimalPattern.MatchString(string(r.SpreadPercent)) ||
+		percent.Sign() < 0 || percent.Cmp(big.NewRat(100, 1)) >= 0 {
+		return Money{}, ErrInvalidCurrency
+	}
+	for _, d := range []Decimal{r.FixedFee, r.MinFee} {
+		if m, err := ParseMoney(string(d), from); err != nil || m.IsNegative() {
+			return Money{}, ErrInvalidCurrency
+		}
+	}
+	min, err := ParseMoney(string(r.MinAmount), from)
+	if err != nil || min.IsNegative() {
+		return Money{}, ErrInvalidCurrency
+	}
+	return min, nil
+}
+
+// exchangeFee returns the fee for exchanging amount, using the tier of the
+// pair the amount falls into. Pairs without rules are free.
+func (s *ServiceWallet) exchangeFee(ctx context.Context, amount Money, from, to Currency) (Fee, error) {
+	rules, err := s.GetFeeRules(ctx)
+	if err != nil {
+		return Fee{}, err
+	}
+	fee := Fee{SpreadPercent: "0", Spread: from.Zero(), Fixed: from.Zero(), MinFee: from.Zero(), Total: from.Zero()}
+	var tier Money
+	for _, r := range rules {
+		if r.FromCurrency != from.Code || r.ToCurrency != to.Code {
+			continue
+		}
+		min, err := ParseMoney(string(r.MinAmount), from)
+		if err != nil {
+			return Fee{}, err
+		}
+		if amount.Sub(min).IsNegative() || min.Sub(tier).IsNegative() {
+			continue
+		}
+		if fee, err = r.apply(amount, from); err != nil {
+			return Fee{}, err
+		}
+		tier = min
+	}
+	return fee, nil
+}
@@ -1,11 +1,14 @@
 package wallet
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"net/http"
+	"wallet/internal/domain/stepup"
 )
 
 type ExchangeRateGetter interface {
@@ -14,15 +17,23 @@ type ExchangeRateGetter interface {
 
 // GetWalletBalanceHandler godoc
 // @Summary      Get wallet balance
-// @Description  Retrieve the balance of the user's wallet
+// @Description  Retrieve the balance of the user's wallet, now or at a past instant
 // @Tags         wallet
 // @Accept       json
 // @Produce      json
 // @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
-// @Success      200  {object}  CurrenciesResponse
+// @Param        at  query     string  false  "Instant to get the balance at (RFC3339), defaults to now"
+// @Success      200  {object}  BalanceResponse
+// @Failure      400  {object}  map[string]string  "invalid request or time in the future"
+// @Failure      404  {object}  map[string]string  "wallet not found"
 // @Router       /api/v1/wallet/balance/ [get]
 func GetWalletBalanceHandler(s Service) func(c *gin.Context) {
 	return func(c *gin.Context) {
+		var req BalanceRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
 		userID, exists := c.Get("userID")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
@@ -33,21 +44,155 @@ func GetWalletBalanceHandler(s Service) func(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
 			return
 		}
-		w, err := s.GetBalance(context.Background(), userIDStr)
+		var w Wallet
+		var err error
+		if req.At.IsZero() {
+			w, err = s.GetBalance(context.Background(), userIDStr)
+		} else {
+			w, err = s.GetBalanceAt(context.Background(), userIDStr, req.At)
+		}
 		if err != nil {
 			writeJSONError(c, err)
 			return
 		}
 
-		balance := CurrenciesResponse{
-			EUR: w.BalanceEUR,
-			USD: w.BalanceUSD,
-			RUB: w.BalanceRUB,
+		c.JSON(http.StatusOK, BalanceResponse{Balance: w.Balances})
+	}
+}
+
+// GetTransactionsHandler godoc
+// @Summary      Get wallet transactions
+// @Description  Retrieve the user's wallet transactions with cursor pagination, filtering and sorting. Amount filters and sorting apply to the absolute amount.
+// @Tags         wallet
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        cursor      query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        limit       query     int     false  "Page size (1-100, default 20)"
+// @Param        currency    query     string  false  "Currency code"
+// @Param        type        query     string  false  "Transaction type"  Enums(deposit, withdraw, exchange, transfer, fee, adjustment)
+// @Param        min_amount  query     number  false  "Minimum amount"
+// @Param        max_amount  query     number  false  "Maximum amount"
+// @Param        from        query     string  false  "Start of the period, inclusive (RFC3339)"
+// @Param        to          query     string  false  "End of the period, exclusive (RFC3339)"
+// @Param        sort        query     string  false  "Sort key (default created_at)"  Enums(created_at, amount)
+// @Param        order       query     string  false  "Sort order (default desc)"  Enums(asc, desc)
+// @Success      200      {object}  TransactionsResponse
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid cursor"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/wallet/transactions/ [get]
+func GetTransactionsHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req TransactionsRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
 		}
-		res := map[string]CurrenciesResponse{
-			"balance": balance,
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
 		}
-		c.JSON(http.StatusOK, res)
+		userIDStr, ok := userID.(string)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+			return
+		}
+		writeTransactions(c, req, func(filter TransactionFilter) ([]Transaction, *TransactionCursor, error) {
+			return s.GetTransactions(context.Background(), userIDStr, filter)
+		})
+	}
+}
+
+// GetStatementHandler godoc
+// @Summary      Get wallet statement
+// @Description  Download the statement of a period with the opening balance, every transaction with the running balance and the closing balance per currency
+// @Tags         wallet
+// @Produce      text/csv
+// @Produce      application/pdf
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        from      query     string  true   "Start of the period (RFC3339), inclusive"
+// @Param        to        query     string  false  "End of the period (RFC3339), exclusive, defaults to now"
+// @Param        currency  query     string  false  "Currency code, all currencies if empty"
+// @Param        format    query     string  false  "Statement format"  Enums(csv, pdf)
+// @Success      200  {file}    file
+// @Failure      400  {object}  map[string]interface{}  "Validation failed"
+// @Failure      401  {object}  map[string]string       "user not found"
+// @Failure      404  {object}  map[string]string       "wallet not found"
+// @Failure      500  {object}  map[string]string       "internal server error"
+// @Router       /api/v1/wallet/statement/ [get]
+func GetStatementHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req StatementRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		userIDStr, ok := userID.(string)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+			return
+		}
+
+		st, err := s.GetStatement(context.Background(), userIDStr, req.Currency, req.From, req.To)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		var buf bytes.Buffer
+		contentType := "text/csv"
+		if req.Format == "pdf" {
+			contentType = "application/pdf"
+			err = WriteStatementPDF(&buf, st)
+		} else {
+			req.Format = "csv"
+			err = WriteStatementCSV(&buf, st)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+			return
+		}
+		filename := fmt.Sprintf("statement_%s_%s.%s", st.From.UTC().Format("20060102"), st.To.UTC().Format("20060102"), req.Format)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, contentType, buf.Bytes())
 	}
 }
 
@@ -59,16 +204,19 @@ func GetWalletBalanceHandler(s Service) func(c *gin.Context) {
 // @Produce      json
 // @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
 // @Param        request  body      ChangeBalanceRequest  true  "Deposit request"
+// @Param        Idempotency-Key  header  string  false  "Unique key to safely retry the request"
 // @Success      200      {object}  map[string]interface{}
 // @Failure      400      {object}  map[string]interface{}  "Validation failed"
 // @Failure      401      {object}  map[string]string       "userID not found in context"
+// @Failure      409      {object}  map[string]string       "request with this Idempotency-Key is in progress"
+// @Failure      422      {object}  map[string]string       "Idempotency-Key reused with a different request"
 // @Failure      500      {object}  map[string]string       "internal server error"
 // @Router       /api/v1/wallet/deposit/ [post]
 func UpdateWalletBalanceDeposit(s Service, v *validator.Validate) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var req ChangeBalanceRequest
 		if err := c.ShouldBind(&req); err != nil {
-			writeJSONError(c, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 			return
 		}
 
@@ -97,19 +245,19 @@ func UpdateWalletBalanceDeposit(s Service, v *validator.Validate) func(c *gin.Co
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "userID is not a valid string"})
 			return
 		}
-		w, err := s.WalletDeposit(context.Background(), userIDStr, req.Amount, req.Currency)
+		amount, err := s.ParseAmount(context.Background(), req.Amount, req.Currency)
 		if err != nil {
 			writeJSONError(c, err)
 			return
 		}
-		balance := CurrenciesResponse{
-			EUR: w.BalanceEUR,
-			USD: w.BalanceUSD,
-			RUB: w.BalanceRUB,
+		w, err := s.WalletDeposit(context.Background(), userIDStr, amount)
+		if err != nil {
+			writeJSONError(c, err)
+			return
 		}
 		res := map[string]interface{}{
 			"message":     "Account topped up successfully",
-			"new_balance": balance,
+			"new_balance": w.Balances,
 		}
 		c.JSON(http.StatusOK, res)
 	}
@@ -117,22 +265,27 @@ func UpdateWalletBalanceDeposit(s Service, v *validator.Validate) func(c *gin.Co
 
 // UpdateWalletBalanceWithdraw godoc
 // @Summary      Withdraw money from wallet
-// @Description  Deduct a specified amount from the user's wallet
+// @Description  Deduct a specified amount from the user's wallet. Amounts above the confirmation threshold of the currency are not withdrawn right away: the response is a challenge to confirm the withdrawal with at /api/v1/wallet/withdraw/confirm/ before it expires.
 // @Tags         wallet
 // @Accept       json
 // @Produce      json
 // @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
-// @Param        request  body      ChangeBalanceRequest  true  "Withdraw request"
+// @Param        request  body      WithdrawRequest  true  "Withdraw request"
+// @Param        Idempotency-Key  header  string  false  "Unique key to safely retry the request"
 // @Success      200      {object}  map[string]interface{}
-// @Failure      400      {object}  map[string]interface{}  "Validation failed"
+// @Success      202      {object}  PendingWithdrawal
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or totp is not enrolled"
 // @Failure      401      {object}  map[string]string       "userID not found in context"
+// @Failure      403      {object}  map[string]string       "wallet is frozen"
+// @Failure      409      {object}  map[string]string       "request with this Idempotency-Key is in progress"
+// @Failure      422      {object}  map[string]string       "Idempotency-Key reused with a different request"
 // @Failure      500      {object}  map[string]string       "internal server error"
 // @Router       /api/v1/wallet/withdraw/ [post]
 func UpdateWalletBalanceWithdraw(s Service, v *validator.Validate) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		var req ChangeBalanceRequest
+		var req WithdrawRequest
 		if err := c.ShouldBind(&req); err != nil {
-			writeJSONError(c, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 			return
 		}
 
@@ -161,62 +314,109 @@ func UpdateWalletBalanceWithdraw(s Service, v *validator.Validate) func(c *gin.C
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "userID is not a valid string"})
 			return
 		}
-		w, err := s.WalletWithdraw(context.Background(), userIDStr, req.Amount, req.Currency)
+		amount, err := s.ParseAmount(context.Background(), req.Amount, req.Currency)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		w, pending, err := s.RequestWithdraw(context.Background(), userIDStr, amount, stepup.Method(req.ConfirmationMethod))
 		if err != nil {
 			writeJSONError(c, err)
 			return
 		}
-		balance := CurrenciesResponse{
-			EUR: w.BalanceEUR,
-			USD: w.BalanceUSD,
-			RUB: w.BalanceRUB,
+		if pending != nil {
+			c.JSON(http.StatusAccepted, pending)
+			return
 		}
 		res := map[string]interface{}{
 			"message":     "Withdrawal successful",
-			"new_balance": balance,
+			"new_balance": w.Balances,
 		}
 		c.JSON(http.StatusOK, res)
 	}
 }
 
-// GetExchangeRates godoc
-// @Summary      Get exchange rates
-// @Description  Retrieve the latest exchange rates for supported currencies
-// @Tags         exchange
+// ConfirmWithdrawHandler godoc
+// @Summary      Confirm withdrawal
+// @Description  Make a withdrawal above the confirmation threshold with the code of its challenge: the current code of the authenticator app or the one-time code sent to the user. The challenge is dropped after too many wrong codes.
+// @Tags         wallet
 // @Accept       json
 // @Produce      json
 // @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
-// @Success      200  {object}  map[string]interface{}
-// @Failure      500  {object}  map[string]string  "internal server error"
-// @Router       /api/v1/exchange/rates/ [get]
-func GetExchangeRates(s Service) func(c *gin.Context) {
+// @Param        request  body      ConfirmWithdrawRequest  true  "Challenge and code"
+// @Param        Idempotency-Key  header  string  false  "Unique key to safely retry the request"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]interface{}  "Validation failed, invalid code or insufficient funds"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      403      {object}  map[string]string       "wallet is frozen"
+// @Failure      409      {object}  map[string]string       "request with this Idempotency-Key is in progress"
+// @Failure      410      {object}  map[string]string       "withdrawal has expired or was already confirmed"
+// @Failure      422      {object}  map[string]string       "Idempotency-Key reused with a different request"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/wallet/withdraw/confirm/ [post]
+func ConfirmWithdrawHandler(s Service, v *validator.Validate) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		res, err := s.GetExchangeRates(context.Background())
+		var req ConfirmWithdrawRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		w, err := s.ConfirmWithdraw(context.Background(), userID, req.ChallengeID, req.Code)
 		if err != nil {
 			writeJSONError(c, err)
+			return
+		}
+		res := map[string]interface{}{
+			"message":     "Withdrawal successful",
+			"new_balance": w.Balances,
 		}
 		c.JSON(http.StatusOK, res)
 	}
 }
 
-// ExchangeRatesForCurrency godoc
-// @Summary      Exchange currency
-// @Description  Exchange a specified amount from one currency to another
-// @Tags         exchange
+// TransferHandler godoc
+// @Summary      Transfer money to another user
+// @Description  Move a specified amount from the user's wallet to another user's wallet identified by exactly one of user ID, username or email. Amounts above the confirmation threshold of the currency are not sent right away: the response is a challenge to confirm the transfer with at /api/v1/wallet/transfer/confirm/ before it expires.
+// @Tags         wallet
 // @Accept       json
 // @Produce      json
 // @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
-// @Param        request  body      ExchangeRequest  true  "Exchange request"
+// @Param        request  body      TransferRequest  true  "Transfer request"
+// @Param        Idempotency-Key  header  string  false  "Unique key to safely retry the request"
 // @Success      200      {object}  map[string]interface{}
-// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid request"
-// @Failure      401      {object}  map[string]string       "user not found"
+// @Success      202      {object}  PendingTransfer
+// @Failure      400      {object}  map[string]interface{}  "Validation failed, invalid recipient, insufficient funds or totp is not enrolled"
+// @Failure      401      {object}  map[string]string       "userID not found in context"
+// @Failure      403      {object}  map[string]string       "wallet is frozen"
+// @Failure      409      {object}  map[string]string       "request with this Idempotency-Key is in progress"
+// @Failure      422      {object}  map[string]string       "Idempotency-Key reused with a different request"
 // @Failure      500      {object}  map[string]string       "internal server error"
-// @Router       /api/v1/exchange/ [post]
-func ExchangeRatesForCurrency(s Service, v *validator.Validate) func(c *gin.Context) {
+// @Router       /api/v1/wallet/transfer/ [post]
+func TransferHandler(s Service, v *validator.Validate) func(c *gin.Context) {
 	return func(c *gin.Context) {
-		var req ExchangeRequest
+		var req TransferRequest
 		if err := c.ShouldBind(&req); err != nil {
-			writeJSONError(c, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 			return
 		}
 
@@ -235,43 +435,944 @@ func ExchangeRatesForCurrency(s Service, v *validator.Validate) func(c *gin.Cont
 			})
 			return
 		}
-		if req.ToCurrency == req.FromCurrency {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		to := Recipient{UserID: req.ToUserID, Username: req.ToUsername, Email: req.ToEmail}
+		if countNonEmpty(to.UserID, to.Username, to.Email) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of to_user_id, to_username and to_email is required"})
 			return
 		}
 		userID, exists := c.Get("userID")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "userID not found in context"})
 			return
 		}
 		userIDStr, ok := userID.(string)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "userID is not a valid string"})
+			return
+		}
+		amount, err := s.ParseAmount(context.Background(), req.Amount, req.Currency)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		w, pending, err := s.RequestTransfer(context.Background(), userIDStr, to, amount, stepup.Method(req.ConfirmationMethod))
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		if pending != nil {
+			c.JSON(http.StatusAccepted, pending)
 			return
 		}
+		res := map[string]interface{}{
+			"message":     "Transfer successful",
+			"new_balance": w.Balances,
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
 
-		r, err := s.ExchangeCurrency(
-			context.Background(),
-			userIDStr,
-			req.Amount,
-			req.FromCurrency,
-			req.ToCurrency,
-		)
+// ConfirmTransferHandler godoc
+// @Summary      Confirm transfer
+// @Description  Send a transfer above the confirmation threshold with the code of its challenge: the current code of the authenticator app or the one-time code sent to the user. The challenge is dropped after too many wrong codes.
+// @Tags         wallet
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      ConfirmTransferRequest  true  "Challenge and code"
+// @Param        Idempotency-Key  header  string  false  "Unique key to safely retry the request"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]interface{}  "Validation failed, invalid code or insufficient funds"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      403      {object}  map[string]string       "wallet is frozen"
+// @Failure      409      {object}  map[string]string       "request with this Idempotency-Key is in progress"
+// @Failure      410      {object}  map[string]string       "transfer has expired or was already confirmed"
+// @Failure      422      {object}  map[string]string       "Idempotency-Key reused with a different request"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/wallet/transfer/confirm/ [post]
+func ConfirmTransferHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req ConfirmTransferRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		w, err := s.ConfirmTransfer(context.Background(), userID, req.ChallengeID, req.Code)
 		if err != nil {
 			writeJSONError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, r)
+		res := map[string]interface{}{
+			"message":     "Transfer successful",
+			"new_balance": w.Balances,
+		}
+		c.JSON(http.StatusOK, res)
 	}
 }
 
-func writeJSONError(c *gin.Context, err error) {
-	switch {
-	case errors.Is(err, ErrSmtWentWrong):
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-	case errors.Is(err, ErrInvalidAmountOrCurrency):
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	case errors.Is(err, ErrNotEnoughFunds):
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// GetExchangeRates godoc
+// @Summary      Get exchange rates
+// @Description  Retrieve the latest exchange rates for supported currencies. While the exchanger is unavailable the last known rates or the rates of the currency table are returned, the origin tells which.
+// @Tags         exchange
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      200  {object}  ExchangeRateResponse
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Failure      503  {object}  map[string]string  "exchange rates are unavailable"
+// @Router       /api/v1/exchange/rates/ [get]
+func GetExchangeRates(s Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		res, err := s.GetExchangeRates(context.Background())
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// GetRateHistoryHandler godoc
+// @Summary      Get exchange rate history
+// @Description  Open, high, low and close rates of a currency pair per interval, built from every rate fetched from the exchanger. Intervals without rates are left out.
+// @Tags         exchange
+// @Produce      json
+// @Param        Authorization  header    string  true   "Bearer Token"  default(Bearer <token>)
+// @Param        pair      query     string  true   "Currency pair"  example(USD/EUR)
+// @Param        interval  query     string  false  "Candle interval"  Enums(1m, 5m, 15m, 1h, 4h, 1d)  default(1h)
+// @Param        from      query     string  false  "Start of the period, RFC 3339, defaults to a day before to"
+// @Param        to        query     string  false  "End of the period, RFC 3339, defaults to now"
+// @Success      200  {object}  RateHistoryResponse
+// @Failure      400  {object}  map[string]interface{}  "Validation failed or invalid request"
+// @Failure      500  {object}  map[string]string       "internal server error"
+// @Router       /api/v1/exchange/rates/history/ [get]
+func GetRateHistoryHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req RateHistoryRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		from, to, ok := ParsePair(req.Pair)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": []string{"Pair"}})
+			return
+		}
+		if req.Interval == "" {
+			req.Interval = "1h"
+		}
+
+		candles, err := s.GetRateHistory(context.Background(), from, to, CandleIntervals[req.Interval], req.From, req.To)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, RateHistoryResponse{Pair: from + "/" + to, Interval: req.Interval, Candles: candles})
+	}
+}
+
+// ExchangeRatesForCurrency godoc
+// @Summary      Exchange currency
+// @Description  Exchange a specified amount from one currency to another, or execute a quote given by the quote endpoint
+// @Tags         exchange
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      ExchangeRequest  true  "Exchange request"
+// @Param        Idempotency-Key  header  string  false  "Unique key to safely retry the request"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid request"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      409      {object}  map[string]string       "request with this Idempotency-Key is in progress"
+// @Failure      410      {object}  map[string]string       "quote has expired or was already used"
+// @Failure      422      {object}  map[string]string       "Idempotency-Key reused with a different request"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Failure      503      {object}  map[string]string       "exchange rates are unavailable or stale"
+// @Router       /api/v1/exchange/ [post]
+func ExchangeRatesForCurrency(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req ExchangeRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		if req.QuoteID == "" && req.ToCurrency == req.FromCurrency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		userIDStr, ok := userID.(string)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+			return
+		}
+
+		if req.QuoteID != "" {
+			r, err := s.ExchangeByQuote(context.Background(), userIDStr, req.QuoteID)
+			if err != nil {
+				writeJSONError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, r)
+			return
+		}
+		amount, err := s.ParseAmount(context.Background(), req.Amount, req.FromCurrency)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		r, err := s.ExchangeCurrency(
+			context.Background(),
+			userIDStr,
+			amount,
+			req.ToCurrency,
+		)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, r)
+	}
+}
+
+// CreateQuoteHandler godoc
+// @Summary      Get exchange quote
+// @Description  Lock the current rate for an exchange. The returned quote ID can be executed once with the exchange endpoint until the quote expires.
+// @Tags         exchange
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      QuoteRequest  true  "Quote request"
+// @Success      200      {object}  Quote
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid request"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Failure      503      {object}  map[string]string       "exchange rates are unavailable or stale"
+// @Router       /api/v1/exchange/quote/ [post]
+func CreateQuoteHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req QuoteRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		if req.ToCurrency == req.FromCurrency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+		userIDStr, ok := userID.(string)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+			return
+		}
+
+		amount, err := s.ParseAmount(context.Background(), req.Amount, req.FromCurrency)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		q, err := s.CreateQuote(context.Background(), userIDStr, amount, req.ToCurrency)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, q)
+	}
+}
+
+// GetCurrenciesHandler godoc
+// @Summary      List currencies
+// @Description  Retrieve all currencies of the registry including disabled ones
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      200  {object}  CurrenciesResponse
+// @Failure      401  {object}  map[string]string  "Invalid token"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/admin/currencies/ [get]
+func GetCurrenciesHandler(s Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		currencies, err := s.GetCurrencies(context.Background())
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CurrenciesResponse{Currencies: currencies})
+	}
+}
+
+// CreateCurrencyHandler godoc
+// @Summary      Create currency
+// @Description  Add a new enabled currency to the registry
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      CreateCurrencyRequest  true  "Currency"
+// @Success      201      {object}  Currency
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid currency settings"
+// @Failure      401      {object}  map[string]string       "Invalid token"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      409      {object}  map[string]string       "currency already exists"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/admin/currencies/ [post]
+func CreateCurrencyHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req CreateCurrencyRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		currency := req.currency(req.Code)
+		currency.Enabled = true
+		res, err := s.CreateCurrency(context.Background(), currency)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, res)
+	}
+}
+
+// UpdateCurrencyHandler godoc
+// @Summary      Edit currency
+// @Description  Replace the properties of a currency. The precision can only be increased.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        code     path      string                 true  "Currency code"
+// @Param        request  body      UpdateCurrencyRequest  true  "Currency"
+// @Success      200      {object}  Currency
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid currency settings"
+// @Failure      401      {object}  map[string]string       "Invalid token"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      404      {object}  map[string]string       "currency not found"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/admin/currencies/{code}/ [put]
+func UpdateCurrencyHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req UpdateCurrencyRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		res, err := s.UpdateCurrency(context.Background(), req.currency(c.Param("code")))
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// SetCurrencyEnabledHandler godoc
+// @Summary      Enable or disable currency
+// @Description  Disabled currencies can't be deposited or exchanged, existing balances stay readable and withdrawable
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        code     path      string  true  "Currency code"
+// @Success      200      {object}  Currency
+// @Failure      401      {object}  map[string]string  "Invalid token"
+// @Failure      403      {object}  map[string]string  "insufficient scope"
+// @Failure      404      {object}  map[string]string  "currency not found"
+// @Failure      500      {object}  map[string]string  "internal server error"
+// @Router       /api/v1/admin/currencies/{code}/enable/ [post]
+// @Router       /api/v1/admin/currencies/{code}/disable/ [post]
+func SetCurrencyEnabledHandler(s Service, enabled bool) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		res, err := s.SetCurrencyEnabled(context.Background(), c.Param("code"), enabled)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// GetFeeRulesHandler godoc
+// @Summary      List exchange fee rules
+// @Description  Retrieve the fee tiers of all currency pairs
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      200  {object}  FeeRulesResponse
+// @Failure      401  {object}  map[string]string  "Invalid token"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/admin/fee-rules/ [get]
+func GetFeeRulesHandler(s Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		rules, err := s.GetFeeRules(context.Background())
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, FeeRulesResponse{Rules: rules})
+	}
+}
+
+// SetFeeRulesHandler godoc
+// @Summary      Set exchange fee rules
+// @Description  Replace the fee tiers of a currency pair. A tier applies to amounts from its min_amount (in the source currency) up to the next tier. The fee is spread_percent of the amount plus fixed_fee, but not less than min_fee. No tiers make the pair free.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        from     path      string           true  "Source currency code"
+// @Param        to       path      string           true  "Target currency code"
+// @Param        request  body      FeeRulesRequest  true  "Fee tiers"
+// @Success      200      {object}  FeeRulesResponse
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid currency settings"
+// @Failure      401      {object}  map[string]string       "Invalid token"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/admin/fee-rules/{from}/{to}/ [put]
+func SetFeeRulesHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req FeeRulesRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		rules := make([]FeeRule, len(req.Tiers))
+		for i, t := range req.Tiers {
+			rules[i] = FeeRule{
+				MinAmount:     t.MinAmount,
+				SpreadPercent: t.SpreadPercent,
+				FixedFee:      t.FixedFee,
+				MinFee:        t.MinFee,
+			}
+		}
+		res, err := s.SetFeeRules(context.Background(), c.Param("from"), c.Param("to"), rules)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, FeeRulesResponse{Rules: res})
+	}
+}
+
+// GetTrialBalanceHandler godoc
+// @Summary      Get trial balance
+// @Description  Sum the balances of all accounts by type and currency. The ledger is balanced if every journal entry is balanced, every account balance matches its postings and the totals of all currencies are zero.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      200  {object}  TrialBalanceResponse
+// @Failure      401  {object}  map[string]string  "Invalid token"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/admin/ledger/trial-balance/ [get]
+func GetTrialBalanceHandler(s Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		res, err := s.GetTrialBalance(context.Background())
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// AdminGetWalletHandler godoc
+// @Summary      View wallet
+// @Description  Retrieve the wallet of any user with its balances and freeze. The view is recorded in the admin action log.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200  {object}  Wallet
+// @Failure      401  {object}  map[string]string  "Invalid token"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      404  {object}  map[string]string  "wallet not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/admin/wallets/{user_id}/ [get]
+func AdminGetWalletHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		operatorID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		userID := c.Param("user_id")
+		if err := v.Var(userID, "uuid"); err != nil {
+			writeJSONError(c, ErrWalletNotFound)
+			return
+		}
+		w, err := s.GetWallet(context.Background(), operatorID, userID)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, w)
+	}
+}
+
+// AdminGetTransactionsHandler godoc
+// @Summary      View wallet transactions
+// @Description  Retrieve the transactions of any user's wallet, paginated, filtered and sorted like the user's own transactions. The view is recorded in the admin action log.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        user_id     path      string  true   "User ID"
+// @Param        cursor      query     string  false  "Cursor returned as next_cursor by the previous page"
+// @Param        limit       query     int     false  "Page size (1-100, default 20)"
+// @Param        currency    query     string  false  "Currency code"
+// @Param        type        query     string  false  "Transaction type"  Enums(deposit, withdraw, exchange, transfer, fee, adjustment)
+// @Param        min_amount  query     number  false  "Minimum amount"
+// @Param        max_amount  query     number  false  "Maximum amount"
+// @Param        from        query     string  false  "Start of the period, inclusive (RFC3339)"
+// @Param        to          query     string  false  "End of the period, exclusive (RFC3339)"
+// @Param        sort        query     string  false  "Sort key (default created_at)"  Enums(created_at, amount)
+// @Param        order       query     string  false  "Sort order (default desc)"  Enums(asc, desc)
+// @Success      200      {object}  TransactionsResponse
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or invalid cursor"
+// @Failure      401      {object}  map[string]string       "Invalid token"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      404      {object}  map[string]string       "wallet not found"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/admin/wallets/{user_id}/transactions/ [get]
+func AdminGetTransactionsHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req TransactionsRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		operatorID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		userID := c.Param("user_id")
+		if err := v.Var(userID, "uuid"); err != nil {
+			writeJSONError(c, ErrWalletNotFound)
+			return
+		}
+		writeTransactions(c, req, func(filter TransactionFilter) ([]Transaction, *TransactionCursor, error) {
+			return s.GetWalletTransactions(context.Background(), operatorID, userID, filter)
+		})
+	}
+}
+
+// FreezeWalletHandler godoc
+// @Summary      Freeze or unfreeze wallet
+// @Description  The owner of a frozen wallet can't deposit, withdraw, exchange or send money until it is unfrozen. The operator and the reason are recorded in the admin action log.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        user_id  path      string         true  "User ID"
+// @Param        request  body      FreezeRequest  true  "Freeze request"
+// @Success      200      {object}  Wallet
+// @Failure      400      {object}  map[string]interface{}  "Validation failed"
+// @Failure      401      {object}  map[string]string       "Invalid token"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      404      {object}  map[string]string       "wallet not found"
+// @Failure      409      {object}  map[string]string       "wallet is already frozen or is not frozen"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/admin/wallets/{user_id}/freeze/ [post]
+// @Router       /api/v1/admin/wallets/{user_id}/unfreeze/ [post]
+func FreezeWalletHandler(s Service, v *validator.Validate, frozen bool) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req FreezeRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		operatorID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		userID := c.Param("user_id")
+		if err := v.Var(userID, "uuid"); err != nil {
+			writeJSONError(c, ErrWalletNotFound)
+			return
+		}
+		var w Wallet
+		var err error
+		if frozen {
+			w, err = s.FreezeWallet(context.Background(), operatorID, userID, req.Reason)
+		} else {
+			w, err = s.UnfreezeWallet(context.Background(), operatorID, userID, req.Reason)
+		}
+		if err != nil {
+			if errors.Is(err, ErrWalletFrozen) {
+				c.JSON(http.StatusConflict, gin.H{"error": "wallet is already frozen"})
+				return
+			}
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, w)
+	}
+}
+
+// AdjustBalanceHandler godoc
+// @Summary      Adjust wallet balance
+// @Description  Credit a positive amount to the wallet or debit a negative one against the adjustment account. Frozen wallets can be adjusted too. The operator and the reason are recorded in the admin action log.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        user_id  path      string             true  "User ID"
+// @Param        request  body      AdjustmentRequest  true  "Adjustment request"
+// @Param        Idempotency-Key  header  string  false  "Unique key to safely retry the request"
+// @Success      200      {object}  Wallet
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or insufficient funds"
+// @Failure      401      {object}  map[string]string       "Invalid token"
+// @Failure      403      {object}  map[string]string       "insufficient scope"
+// @Failure      404      {object}  map[string]string       "wallet not found"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/admin/wallets/{user_id}/adjustments/ [post]
+func AdjustBalanceHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req AdjustmentRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		operatorID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		userID := c.Param("user_id")
+		if err := v.Var(userID, "uuid"); err != nil {
+			writeJSONError(c, ErrWalletNotFound)
+			return
+		}
+		amount, err := s.ParseAmount(context.Background(), req.Amount, req.Currency)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		w, err := s.AdjustBalance(context.Background(), operatorID, userID, amount, req.Reason)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, w)
+	}
+}
+
+// GetAdminActionsHandler godoc
+// @Summary      List admin actions
+// @Description  Retrieve the latest 100 actions of the support staff with the user's wallet, newest first
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        user_id  path      string  true  "User ID"
+// @Success      200  {object}  AdminActionsResponse
+// @Failure      401  {object}  map[string]string  "Invalid token"
+// @Failure      403  {object}  map[string]string  "insufficient scope"
+// @Failure      404  {object}  map[string]string  "wallet not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/admin/wallets/{user_id}/actions/ [get]
+func GetAdminActionsHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID := c.Param("user_id")
+		if err := v.Var(userID, "uuid"); err != nil {
+			writeJSONError(c, ErrWalletNotFound)
+			return
+		}
+		actions, err := s.GetAdminActions(context.Background(), userID)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, AdminActionsResponse{Actions: actions})
+	}
+}
+
+// currentUser returns the id of the authenticated user, responding with an
+// error if there is none.
+func currentUser(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return "", false
+	}
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+		return "", false
+	}
+	return userIDStr, true
+}
+
+// writeTransactions responds with the page of transactions get returns for the
+// filter the request asks for.
+func writeTransactions(c *gin.Context, req TransactionsRequest, get func(filter TransactionFilter) ([]Transaction, *TransactionCursor, error)) {
+	filter := TransactionFilter{
+		Currency:  req.Currency,
+		Type:      TransactionType(req.Type),
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+		From:      req.From,
+		To:        req.To,
+		SortBy:    req.Sort,
+		Desc:      req.Order != "asc",
+		Limit:     req.Limit,
+	}
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		filter.After = &cursor
+	}
+
+	transactions, next, err := get(filter)
+	if err != nil {
+		writeJSONError(c, err)
+		return
+	}
+	res := TransactionsResponse{Transactions: transactions}
+	if next != nil {
+		res.NextCursor = encodeCursor(*next)
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func writeJSONError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrSmtWentWrong):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidAmountOrCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotEnoughFunds):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidRecipient):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCurrencyDisabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCurrencyNotExchangeable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAmountOutOfRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCurrencyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCurrencyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidTime):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrQuoteExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRatesUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWalletNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWalletFrozen):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWalletNotFrozen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWithdrawalExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTransferExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, stepup.ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, stepup.ErrTOTPNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+	}
+}
+
+func countNonEmpty(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
 	}
+	return n
 }
@@ -5,6 +5,8 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"time"
+	"wallet/internal/domain/outbox"
+	"wallet/internal/domain/stepup"
 )
 
 type PsqlClient interface {
@@ -15,23 +17,136 @@ type PsqlClient interface {
 }
 
 type Storage interface {
-	CreateWallet(ctx context.Context, userID string) error
-	UpdateWallet(ctx context.Context, userID string, wallet Wallet) error
+	// CreateWallet creates the user's wallet and returns its id.
+	CreateWallet(ctx context.Context, userID string) (string, error)
+	ApplyTransactions(ctx context.Context, entries []Transaction) ([]Transaction, error)
 	GetWalletByUserID(ctx context.Context, UserID string) (Wallet, error)
+	GetWalletByUserIDAt(ctx context.Context, userID string, at time.Time) (Wallet, error)
+	CreateSnapshots(ctx context.Context, at time.Time) error
+	// LockWalletByUserID reads the wallet and locks its row until the end of
+	// the surrounding transaction. It must be called within WithinTransaction.
+	LockWalletByUserID(ctx context.Context, userID string) (Wallet, error)
+	// WithinTransaction runs fn against a storage bound to a single database
+	// transaction, committing it if fn returns nil and rolling it back otherwise.
+	WithinTransaction(ctx context.Context, fn func(Storage) error) error
+	GetTransactions(ctx context.Context, userID string, filter TransactionFilter) ([]Transaction, error)
+	GetUserIDByRecipient(ctx context.Context, r Recipient) (string, error)
+	GetCurrencies(ctx context.Context) ([]Currency, error)
+	// GetCurrencyRates returns the reference rates of the currency table,
+	// quoted against a common base currency like the rates of the exchanger.
+	GetCurrencyRates(ctx context.Context) (map[string]float32, error)
+	// CreateCurrency returns ErrCurrencyExists if the code is already taken.
+	CreateCurrency(ctx context.Context, c Currency) error
+	// UpdateCurrency and SetCurrencyEnabled return ErrCurrencyNotFound if
+	// there is no currency with the code.
+	UpdateCurrency(ctx context.Context, c Currency) error
+	SetCurrencyEnabled(ctx context.Context, code string, enabled bool) error
+	GetFeeRules(ctx context.Context) ([]FeeRule, error)
+	// ReplaceFeeRules atomically replaces all tiers of the currency pair.
+	ReplaceFeeRules(ctx context.Context, fromCurrency, toCurrency string, rules []FeeRule) error
+	GetAccountBalances(ctx context.Context) ([]AccountBalance, error)
+	CountUnbalancedEntries(ctx context.Context) (int, error)
+	// AddEvent writes a domain event to the outbox.
+	AddEvent(ctx context.Context, e outbox.Event) error
+	AddExchangeRates(ctx context.Context, rates []ExchangeRate) error
+	// GetCandles aggregates the rates of the pair fetched in [from, to) into
+	// candles of the interval, aligned to the Unix epoch. Intervals without
+	// rates are left out.
+	GetCandles(ctx context.Context, fromCurrency, toCurrency string, interval time.Duration, from, to time.Time) ([]Candle, error)
+	// SetWalletFreeze freezes the wallet or, with a nil freeze, unfreezes it.
+	SetWalletFreeze(ctx context.Context, walletID string, f *Freeze) error
+	AddAdminAction(ctx context.Context, a AdminAction) error
+	// GetAdminActions returns the latest actions with the wallet, newest
+	// first.
+	GetAdminActions(ctx context.Context, walletID string, limit int) ([]AdminAction, error)
+}
+
+// Notifier pushes messages to streaming clients on every replica of the
+// service.
+type Notifier interface {
+	// Publish pushes a message to the clients of the user.
+	Publish(ctx context.Context, userID string, v interface{}) error
+	// PublishRates pushes refreshed exchange rates to the clients streaming
+	// rates.
+	PublishRates(ctx context.Context, v interface{}) error
+}
+
+// StepUp confirms operations with a second factor of the user.
+type StepUp interface {
+	CreateChallenge(ctx context.Context, userID string, method stepup.Method) (stepup.Challenge, error)
+	VerifyChallenge(ctx context.Context, userID, challengeID, code string) error
 }
 
 type Cache interface {
 	GetValue(ctx context.Context, key string) (string, error)
 	SetValue(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	DeleteValue(ctx context.Context, key string) error
+	// PopValue returns the value and deletes the key in one step, so only
+	// one of concurrent callers gets it. A missing key yields "".
+	PopValue(ctx context.Context, key string) (string, error)
 }
 
 type Service interface {
 	GetBalance(ctx context.Context, userID string) (Wallet, error)
-	WalletDeposit(ctx context.Context, userID string, amount float32, currency string) (Wallet, error)
-	WalletWithdraw(ctx context.Context, userID string, amount float32, currency string) (Wallet, error)
+	// GetBalanceAt returns the balances the wallet had at the given instant.
+	GetBalanceAt(ctx context.Context, userID string, at time.Time) (Wallet, error)
+	// TakeSnapshots stores the balances of all accounts as of snapshotLag ago.
+	TakeSnapshots(ctx context.Context) error
+	WalletDeposit(ctx context.Context, userID string, amount Money) (Wallet, error)
+	// WalletWithdraw withdraws the amount right away, without the
+	// confirmation RequestWithdraw asks for.
+	WalletWithdraw(ctx context.Context, userID string, amount Money) (Wallet, error)
+	// RequestWithdraw withdraws the amount unless it is above the
+	// confirmation threshold of the currency, in which case it returns the
+	// pending withdrawal to confirm with ConfirmWithdraw.
+	RequestWithdraw(ctx context.Context, userID string, amount Money, method stepup.Method) (Wallet, *PendingWithdrawal, error)
+	ConfirmWithdraw(ctx context.Context, userID, challengeID, code string) (Wallet, error)
 	CreateUserWallet(ctx context.Context, userID string) error
-	ExchangeCurrency(ctx context.Context, userID string, amount float32, fromCurrency, toCurrency string) (ExchangeResponse, error)
+	ExchangeCurrency(ctx context.Context, userID string, amount Money, toCurrency string) (ExchangeResponse, error)
+	CreateQuote(ctx context.Context, userID string, amount Money, toCurrency string) (Quote, error)
+	ExchangeByQuote(ctx context.Context, userID, quoteID string) (ExchangeResponse, error)
 	GetExchangeRates(ctx context.Context) (ExchangeRateResponse, error)
+	// GetRateHistory returns the candles of the pair's rates over [from, to).
+	GetRateHistory(ctx context.Context, fromCurrency, toCurrency string, interval time.Duration, from, to time.Time) ([]Candle, error)
+	GetTransactions(ctx context.Context, userID string, filter TransactionFilter) ([]Transaction, *TransactionCursor, error)
+	// GetStatement returns the user's ledger entries of the period with the
+	// opening, running and closing balances per currency.
+	GetStatement(ctx context.Context, userID, currency string, from, to time.Time) (Statement, error)
+	// Transfer sends the amount right away, without the confirmation
+	// RequestTransfer asks for.
+	Transfer(ctx context.Context, userID string, to Recipient, amount Money) (Wallet, error)
+	// RequestTransfer sends the amount unless it is above the confirmation
+	// threshold of the currency, in which case it returns the pending
+	// transfer to confirm with ConfirmTransfer.
+	RequestTransfer(ctx context.Context, userID string, to Recipient, amount Money, method stepup.Method) (Wallet, *PendingTransfer, error)
+	ConfirmTransfer(ctx context.Context, userID, challengeID, code string) (Wallet, error)
+	GetCurrencies(ctx context.Context) ([]Currency, error)
+	// ParseAmount parses a client supplied amount in a currency of the registry.
+	ParseAmount(ctx context.Context, amount Decimal, currency string) (Money, error)
+	CreateCurrency(ctx context.Context, c Currency) (Currency, error)
+	UpdateCurrency(ctx context.Context, c Currency) (Currency, error)
+	SetCurrencyEnabled(ctx context.Context, code string, enabled bool) (Currency, error)
+	GetFeeRules(ctx context.Context) ([]FeeRule, error)
+	SetFeeRules(ctx context.Context, fromCurrency, toCurrency string, rules []FeeRule) ([]FeeRule, error)
+	// GetTrialBalance proves that the ledger is consistent: every journal
+	// entry is balanced, account balances match their postings and the
+	// balances of all accounts sum to zero in every currency.
+	GetTrialBalance(ctx context.Context) (TrialBalanceResponse, error)
+	// GetWallet and GetWalletTransactions return the wallet of any user and
+	// its transactions for the support staff. The views are recorded in the
+	// admin action log.
+	GetWallet(ctx context.Context, operatorID, userID string) (Wallet, error)
+	GetWalletTransactions(ctx context.Context, operatorID, userID string, filter TransactionFilter) ([]Transaction, *TransactionCursor, error)
+	// FreezeWallet and UnfreezeWallet stop and resume the operations of the
+	// wallet's owner on behalf of the operator.
+	FreezeWallet(ctx context.Context, operatorID, userID, reason string) (Wallet, error)
+	UnfreezeWallet(ctx context.Context, operatorID, userID, reason string) (Wallet, error)
+	// AdjustBalance credits a positive amount to the wallet or debits a
+	// negative one on behalf of the operator.
+	AdjustBalance(ctx context.Context, operatorID, userID string, amount Money, reason string) (Wallet, error)
+	// GetAdminActions returns the latest actions of the support staff with
+	// the user's wallet.
+	GetAdminActions(ctx context.Context, userID string) ([]AdminAction, error)
 }
 
 type ExchangerService interface {
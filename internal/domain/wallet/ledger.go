@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"context"
+	"log/slog"
+)
+
+func (s *ServiceWallet) GetTrialBalance(ctx context.Context) (TrialBalanceResponse, error) {
+	const op = "wallet.GetTrialBalance"
+	log := s.logger.With(slog.String("op", op))
+
+	accounts, err := s.storage.GetAccountBalances(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return TrialBalanceResponse{}, ErrSmtWentWrong
+	}
+	unbalanced, err := s.storage.CountUnbalancedEntries(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return TrialBalanceResponse{}, ErrSmtWentWrong
+	}
+
+	res := TrialBalanceResponse{
+		Accounts:          accounts,
+		Totals:            make(map[string]Money),
+		UnbalancedEntries: unbalanced,
+		Balanced:          unbalanced == 0,
+	}
+	for _, a := range accounts {
+		if a.Balance != a.Postings {
+			res.Balanced = false
+		}
+		if total, ok := res.Totals[a.Currency]; ok {
+			res.Totals[a.Currency] = total.Add(a.Balance)
+		} else {
+			res.Totals[a.Currency] = a.Balance
+		}
+	}
+	for _, total := range res.Totals {
+		if total.Minor != 0 {
+			res.Balanced = false
+		}
+	}
+	return res, nil
+}
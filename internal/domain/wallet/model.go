@@ -1,9 +1,226 @@
 package wallet
 
+import (
+	"time"
+	"wallet/internal/domain/stepup"
+)
+
 type Wallet struct {
-	UUID       string  `json:"uuid"`
-	UserUUID   string  `json:"user_uuid"`
-	BalanceEUR float32 `json:"balance_eur"`
-	BalanceUSD float32 `json:"balance_usd"`
-	BalanceRUB float32 `json:"balance_rub"`
+	UUID     string           `json:"uuid"`
+	UserUUID string           `json:"user_uuid"`
+	Balances map[string]Money `json:"balances" swaggertype:"object,string"`
+	// Frozen is set while the wallet is frozen by the support staff.
+	Frozen *Freeze `json:"frozen,omitempty"`
+}
+
+// Freeze tells who froze the wallet, when and why. The owner of a frozen
+// wallet can't make operations with it.
+type Freeze struct {
+	OperatorUUID string    `json:"operator_uuid"`
+	Reason       string    `json:"reason"`
+	FrozenAt     time.Time `json:"frozen_at"`
+}
+
+// Balance returns the wallet balance in the currency. A currency the wallet
+// has never held has a zero balance.
+func (w Wallet) Balance(c Currency) Money {
+	if b, ok := w.Balances[c.Code]; ok {
+		return b
+	}
+	return c.Zero()
+}
+
+func (w *Wallet) setBalance(b Money) {
+	if w.Balances == nil {
+		w.Balances = make(map[string]Money)
+	}
+	w.Balances[b.Currency] = b
+}
+
+type TransactionType string
+
+const (
+	TransactionOpening  TransactionType = "opening"
+	TransactionDeposit  TransactionType = "deposit"
+	TransactionWithdraw TransactionType = "withdraw"
+	TransactionExchange TransactionType = "exchange"
+	TransactionTransfer TransactionType = "transfer"
+	TransactionFee      TransactionType = "fee"
+	// TransactionAdjustment is a manual correction made by the support staff.
+	TransactionAdjustment TransactionType = "adjustment"
+)
+
+// AccountType is the kind of an account of the chart of accounts. Every
+// wallet has a user account per currency, the other types are system
+// accounts with one account per currency.
+type AccountType string
+
+const (
+	AccountUser AccountType = "user"
+	// AccountHouseFX is the counterparty of both legs of every exchange.
+	AccountHouseFX AccountType = "house_fx"
+	// AccountFeeRevenue collects the exchange fees.
+	AccountFeeRevenue AccountType = "fee_revenue"
+	// AccountSettlement stands for the world outside of the service: deposits
+	// come from it and withdrawals go to it.
+	AccountSettlement AccountType = "settlement"
+	// AccountAdjustment is the counterparty of manual adjustments.
+	AccountAdjustment AccountType = "adjustment"
+)
+
+// Transaction is an immutable posting to an account of the ledger. Amount is
+// signed: credits are positive, debits are negative. All postings of a single
+// operation share the same OperationUUID and form a journal entry, whose
+// amounts sum to zero in every currency. Postings to user accounts have the
+// WalletUUID set, postings to system accounts have AccountType set instead.
+// Transfer entries reference the other side's wallet in CounterpartyWalletUUID.
+type Transaction struct {
+	UUID                   string          `json:"uuid"`
+	WalletUUID             string          `json:"wallet_uuid"`
+	AccountType            AccountType     `json:"-"`
+	OperationUUID          string          `json:"operation_uuid"`
+	Type                   TransactionType `json:"type"`
+	Currency               string          `json:"currency"`
+	Amount                 Money           `json:"amount" swaggertype:"string"`
+	BalanceAfter           Money           `json:"balance_after" swaggertype:"string"`
+	CounterpartyWalletUUID string          `json:"counterparty_wallet_uuid,omitempty"`
+	CreatedAt              time.Time       `json:"created_at"`
+}
+
+// Recipient identifies the receiving user of a transfer by exactly one of
+// the fields.
+type Recipient struct {
+	UserID   string
+	Username string
+	Email    string
+}
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByAmount    = "amount"
+)
+
+// TransactionFilter narrows down and orders a wallet's ledger entries.
+// Amount bounds and amount sorting apply to the absolute value of an entry,
+// so withdrawals and deposits of the same size are treated alike.
+type TransactionFilter struct {
+	Currency  string
+	Type      TransactionType
+	MinAmount Decimal
+	MaxAmount Decimal
+	From      time.Time
+	To        time.Time
+	SortBy    string
+	Desc      bool
+	After     *TransactionCursor
+	Limit     int
+}
+
+// TransactionCursor points at the last entry of a page: the value of the
+// sort key and the entry id used as a tie-breaker.
+type TransactionCursor struct {
+	SortBy string `json:"s"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
+}
+
+// Quote is an offer to exchange Amount for ExchangedAmount at Rate after
+// deducting Fee, valid until ExpiresAt.
+type Quote struct {
+	ID              string      `json:"quote_id"`
+	FromCurrency    string      `json:"from_currency"`
+	ToCurrency      string      `json:"to_currency"`
+	Amount          Money       `json:"amount" swaggertype:"string"`
+	Rate            float32     `json:"rate"`
+	RateOrigin      *RateOrigin `json:"rate_origin,omitempty"`
+	ExchangedAmount Money       `json:"exchanged_amount" swaggertype:"string"`
+	Fee             Fee         `json:"fee"`
+	ExpiresAt       time.Time   `json:"expires_at"`
+}
+
+// PendingWithdrawal is a withdrawal above the confirmation threshold of its
+// currency. It is made once the challenge is answered with ConfirmWithdraw
+// and dropped at ExpiresAt otherwise.
+type PendingWithdrawal struct {
+	stepup.Challenge
+	Amount   Money  `json:"amount" swaggertype:"string"`
+	Currency string `json:"currency"`
+}
+
+// PendingTransfer is a transfer above the confirmation threshold of its
+// currency, sent once the challenge is answered with ConfirmTransfer.
+type PendingTransfer struct {
+	stepup.Challenge
+	Amount   Money  `json:"amount" swaggertype:"string"`
+	Currency string `json:"currency"`
+}
+
+// ExchangeRate is a rate of the currency pair given by the exchanger, in the
+// form Money.Convert expects.
+type ExchangeRate struct {
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         float32   `json:"rate"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// RateOrigin tells where a rate comes from and how old it is. FetchedAt and
+// AgeSeconds are missing for the rates of the currency table, whose age is
+// unknown. Stale rates are those not fresh from the exchanger.
+type RateOrigin struct {
+	Source     string     `json:"source" enums:"exchanger,last_known,currency_table"`
+	FetchedAt  *time.Time `json:"fetched_at,omitempty"`
+	AgeSeconds *int64     `json:"age_seconds,omitempty"`
+	Stale      bool       `json:"stale"`
+}
+
+// Candle sums up the rates of a pair fetched in [OpenTime, OpenTime+interval).
+type Candle struct {
+	OpenTime time.Time `json:"open_time"`
+	Open     float32   `json:"open"`
+	High     float32   `json:"high"`
+	Low      float32   `json:"low"`
+	Close    float32   `json:"close"`
+	// Count is the number of rates the candle is made of.
+	Count int `json:"count"`
+}
+
+// AccountBalance is the total balance of the accounts of a type in a currency
+// next to the sum of their postings.
+type AccountBalance struct {
+	AccountType AccountType `json:"account_type"`
+	Currency    string      `json:"currency"`
+	Balance     Money       `json:"balance" swaggertype:"string"`
+	Postings    Money       `json:"postings" swaggertype:"string"`
+}
+
+// BalanceUpdate is pushed to the user's streaming clients when an operation
+// changes the balance of the wallet. Transactions are the postings of the
+// operation to the wallet.
+type BalanceUpdate struct {
+	Balances     map[string]Money `json:"balances" swaggertype:"object,string"`
+	Transactions []Transaction    `json:"transactions"`
+}
+
+// Actions of the support staff recorded in the admin action log.
+const (
+	AdminActionFreeze   = "freeze"
+	AdminActionUnfreeze = "unfreeze"
+	AdminActionAdjust   = "adjust"
+	// Views of the wallet and of its transactions by the support staff.
+	AdminActionViewWallet       = "view_wallet"
+	AdminActionViewTransactions = "view_transactions"
+)
+
+// AdminAction is an action of an operator of the support staff with a wallet.
+// Adjustments carry the signed Amount and the journal entry they posted.
+type AdminAction struct {
+	UUID          string    `json:"uuid"`
+	OperatorUUID  string    `json:"operator_uuid"`
+	Action        string    `json:"action" enums:"freeze,unfreeze,adjust,view_wallet,view_transactions"`
+	WalletUUID    string    `json:"wallet_uuid"`
+	OperationUUID string    `json:"operation_uuid,omitempty"`
+	Amount        *Money    `json:"amount,omitempty" swaggertype:"string"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"created_at"`
 }
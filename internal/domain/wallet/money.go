@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type RoundingMode string
+
+const (
+	RoundHalfEven RoundingMode = "half_even"
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundDown     RoundingMode = "down"
+)
+
+// Currency is an entry of the currency registry. Precision is the number of
+// digits after the decimal point and Rounding is applied to results of
+// calculations (e.g. conversions) in this currency. Empty MinAmount and
+// MaxAmount mean the transaction amount is not limited. Withdrawals and
+// transfers above ConfirmationThreshold must be confirmed with a second
+// factor, an empty one means they never do.
+type Currency struct {
+	Code         string       `json:"code"`
+	Name         string       `json:"name"`
+	Symbol       string       `json:"symbol"`
+	Precision    int          `json:"precision"`
+	Rounding     RoundingMode `json:"rounding"`
+	MinAmount    Decimal      `json:"min_amount,omitempty" swaggertype:"string"`
+	MaxAmount    Decimal      `json:"max_amount,omitempty" swaggertype:"string"`
+	Exchangeable bool         `json:"exchangeable"`
+	Enabled      bool         `json:"enabled"`
+
+	ConfirmationThreshold Decimal `json:"confirmation_threshold,omitempty" swaggertype:"string"`
+}
+
+// Zero returns a zero amount of the currency.
+func (c Currency) Zero() Money {
+	return Money{Currency: c.Code, Precision: c.Precision}
+}
+
+// needsConfirmation tells whether a withdrawal or a transfer of the amount
+// must be confirmed with a second factor.
+func (c Currency) needsConfirmation(amount Money) (bool, error) {
+	if c.ConfirmationThreshold == "" {
+		return false, nil
+	}
+	threshold, err := ParseMoney(string(c.ConfirmationThreshold), c)
+	if err != nil {
+		return false, err
+	}
+	return amount.Sub(threshold).IsPositive(), nil
+}
+
+// checkLimits reports ErrAmountOutOfRange if the transaction amount is outside
+// of the currency limits.
+func (c Currency) checkLimits(amount Money) error {
+	if c.MinAmount != "" {
+		min, err := ParseMoney(string(c.MinAmount), c)
+		if err != nil {
+			return err
+		}
+		if amount.Sub(min).IsNegative() {
+			return ErrAmountOutOfRange
+		}
+	}
+	if c.MaxAmount != "" {
+		max, err := ParseMoney(string(c.MaxAmount), c)
+		if err != nil {
+			return err
+		}
+		if max.Sub(amount).IsNegative() {
+			return ErrAmountOutOfRange
+		}
+	}
+	return nil
+}
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Decimal is a decimal amount as sent by clients. It accepts both JSON strings
+// and JSON numbers, keeping the digits exactly as written.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*d = Decimal(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*d = Decimal(n)
+	return nil
+}
+
+// Money is an exact amount of a currency kept in integer minor units
+// (cents, kopecks) together with the currency precision. It is encoded in
+// JSON as a decimal string, e.g. "12.30".
+type Money struct {
+	Minor     int64
+	Currency  string
+	Precision int
+}
+
+// ParseMoney parses a plain decimal string. Amounts with more significant
+// fractional digits than the currency allows are rejected rather than rounded.
+func ParseMoney(s string, c Currency) (Money, error) {
+	if !decimalPattern.MatchString(s) {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	r.Mul(r, new(big.Rat).SetInt(pow10(c.Precision)))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	return Money{Minor: r.Num().Int64(), Currency: c.Code, Precision: c.Precision}, nil
+}
+
+func (m Money) String() string {
+	digits := strconv.FormatInt(m.Minor, 10)
+	sign := ""
+	if m.Minor < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if m.Precision == 0 {
+		return sign + digits
+	}
+	if len(digits) <= m.Precision {
+		digits = strings.Repeat("0", m.Precision-len(digits)+1) + digits
+	}
+	point := len(digits) - m.Precision
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// Add returns m + o. Both amounts must be in the same currency.
+func (m Money) Add(o Money) Money {
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency, Precision: m.Precision}
+}
+
+// Sub returns m - o. Both amounts must be in the same currency.
+func (m Money) Sub(o Money) Money {
+	return Money{Minor: m.Minor - o.Minor, Currency: m.Currency, Precision: m.Precision}
+}
+
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency, Precision: m.Precision}
+}
+
+func (m Money) Abs() Money {
+	if m.Minor < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Convert divides the amount by rate and expresses the result in currency,
+// rounding it according to the target currency's rule.
+func (m Money) Convert(rate float32, to Currency) (Money, error) {
+	if rate <= 0 {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	// Go through the shortest decimal form of the rate so that e.g. 1.1
+	// is used as exactly 1.1 and not as its nearest binary approximation.
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(float64(rate), 'f', -1, 32))
+	if !ok {
+		return Money{}, errors.New("invalid rate")
+	}
+	v := new(big.Rat).SetFrac(big.NewInt(m.Minor), pow10(m.Precision))
+	v.Quo(v, r)
+	v.Mul(v, new(big.Rat).SetInt(pow10(to.Precision)))
+	minor := roundRat(v, to.Rounding)
+	if !minor.IsInt64() {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	return Money{Minor: minor.Int64(), Currency: to.Code, Precision: to.Precision}, nil
+}
+
+// Percent returns p percent of the amount, rounded using the given mode.
+func (m Money) Percent(p Decimal, mode RoundingMode) (Money, error) {
+	if !decimalPattern.MatchString(string(p)) {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	r, ok := new(big.Rat).SetString(string(p))
+	if !ok {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	v := new(big.Rat).SetInt64(m.Minor)
+	v.Mul(v, r)
+	v.Quo(v, big.NewRat(100, 1))
+	minor := roundRat(v, mode)
+	if !minor.IsInt64() {
+		return Money{}, ErrInvalidAmountOrCurrency
+	}
+	return Money{Minor: minor.Int64(), Currency: m.Currency, Precision: m.Precision}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundRat rounds r to an integer using the given mode.
+func roundRat(r *big.Rat, mode RoundingMode) *big.Int {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() == 0 || mode == RoundDown {
+		return q
+	}
+	// Compare the remainder with half of the denominator.
+	half := new(big.Int).Abs(rem)
+	half.Mul(half, big.NewInt(2))
+	cmp := half.Cmp(r.Denom())
+	if cmp > 0 || (cmp == 0 && (mode == RoundHalfUp || q.Bit(0) == 1)) {
+		if r.Sign() < 0 {
+			return q.Sub(q, big.NewInt(1))
+		}
+		return q.Add(q, big.NewInt(1))
+	}
+	return q
+}
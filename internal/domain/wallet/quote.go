@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// quoteTTL is how long the rate of a quote is guaranteed.
+const quoteTTL = 30 * time.Second
+
+// quoteRecord is a quote as it is kept in the cache. Amounts are stored as
+// decimal strings and parsed back with the currency precision.
+type quoteRecord struct {
+	FromCurrency    string    `json:"from_currency"`
+	ToCurrency      string    `json:"to_currency"`
+	Amount          string    `json:"amount"`
+	Rate            float32   `json:"rate"`
+	RateSource      string    `json:"rate_source"`
+	RateFetchedAt   time.Time `json:"rate_fetched_at"`
+	ExchangedAmount string    `json:"exchanged_amount"`
+	Fee             feeRecord `json:"fee"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+type feeRecord struct {
+	SpreadPercent Decimal `json:"spread_percent"`
+	Spread        string  `json:"spread"`
+	Fixed         string  `json:"fixed"`
+	MinFee        string  `json:"min_fee"`
+	Total         string  `json:"total"`
+}
+
+func newFeeRecord(f Fee) feeRecord {
+	return feeRecord{
+		SpreadPercent: f.SpreadPercent,
+		Spread:        f.Spread.String(),
+		Fixed:         f.Fixed.String(),
+		MinFee:        f.MinFee.String(),
+		Total:         f.Total.String(),
+	}
+}
+
+func (r feeRecord) fee(c Currency) (Fee, error) {
+	f := Fee{SpreadPercent: r.SpreadPercent}
+	var err error
+	if f.Spread, err = ParseMoney(r.Spread, c); err != nil {
+		return Fee{}, err
+	}
+	if f.Fixed, err = ParseMoney(r.Fixed, c); err != nil {
+		return Fee{}, err
+	}
+	if f.MinFee, err = ParseMoney(r.MinFee, c); err != nil {
+		return Fee{}, err
+	}
+	if f.Total, err = ParseMoney(r.Total, c); err != nil {
+		return Fee{}, err
+	}
+	return f, nil
+}
+
+// CreateQuote fixes the current rate for exchanging amount to toCurrency. The
+// quote can be executed once by the same user with ExchangeByQuote until it
+// expires.
+func (s *ServiceWallet) CreateQuote(ctx context.Context, userID string, amount Money, toCurrency string) (Quote, error) {
+	const op = "wallet.CreateQuote"
+	log := s.logger.With(slog.String("op", op))
+
+	from, to, err := s.exchangeCurrencies(ctx, amount, toCurrency)
+	if err != nil {
+		return Quote{}, err
+	}
+	fee, err := s.exchangeFee(ctx, amount, from, to)
+	if err != nil {
+		log.Error(err.Error())
+		return Quote{}, ErrSmtWentWrong
+	}
+	rate, err := s.exchangeRate(ctx, from.Code, to.Code)
+	if err != nil {
+		return Quote{}, err
+	}
+	exchanged, err := convert(log, amount.Sub(fee.Total), rate.Rate, to)
+	if err != nil {
+		return Quote{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		log.Error(err.Error())
+		return Quote{}, ErrSmtWentWrong
+	}
+
+	q := Quote{
+		ID:              id,
+		FromCurrency:    from.Code,
+		ToCurrency:      to.Code,
+		Amount:          amount,
+		Rate:            rate.Rate,
+		RateOrigin:      rate.origin(time.Now()),
+		ExchangedAmount: exchanged,
+		Fee:             fee,
+		ExpiresAt:       time.Now().Add(quoteTTL).UTC(),
+	}
+	jsonData, err := json.Marshal(quoteRecord{
+		FromCurrency:    q.FromCurrency,
+		ToCurrency:      q.ToCurrency,
+		Amount:          q.Amount.String(),
+		Rate:            rate.Rate,
+		RateSource:      rate.Source,
+		RateFetchedAt:   rate.FetchedAt,
+		ExchangedAmount: q.ExchangedAmount.String(),
+		Fee:             newFeeRecord(q.Fee),
+		ExpiresAt:       q.ExpiresAt,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return Quote{}, ErrSmtWentWrong
+	}
+	if err = s.cache.SetValue(ctx, quoteKey(userID, id), string(jsonData), quoteTTL); err != nil {
+		log.Error(err.Error())
+		return Quote{}, ErrSmtWentWrong
+	}
+	return q, nil
+}
+
+// ExchangeByQuote executes the exchange at the rate and fee locked by the
+// quote. The quote is consumed even if the exchange fails, e.g. for
+// insufficient funds.
+func (s *ServiceWallet) ExchangeByQuote(ctx context.Context, userID, quoteID string) (ExchangeResponse, error) {
+	const op = "wallet.ExchangeByQuote"
+	log := s.logger.With(slog.String("op", op))
+
+	data, err := s.cache.PopValue(ctx, quoteKey(userID, quoteID))
+	if err != nil {
+		log.Error(err.Error())
+		return ExchangeResponse{}, ErrSmtWentWrong
+	}
+	if data == "" {
+		return ExchangeResponse{}, ErrQuoteExpired
+	}
+	var r quoteRecord
+	if err = json.Unmarshal([]byte(data), &r); err != nil {
+		log.Error(err.Error())
+		return ExchangeResponse{}, ErrSmtWentWrong
+	}
+	if time.Now().After(r.ExpiresAt) {
+		return ExchangeResponse{}, ErrQuoteExpired
+	}
+
+	// The currencies are checked again as they may have been disabled since
+	// the quote was given.
+	from, err := s.currency(ctx, r.FromCurrency)
+	if err != nil {
+		return ExchangeResponse{}, err
+	}
+	amount, err := ParseMoney(r.Amount, from)
+	if err != nil {
+		log.Error(err.Error())
+		return ExchangeResponse{}, ErrSmtWentWrong
+	}
+	from, to, err := s.exchangeCurrencies(ctx, amount, r.ToCurrency)
+	if err != nil {
+		return ExchangeResponse{}, err
+	}
+	exchanged, err := ParseMoney(r.ExchangedAmount, to)
+	if err != nil {
+		log.Error(err.Error())
+		return ExchangeResponse{}, ErrSmtWentWrong
+	}
+	fee, err := r.Fee.fee(from)
+	if err != nil {
+		log.Error(err.Error())
+		return ExchangeResponse{}, ErrSmtWentWrong
+	}
+	rate := sourcedRate{Rate: r.Rate, Source: r.RateSource, FetchedAt: r.RateFetchedAt}
+	return s.exchange(ctx, log, userID, amount, exchanged, fee, from, to, rate)
+}
+
+// quoteKey scopes quotes to their user, so a quote id of another user is
+// reported as expired.
+func quoteKey(userID, quoteID string) string {
+	return fmt.Sprintf("exchange_quote:%s:%s", userID, quoteID)
+}
+
+// newID returns a random (version 4) UUID.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,274 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sources of rates, from the most to the least reliable.
+const (
+	RateSourceExchanger     = "exchanger"
+	RateSourceLastKnown     = "last_known"
+	RateSourceCurrencyTable = "currency_table"
+)
+
+// rateCacheTTL is how long rates of the exchanger are used before it is
+// asked again.
+const rateCacheTTL = 30 * time.Second
+
+// Cache keys of the rates of the exchanger. The last known rates never
+// expire, they are replaced by every successful fetch.
+const (
+	ratesKey          = "exchange_rates"
+	lastKnownRatesKey = "exchange_rates:last_known"
+)
+
+func rateKey(from, to string) string {
+	return fmt.Sprintf("exchange_rate:%s:%s", from, to)
+}
+
+func lastKnownRateKey(from, to string) string {
+	return fmt.Sprintf("exchange_rate:last_known:%s:%s", from, to)
+}
+
+// RateConfig sets how the service degrades while the exchanger is
+// unavailable.
+type RateConfig struct {
+	// MaxStaleness is how old the last known rates may be to be used.
+	MaxStaleness time.Duration
+	// AllowStaleExchanges permits exchanges and quotes at rates not fresh
+	// from the exchanger.
+	AllowStaleExchanges bool
+}
+
+// sourcedRate is a rate of a pair along with where and when it was got.
+// FetchedAt is zero for the rates of the currency table.
+type sourcedRate struct {
+	Rate      float32   `json:"rate"`
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func (r sourcedRate) stale() bool {
+	return r.Source != RateSourceExchanger
+}
+
+func (r sourcedRate) origin(now time.Time) *RateOrigin {
+	return newRateOrigin(r.Source, r.FetchedAt, now)
+}
+
+// sourcedRates are the rates of the exchanger along with where and when
+// they were got.
+type sourcedRates struct {
+	Rates     map[string]float32 `json:"rates"`
+	Source    string             `json:"source"`
+	FetchedAt time.Time          `json:"fetched_at"`
+}
+
+func (r sourcedRates) response(now time.Time) ExchangeRateResponse {
+	return ExchangeRateResponse{Rates: r.Rates, Origin: newRateOrigin(r.Source, r.FetchedAt, now)}
+}
+
+func newRateOrigin(source string, fetchedAt, now time.Time) *RateOrigin {
+	o := &RateOrigin{Source: source, Stale: source != RateSourceExchanger}
+	if !fetchedAt.IsZero() {
+		age := int64(now.Sub(fetchedAt) / time.Second)
+		o.FetchedAt, o.AgeSeconds = &fetchedAt, &age
+	}
+	return o
+}
+
+// maxCandles limits the number of candles of a history request.
+const maxCandles = 1000
+
+// CandleIntervals are the supported candle intervals by their names.
+var CandleIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// PairRate returns the rate of the currency pair from the rates of the
+// exchanger, which are quoted against a common base currency, in the form
+// Money.Convert expects.
+func PairRate(rates map[string]float32, from, to string) (float32, bool) {
+	fromRate, ok := rates[from]
+	if !ok || fromRate <= 0 {
+		return 0, false
+	}
+	toRate, ok := rates[to]
+	if !ok || toRate <= 0 {
+		return 0, false
+	}
+	return toRate / fromRate, true
+}
+
+// ParsePair splits a currency pair such as "USD/EUR" into its currencies.
+func ParsePair(pair string) (from, to string, ok bool) {
+	from, to, ok = strings.Cut(strings.ToUpper(strings.TrimSpace(pair)), "/")
+	if !ok || len(from) != 3 || len(to) != 3 || from == to {
+		return "", "", false
+	}
+	return from, to, true
+}
+
+// allPairRates returns the rates of every ordered pair of the currencies of
+// rates.
+func allPairRates(rates map[string]float32, at time.Time) []ExchangeRate {
+	codes := make([]string, 0, len(rates))
+	for code := range rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	var res []ExchangeRate
+	for _, from := range codes {
+		for _, to := range codes {
+			if from == to {
+				continue
+			}
+			if rate, ok := PairRate(rates, from, to); ok {
+				res = append(res, ExchangeRate{FromCurrency: from, ToCurrency: to, Rate: rate, FetchedAt: at})
+			}
+		}
+	}
+	return res
+}
+
+func (s *ServiceWallet) cachedRates(ctx context.Context, log *slog.Logger, key string) (sourcedRates, bool) {
+	data, _ := s.cache.GetValue(ctx, key)
+	if data == "" {
+		return sourcedRates{}, false
+	}
+	var r sourcedRates
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		log.Error(err.Error())
+		return sourcedRates{}, false
+	}
+	return r, true
+}
+
+func (s *ServiceWallet) cachedRate(ctx context.Context, log *slog.Logger, key string) (sourcedRate, bool) {
+	data, _ := s.cache.GetValue(ctx, key)
+	if data == "" {
+		return sourcedRate{}, false
+	}
+	var r sourcedRate
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		log.Error(err.Error())
+		return sourcedRate{}, false
+	}
+	return r, true
+}
+
+// fallbackRates returns the last known rates if they are fresh enough, or
+// else the rates of the currency table.
+func (s *ServiceWallet) fallbackRates(ctx context.Context, log *slog.Logger) (ExchangeRateResponse, error) {
+	now := time.Now()
+	if r, ok := s.cachedRates(ctx, log, lastKnownRatesKey); ok && now.Sub(r.FetchedAt) <= s.rates.MaxStaleness {
+		r.Source = RateSourceLastKnown
+		return r.response(now), nil
+	}
+	rates, err := s.storage.GetCurrencyRates(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return ExchangeRateResponse{}, ErrRatesUnavailable
+	}
+	if len(rates) == 0 {
+		return ExchangeRateResponse{}, ErrRatesUnavailable
+	}
+	return sourcedRates{Rates: rates, Source: RateSourceCurrencyTable}.response(now), nil
+}
+
+// fallbackRate returns the last known rate of the pair if it is fresh
+// enough, or else the rate of the currency table. The last known rate comes
+// from the pair itself or from the last known rates of all currencies,
+// whichever is newer.
+func (s *ServiceWallet) fallbackRate(ctx context.Context, log *slog.Logger, fromCurrency, toCurrency string) (sourcedRate, error) {
+	now := time.Now()
+	last, ok := s.cachedRate(ctx, log, lastKnownRateKey(fromCurrency, toCurrency))
+	if all, found := s.cachedRates(ctx, log, lastKnownRatesKey); found && (!ok || all.FetchedAt.After(last.FetchedAt)) {
+		if rate, found := PairRate(all.Rates, fromCurrency, toCurrency); found {
+			last, ok = sourcedRate{Rate: rate, FetchedAt: all.FetchedAt}, true
+		}
+	}
+	if ok && now.Sub(last.FetchedAt) <= s.rates.MaxStaleness {
+		last.Source = RateSourceLastKnown
+		return last, nil
+	}
+	rates, err := s.storage.GetCurrencyRates(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return sourcedRate{}, ErrRatesUnavailable
+	}
+	rate, ok := PairRate(rates, fromCurrency, toCurrency)
+	if !ok {
+		return sourcedRate{}, ErrRatesUnavailable
+	}
+	return sourcedRate{Rate: rate, Source: RateSourceCurrencyTable}, nil
+}
+
+// exchangeRate returns the rate to exchange at, refusing stale rates unless
+// they are allowed for exchanges.
+func (s *ServiceWallet) exchangeRate(ctx context.Context, fromCurrency, toCurrency string) (sourcedRate, error) {
+	const op = "wallet.exchangeRate"
+	log := s.logger.With(slog.String("op", op))
+
+	rate, err := s.getRate(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		return sourcedRate{}, err
+	}
+	if rate.stale() && !s.rates.AllowStaleExchanges {
+		log.Warn("refused to exchange at a stale rate", slog.String("source", rate.Source))
+		return sourcedRate{}, ErrRatesUnavailable
+	}
+	return rate, nil
+}
+
+// recordRates stores the rates fetched from the exchanger. The history is
+// not worth failing the request for, so errors are only logged.
+func (s *ServiceWallet) recordRates(ctx context.Context, rates []ExchangeRate) {
+	const op = "wallet.recordRates"
+	log := s.logger.With(slog.String("op", op))
+
+	if len(rates) == 0 {
+		return
+	}
+	if err := s.storage.AddExchangeRates(ctx, rates); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+func (s *ServiceWallet) GetRateHistory(ctx context.Context, fromCurrency, toCurrency string, interval time.Duration, from, to time.Time) ([]Candle, error) {
+	const op = "wallet.GetRateHistory"
+	log := s.logger.With(slog.String("op", op))
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	if interval <= 0 || !to.After(from) || to.Sub(from)/interval > maxCandles {
+		return nil, ErrInvalidPeriod
+	}
+	if fromCurrency == toCurrency {
+		return nil, ErrInvalidCurrency
+	}
+	candles, err := s.storage.GetCandles(ctx, fromCurrency, toCurrency, interval, from, to)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	if candles == nil {
+		candles = []Candle{}
+	}
+	return candles, nil
+}
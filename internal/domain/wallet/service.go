@@ -4,11 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"log/slog"
-	"reflect"
-	"strconv"
 	"time"
+	"wallet/internal/domain/stepup"
 )
 
 type ServiceWallet struct {
@@ -16,39 +14,50 @@ type ServiceWallet struct {
 	storage          Storage
 	cache            Cache
 	exchangerService ExchangerService
+	notifier         Notifier
+	stepUp           StepUp
+	rates            RateConfig
 }
 
-func NewService(storage Storage, logger *slog.Logger, cache Cache, es ExchangerService) Service {
+func NewService(storage Storage, logger *slog.Logger, cache Cache, es ExchangerService, notifier Notifier, stepUp StepUp, rates RateConfig) Service {
 	return &ServiceWallet{
 		storage:          storage,
 		logger:           logger,
 		cache:            cache,
 		exchangerService: es,
+		notifier:         notifier,
+		stepUp:           stepUp,
+		rates:            rates,
 	}
 }
 
+// GetExchangeRates returns the rates of the exchanger, cached for
+// rateCacheTTL. While the exchanger is unavailable it falls back to the last
+// known rates and then to the rates of the currency table.
 func (s *ServiceWallet) GetExchangeRates(ctx context.Context) (ExchangeRateResponse, error) {
 	const op = "wallet.GetExchangeRates"
 	log := s.logger.With(slog.String("op", op))
 
-	rates, _ := s.cache.GetValue(context.Background(), "exchange_rates")
-	if rates != "" {
-		var result ExchangeRateResponse
-		err := json.Unmarshal([]byte(rates), &result)
-		if err != nil {
-			log.Error(err.Error())
-		}
-		return result, nil
+	if r, ok := s.cachedRates(ctx, log, ratesKey); ok {
+		return r.response(time.Now()), nil
 	}
 	res, err := s.exchangerService.GetExchangeRates(ctx)
 	if err != nil {
-		return ExchangeRateResponse{}, err
+		log.Error(err.Error())
+		return s.fallbackRates(ctx, log)
 	}
-	jsonData, err := json.Marshal(res)
+	r := sourcedRates{Rates: res.Rates, Source: RateSourceExchanger, FetchedAt: time.Now().UTC()}
+	jsonData, err := json.Marshal(r)
 	if err != nil {
 		log.Error(err.Error())
 	}
-	_ = s.cache.SetValue(ctx, "exchange_rates", string(jsonData), 30*time.Second)
+	_ = s.cache.SetValue(ctx, ratesKey, string(jsonData), rateCacheTTL)
+	_ = s.cache.SetValue(ctx, lastKnownRatesKey, string(jsonData), 0)
+	s.recordRates(ctx, allPairRates(r.Rates, r.FetchedAt))
+	res = r.response(r.FetchedAt)
+	if s.notifier != nil {
+		_ = s.notifier.PublishRates(ctx, res)
+	}
 	return res, nil
 }
 
@@ -56,7 +65,14 @@ func (s *ServiceWallet) CreateUserWallet(ctx context.Context, userID string) err
 	const op = "wallet.CreateUserWallet"
 	log := s.logger.With("op", op)
 
-	if err := s.storage.CreateWallet(ctx, userID); err != nil {
+	err := s.storage.WithinTransaction(ctx, func(st Storage) error {
+		walletID, err := st.CreateWallet(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return addEvent(ctx, st, EventWalletCreated, walletID, WalletCreatedEvent{WalletUUID: walletID, UserUUID: userID})
+	})
+	if err != nil {
 		log.Error(err.Error())
 		return ErrSmtWentWrong
 	}
@@ -73,180 +89,531 @@ func (s *ServiceWallet) GetBalance(ctx context.Context, userID string) (Wallet,
 		log.Error(err.Error())
 		return Wallet{}, ErrSmtWentWrong
 	}
+	if err = s.addZeroBalances(ctx, &w); err != nil {
+		return Wallet{}, err
+	}
 	return w, nil
 }
 
-func (s *ServiceWallet) WalletDeposit(ctx context.Context, userID string, amount float32, currency string) (Wallet, error) {
-	const op = "wallet.WalletDeposit"
+func (s *ServiceWallet) GetBalanceAt(ctx context.Context, userID string, at time.Time) (Wallet, error) {
+	const op = "wallet.GetBalanceAt"
 	log := s.logger.With("op", op)
 
-	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	if at.After(time.Now()) {
+		return Wallet{}, ErrInvalidTime
+	}
+	w, err := s.storage.GetWalletByUserIDAt(ctx, userID, at)
 	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	if err = s.addZeroBalances(ctx, &w); err != nil {
+		return Wallet{}, err
+	}
+	return w, nil
+}
+
+// snapshotLag keeps snapshots behind the current time. Postings are stamped
+// with the start time of their database transaction, so a snapshot taken right
+// now could miss postings of transactions still in progress.
+const snapshotLag = 5 * time.Minute
+
+func (s *ServiceWallet) TakeSnapshots(ctx context.Context) error {
+	const op = "wallet.TakeSnapshots"
+	log := s.logger.With("op", op)
+
+	at := time.Now().Add(-snapshotLag).UTC()
+	if err := s.storage.CreateSnapshots(ctx, at); err != nil {
 		log.Error(err.Error())
-		return Wallet{}, ErrSmtWentWrong
+		return ErrSmtWentWrong
 	}
-	switch currency {
-	case "EUR":
-		w.BalanceEUR += amount
-	case "USD":
-		w.BalanceUSD += amount
-	case "RUB":
-		w.BalanceRUB += amount
-	default:
+	return nil
+}
+
+func (s *ServiceWallet) WalletDeposit(ctx context.Context, userID string, amount Money) (Wallet, error) {
+	const op = "wallet.WalletDeposit"
+	log := s.logger.With("op", op)
+
+	if !amount.IsPositive() {
 		return Wallet{}, ErrInvalidAmountOrCurrency
 	}
-	err = s.storage.UpdateWallet(ctx, userID, w)
+	c, err := s.currency(ctx, amount.Currency)
 	if err != nil {
+		return Wallet{}, err
+	}
+	if !c.Enabled {
+		return Wallet{}, ErrCurrencyDisabled
+	}
+	if err = c.checkLimits(amount); err != nil {
+		return Wallet{}, err
+	}
+	var w Wallet
+	var postings []Transaction
+	err = s.storage.WithinTransaction(ctx, func(st Storage) error {
+		var err error
+		if w, err = lockActiveWallet(ctx, st, userID); err != nil {
+			return err
+		}
+		entries := []Transaction{
+			{WalletUUID: w.UUID, Type: TransactionDeposit, Currency: amount.Currency, Amount: amount},
+			{AccountType: AccountSettlement, Type: TransactionDeposit, Currency: amount.Currency, Amount: amount.Neg()},
+		}
+		if postings, err = applyTransactions(ctx, st, &w, entries); err != nil {
+			return err
+		}
+		return addEvent(ctx, st, EventDeposited, w.UUID, FundsEvent{
+			WalletUUID:    w.UUID,
+			UserUUID:      userID,
+			OperationUUID: postings[0].OperationUUID,
+			Amount:        amount,
+			Currency:      amount.Currency,
+			Balance:       w.Balance(c),
+		})
+	})
+	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	if err = s.addZeroBalances(ctx, &w); err != nil {
+		// The deposit is committed, it is not reported as failed for the
+		// zero balances missing from the response.
 		log.Error(err.Error())
-		return Wallet{}, ErrSmtWentWrong
 	}
+	s.notifyBalance(ctx, log, userID, w, postings)
 
 	return w, nil
 }
 
-func (s *ServiceWallet) WalletWithdraw(ctx context.Context, userID string, amount float32, currency string) (Wallet, error) {
+func (s *ServiceWallet) WalletWithdraw(ctx context.Context, userID string, amount Money) (Wallet, error) {
 	const op = "wallet.WalletWithdraw"
 	log := s.logger.With("op", op)
 
-	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	if !amount.IsPositive() {
+		return Wallet{}, ErrInvalidAmountOrCurrency
+	}
+	c, err := s.currency(ctx, amount.Currency)
 	if err != nil {
-		log.Error(err.Error())
 		return Wallet{}, err
 	}
-	switch currency {
-	case "EUR":
-		if w.BalanceEUR-amount < 0 {
-			return Wallet{}, ErrInvalidAmountOrCurrency
+	if err = c.checkLimits(amount); err != nil {
+		return Wallet{}, err
+	}
+	var w Wallet
+	var postings []Transaction
+	err = s.storage.WithinTransaction(ctx, func(st Storage) error {
+		var err error
+		if w, err = lockActiveWallet(ctx, st, userID); err != nil {
+			return err
 		}
-		w.BalanceEUR -= amount
-	case "USD":
-		if w.BalanceUSD-amount < 0 {
-			return Wallet{}, ErrInvalidAmountOrCurrency
+		if w.Balance(c).Sub(amount).IsNegative() {
+			return ErrInvalidAmountOrCurrency
 		}
-		w.BalanceUSD -= amount
-	case "RUB":
-		if w.BalanceRUB-amount < 0 {
-			return Wallet{}, ErrInvalidAmountOrCurrency
+		entries := []Transaction{
+			{WalletUUID: w.UUID, Type: TransactionWithdraw, Currency: amount.Currency, Amount: amount.Neg()},
+			{AccountType: AccountSettlement, Type: TransactionWithdraw, Currency: amount.Currency, Amount: amount},
 		}
-		w.BalanceRUB -= amount
-	default:
-		return Wallet{}, ErrInvalidAmountOrCurrency
-	}
-	err = s.storage.UpdateWallet(ctx, userID, w)
+		if postings, err = applyTransactions(ctx, st, &w, entries); err != nil {
+			return err
+		}
+		return addEvent(ctx, st, EventWithdrawn, w.UUID, FundsEvent{
+			WalletUUID:    w.UUID,
+			UserUUID:      userID,
+			OperationUUID: postings[0].OperationUUID,
+			Amount:        amount,
+			Currency:      amount.Currency,
+			Balance:       w.Balance(c),
+		})
+	})
 	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	if err = s.addZeroBalances(ctx, &w); err != nil {
 		log.Error(err.Error())
-		return Wallet{}, ErrSmtWentWrong
 	}
+	s.notifyBalance(ctx, log, userID, w, postings)
 
 	return w, nil
 }
 
-func (s *ServiceWallet) ExchangeCurrency(ctx context.Context, userID string, amount float32, fromCurrency, toCurrency string) (ExchangeResponse, error) {
+func (s *ServiceWallet) ExchangeCurrency(ctx context.Context, userID string, amount Money, toCurrency string) (ExchangeResponse, error) {
 	const op = "wallet.ExchangeCurrency"
 	log := s.logger.With("op", op)
 
-	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	from, to, err := s.exchangeCurrencies(ctx, amount, toCurrency)
 	if err != nil {
-		log.Error(err.Error())
-		return ExchangeResponse{}, ErrSmtWentWrong
+		return ExchangeResponse{}, err
 	}
-	rate, err := s.getRate(ctx, fromCurrency, toCurrency)
+	fee, err := s.exchangeFee(ctx, amount, from, to)
 	if err != nil {
 		log.Error(err.Error())
 		return ExchangeResponse{}, ErrSmtWentWrong
 	}
-	fromCur, err := getBalanceByCurrency(w, fromCurrency)
+	rate, err := s.exchangeRate(ctx, from.Code, to.Code)
 	if err != nil {
-		log.Error(err.Error())
-		return ExchangeResponse{}, ErrSmtWentWrong
+		return ExchangeResponse{}, err
+	}
+	exchanged, err := convert(log, amount.Sub(fee.Total), rate.Rate, to)
+	if err != nil {
+		return ExchangeResponse{}, err
 	}
-	if amount > fromCur {
-		return ExchangeResponse{}, ErrNotEnoughFunds
+	return s.exchange(ctx, log, userID, amount, exchanged, fee, from, to, rate)
+}
+
+// exchangeCurrencies checks that amount can be exchanged to toCurrency and
+// returns both currencies of the exchange.
+func (s *ServiceWallet) exchangeCurrencies(ctx context.Context, amount Money, toCurrency string) (Currency, Currency, error) {
+	if !amount.IsPositive() {
+		return Currency{}, Currency{}, ErrInvalidAmountOrCurrency
 	}
-	fromCur -= amount
-	toCur, err := getBalanceByCurrency(w, toCurrency)
+	from, err := s.currency(ctx, amount.Currency)
 	if err != nil {
-		log.Error(err.Error())
-		return ExchangeResponse{}, ErrSmtWentWrong
+		return Currency{}, Currency{}, err
 	}
-	toCur = toCur + amount/rate
-	if err = updateBalanceByCurrency(&w, fromCurrency, fromCur); err != nil {
-		log.Error(err.Error())
-		return ExchangeResponse{}, ErrSmtWentWrong
+	to, err := s.currency(ctx, toCurrency)
+	if err != nil {
+		return Currency{}, Currency{}, err
 	}
-	if err = updateBalanceByCurrency(&w, toCurrency, toCur); err != nil {
-		log.Error(err.Error())
-		return ExchangeResponse{}, ErrSmtWentWrong
+	if from.Code == to.Code {
+		return Currency{}, Currency{}, ErrInvalidAmountOrCurrency
+	}
+	if !from.Enabled || !to.Enabled {
+		return Currency{}, Currency{}, ErrCurrencyDisabled
+	}
+	if !from.Exchangeable || !to.Exchangeable {
+		return Currency{}, Currency{}, ErrCurrencyNotExchangeable
+	}
+	if err = from.checkLimits(amount); err != nil {
+		return Currency{}, Currency{}, err
+	}
+	return from, to, nil
+}
+
+// convert converts amount at rate, rejecting amounts too small to get anything
+// in the target currency, e.g. when the fee takes up all of the amount.
+func convert(log *slog.Logger, amount Money, rate float32, to Currency) (Money, error) {
+	if !amount.IsPositive() {
+		return Money{}, ErrInvalidAmountOrCurrency
 	}
-	if err = s.storage.UpdateWallet(ctx, userID, w); err != nil {
+	exchanged, err := amount.Convert(rate, to)
+	if err != nil {
 		log.Error(err.Error())
-		return ExchangeResponse{}, ErrSmtWentWrong
+		return Money{}, ErrSmtWentWrong
+	}
+	if !exchanged.IsPositive() {
+		return Money{}, ErrInvalidAmountOrCurrency
 	}
+	return exchanged, nil
+}
+
+// exchange debits amount from the user's wallet and credits exchanged, the
+// amount less the fee converted to the target currency. The house FX account
+// takes the other side of both legs and the fee goes to the fee revenue
+// account, all within the same journal entry.
+func (s *ServiceWallet) exchange(ctx context.Context, log *slog.Logger, userID string, amount, exchanged Money, fee Fee, from, to Currency, rate sourcedRate) (ExchangeResponse, error) {
+	net := amount.Sub(fee.Total)
+	var w Wallet
+	var postings []Transaction
+	err := s.storage.WithinTransaction(ctx, func(st Storage) error {
+		var err error
+		if w, err = lockActiveWallet(ctx, st, userID); err != nil {
+			return err
+		}
+		if w.Balance(from).Sub(amount).IsNegative() {
+			return ErrNotEnoughFunds
+		}
+		entries := []Transaction{
+			{WalletUUID: w.UUID, Type: TransactionExchange, Currency: from.Code, Amount: net.Neg()},
+			{AccountType: AccountHouseFX, Type: TransactionExchange, Currency: from.Code, Amount: net},
+			{AccountType: AccountHouseFX, Type: TransactionExchange, Currency: to.Code, Amount: exchanged.Neg()},
+			{WalletUUID: w.UUID, Type: TransactionExchange, Currency: to.Code, Amount: exchanged},
+		}
+		if fee.Total.IsPositive() {
+			entries = append(entries,
+				Transaction{WalletUUID: w.UUID, Type: TransactionFee, Currency: from.Code, Amount: fee.Total.Neg()},
+				Transaction{AccountType: AccountFeeRevenue, Type: TransactionFee, Currency: from.Code, Amount: fee.Total},
+			)
+		}
+		if postings, err = applyTransactions(ctx, st, &w, entries); err != nil {
+			return err
+		}
+		return addEvent(ctx, st, EventExchanged, w.UUID, ExchangedEvent{
+			WalletUUID:      w.UUID,
+			UserUUID:        userID,
+			OperationUUID:   postings[0].OperationUUID,
+			FromCurrency:    from.Code,
+			ToCurrency:      to.Code,
+			Amount:          amount,
+			ExchangedAmount: exchanged,
+			Fee:             fee.Total,
+			Rate:            rate.Rate,
+			RateSource:      rate.Source,
+			Balances:        map[string]Money{from.Code: w.Balance(from), to.Code: w.Balance(to)},
+		})
+	})
+	if err != nil {
+		return ExchangeResponse{}, domainError(log, err)
+	}
+	s.notifyBalance(ctx, log, userID, w, postings)
 	res := ExchangeResponse{
 		Message:         "Exchange successful",
-		ExchangedAmount: toCur,
-		NewBalance: map[string]float32{
-			fromCurrency: fromCur,
-			toCurrency:   toCur,
+		ExchangedAmount: exchanged,
+		Fee:             fee,
+		Rate:            rate.Rate,
+		RateOrigin:      rate.origin(time.Now()),
+		NewBalance: map[string]Money{
+			from.Code: w.Balance(from),
+			to.Code:   w.Balance(to),
 		},
 	}
 	return res, nil
-
 }
 
-func (s *ServiceWallet) getRate(ctx context.Context, fromCurrency, toCurrency string) (float32, error) {
-	const op = "wallet.getRate"
-	log := s.logger.With(slog.String("op", op))
+// Transfer moves amount from the user's wallet to the recipient's wallet.
+// Both wallets are locked in the order of their owners' ids so that two
+// opposite transfers between the same users cannot deadlock.
+func (s *ServiceWallet) Transfer(ctx context.Context, userID string, to Recipient, amount Money) (Wallet, error) {
+	const op = "wallet.Transfer"
+	log := s.logger.With("op", op)
 
-	res, _ := s.cache.GetValue(ctx, fmt.Sprintf("exchange_rate:%s:%s", fromCurrency, toCurrency))
-	if res != "" {
-		parsedRate, err := strconv.ParseFloat(res, 32)
+	if !amount.IsPositive() {
+		return Wallet{}, ErrInvalidAmountOrCurrency
+	}
+	c, err := s.currency(ctx, amount.Currency)
+	if err != nil {
+		return Wallet{}, err
+	}
+	if err = c.checkLimits(amount); err != nil {
+		return Wallet{}, err
+	}
+	recipientID, err := s.storage.GetUserIDByRecipient(ctx, to)
+	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	if recipientID == userID {
+		return Wallet{}, ErrInvalidRecipient
+	}
+
+	var sender, recipient Wallet
+	var applied []Transaction
+	err = s.storage.WithinTransaction(ctx, func(st Storage) error {
+		order := []string{userID, recipientID}
+		if recipientID < userID {
+			order[0], order[1] = order[1], order[0]
+		}
+		locked := make(map[string]Wallet, len(order))
+		for _, id := range order {
+			w, err := st.LockWalletByUserID(ctx, id)
+			if err != nil {
+				return err
+			}
+			locked[id] = w
+		}
+		sender = locked[userID]
+		recipient = locked[recipientID]
+		if sender.Frozen != nil {
+			return ErrWalletFrozen
+		}
+
+		if sender.Balance(c).Sub(amount).IsNegative() {
+			return ErrNotEnoughFunds
+		}
+		entries := []Transaction{
+			{
+				WalletUUID:             sender.UUID,
+				Type:                   TransactionTransfer,
+				Currency:               amount.Currency,
+				Amount:                 amount.Neg(),
+				CounterpartyWalletUUID: recipient.UUID,
+			},
+			{
+				WalletUUID:             recipient.UUID,
+				Type:                   TransactionTransfer,
+				Currency:               amount.Currency,
+				Amount:                 amount,
+				CounterpartyWalletUUID: sender.UUID,
+			},
+		}
+		var err error
+		if applied, err = postEntry(ctx, st, entries); err != nil {
+			return err
+		}
+		sender.setBalance(applied[0].BalanceAfter)
+		recipient.setBalance(applied[1].BalanceAfter)
+		err = addEvent(ctx, st, EventTransferred, sender.UUID, TransferredEvent{
+			WalletUUID:          sender.UUID,
+			UserUUID:            userID,
+			OperationUUID:       applied[0].OperationUUID,
+			RecipientWalletUUID: recipient.UUID,
+			RecipientUserUUID:   recipientID,
+			Amount:              amount,
+			Currency:            amount.Currency,
+			Balance:             applied[0].BalanceAfter,
+		})
 		if err != nil {
-			log.Error(err.Error())
+			return err
 		}
-		return float32(parsedRate), nil
-	}
-	rate, err := s.exchangerService.GetExchangeRateForCurrency(ctx, fromCurrency, toCurrency)
+		return addEvent(ctx, st, EventTransferReceived, recipient.UUID, TransferReceivedEvent{
+			WalletUUID:       recipient.UUID,
+			UserUUID:         recipientID,
+			OperationUUID:    applied[1].OperationUUID,
+			SenderWalletUUID: sender.UUID,
+			SenderUserUUID:   userID,
+			Amount:           amount,
+			Currency:         amount.Currency,
+			Balance:          applied[1].BalanceAfter,
+		})
+	})
 	if err != nil {
+		return Wallet{}, domainError(log, err)
+	}
+	if err = s.addZeroBalances(ctx, &sender); err != nil {
 		log.Error(err.Error())
-		return 0, ErrSmtWentWrong
 	}
-	_ = s.cache.SetValue(ctx, fmt.Sprintf("exchange_rate:%s:%s", fromCurrency, toCurrency), rate, 30*time.Second)
-	return rate, nil
+	s.notifyBalance(ctx, log, userID, sender, applied[:1])
+	s.notifyBalance(ctx, log, recipientID, recipient, applied[1:])
+
+	return sender, nil
 }
 
-func getBalanceByCurrency(wallet Wallet, currency string) (float32, error) {
-	fieldName := "Balance" + currency
-	v := reflect.ValueOf(wallet)
-	field := v.FieldByName(fieldName)
+const defaultTransactionsLimit = 20
+
+// GetTransactions returns a page of the user's ledger entries and a cursor
+// pointing at the next page, or nil if this is the last one.
+func (s *ServiceWallet) GetTransactions(ctx context.Context, userID string, filter TransactionFilter) ([]Transaction, *TransactionCursor, error) {
+	const op = "wallet.GetTransactions"
+	log := s.logger.With("op", op)
 
-	if !field.IsValid() {
-		return 0, errors.New("invalid currency: " + currency)
+	if filter.SortBy == "" {
+		filter.SortBy = SortByCreatedAt
+	}
+	if filter.After != nil && filter.After.SortBy != filter.SortBy {
+		return nil, nil, ErrInvalidCursor
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTransactionsLimit
 	}
+	limit := filter.Limit
+	filter.Limit++
 
-	if field.Kind() != reflect.Float32 {
-		return 0, errors.New("field is not of type float32")
+	transactions, err := s.storage.GetTransactions(ctx, userID, filter)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, nil, ErrSmtWentWrong
+	}
+	if len(transactions) <= limit {
+		return transactions, nil, nil
+	}
+	transactions = transactions[:limit]
+	last := transactions[limit-1]
+	next := &TransactionCursor{SortBy: filter.SortBy, ID: last.UUID}
+	switch filter.SortBy {
+	case SortByAmount:
+		next.Value = last.Amount.Abs().String()
+	default:
+		next.Value = last.CreatedAt.Format(time.RFC3339Nano)
 	}
+	return transactions, next, nil
+}
 
-	return float32(field.Float()), nil
+// getRate returns the rate of the pair given by the exchanger, cached for
+// rateCacheTTL. While the exchanger is unavailable it falls back to the last
+// known rate and then to the rate of the currency table.
+func (s *ServiceWallet) getRate(ctx context.Context, fromCurrency, toCurrency string) (sourcedRate, error) {
+	const op = "wallet.getRate"
+	log := s.logger.With(slog.String("op", op))
+
+	key := rateKey(fromCurrency, toCurrency)
+	if r, ok := s.cachedRate(ctx, log, key); ok {
+		return r, nil
+	}
+	rate, err := s.exchangerService.GetExchangeRateForCurrency(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		log.Error(err.Error())
+		return s.fallbackRate(ctx, log, fromCurrency, toCurrency)
+	}
+	r := sourcedRate{Rate: rate, Source: RateSourceExchanger, FetchedAt: time.Now().UTC()}
+	jsonData, err := json.Marshal(r)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	_ = s.cache.SetValue(ctx, key, string(jsonData), rateCacheTTL)
+	_ = s.cache.SetValue(ctx, lastKnownRateKey(fromCurrency, toCurrency), string(jsonData), 0)
+	s.recordRates(ctx, []ExchangeRate{{FromCurrency: fromCurrency, ToCurrency: toCurrency, Rate: rate, FetchedAt: r.FetchedAt}})
+	return r, nil
 }
 
-func updateBalanceByCurrency(wallet *Wallet, currency string, newValue float32) error {
-	fieldName := "Balance" + currency
-	v := reflect.ValueOf(wallet).Elem()
-	field := v.FieldByName(fieldName)
-	if !field.IsValid() {
-		return errors.New("invalid currency: " + currency)
+// applyTransactions posts the journal entry and refreshes the in-memory
+// wallet with the resulting balances of its accounts. It returns the postings
+// to the accounts of the wallet.
+func applyTransactions(ctx context.Context, st Storage, w *Wallet, entries []Transaction) ([]Transaction, error) {
+	applied, err := postEntry(ctx, st, entries)
+	if err != nil {
+		return nil, err
 	}
+	var postings []Transaction
+	for _, e := range applied {
+		if e.WalletUUID == w.UUID {
+			w.setBalance(e.BalanceAfter)
+			postings = append(postings, e)
+		}
+	}
+	return postings, nil
+}
 
-	if !field.CanSet() {
-		return errors.New("field cannot be set")
+// notifyBalance pushes the balances of the wallet and the postings that
+// changed them to the user's streaming clients. The operation is committed
+// at this point, so a failure is only logged; clients can still read the
+// balance. Zero balances are added to the wallet like for GetBalance.
+func (s *ServiceWallet) notifyBalance(ctx context.Context, log *slog.Logger, userID string, w Wallet, postings []Transaction) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.addZeroBalances(ctx, &w); err != nil {
+		return
+	}
+	update := BalanceUpdate{Balances: w.Balances, Transactions: postings}
+	if err := s.notifier.Publish(ctx, userID, update); err != nil {
+		log.Error(err.Error())
 	}
+}
 
-	if field.Kind() != reflect.Float32 {
-		return errors.New("field is not of type float32")
+// postEntry writes the postings of a journal entry to the ledger. The
+// database rejects unbalanced entries as well, checking them here gives a
+// clear error before anything is written.
+func postEntry(ctx context.Context, st Storage, entries []Transaction) ([]Transaction, error) {
+	if err := checkBalanced(entries); err != nil {
+		return nil, err
 	}
+	return st.ApplyTransactions(ctx, entries)
+}
 
-	field.SetFloat(float64(newValue))
+// checkBalanced returns ErrUnbalancedEntry unless the amounts of the postings
+// sum to zero in every currency.
+func checkBalanced(entries []Transaction) error {
+	sums := make(map[string]int64)
+	for _, e := range entries {
+		sums[e.Currency] += e.Amount.Minor
+	}
+	for _, sum := range sums {
+		if sum != 0 {
+			return ErrUnbalancedEntry
+		}
+	}
 	return nil
 }
+
+// domainError passes errors meant for the client through as is and logs and
+// hides everything else behind ErrSmtWentWrong.
+func domainError(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidAmountOrCurrency),
+		errors.Is(err, ErrNotEnoughFunds),
+		errors.Is(err, ErrInvalidRecipient),
+		errors.Is(err, ErrWalletNotFound),
+		errors.Is(err, ErrWalletFrozen),
+		errors.Is(err, ErrWalletNotFrozen),
+		errors.Is(err, stepup.ErrInvalidCode),
+		errors.Is(err, stepup.ErrTOTPNotEnrolled):
+		return err
+	}
+	log.Error(err.Error())
+	return ErrSmtWentWrong
+}
@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+	"wallet/pkg/pdf"
+)
+
+// statementPageSize is the number of ledger entries read at once while
+// building a statement.
+const statementPageSize = 100
+
+// Statement lists the user's ledger entries of a period per currency.
+type Statement struct {
+	UserUUID   string
+	From       time.Time
+	To         time.Time
+	Currencies []CurrencyStatement
+}
+
+// CurrencyStatement starts from the balance at the beginning of the period
+// and ends with the balance at its end. Every line carries the balance after
+// the entry in the order of the statement.
+type CurrencyStatement struct {
+	Currency       string
+	OpeningBalance Money
+	Lines          []StatementLine
+	ClosingBalance Money
+}
+
+type StatementLine struct {
+	Transaction
+	RunningBalance Money
+}
+
+// GetStatement builds the statement of the period [from, to) for the currency
+// or, if it is empty, for every currency the wallet holds. A zero to means
+// now.
+func (s *ServiceWallet) GetStatement(ctx context.Context, userID, currency string, from, to time.Time) (Statement, error) {
+	const op = "wallet.GetStatement"
+	log := s.logger.With("op", op)
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() || !to.After(from) {
+		return Statement{}, ErrInvalidPeriod
+	}
+	var c Currency
+	var err error
+	if currency != "" {
+		if c, err = s.currency(ctx, currency); err != nil {
+			return Statement{}, err
+		}
+	}
+
+	// Postings are stamped with microsecond precision, so this is the last
+	// instant before the period.
+	opening, err := s.storage.GetWalletByUserIDAt(ctx, userID, from.Add(-time.Microsecond))
+	if err != nil {
+		return Statement{}, domainError(log, err)
+	}
+	codes := []string{currency}
+	if currency == "" {
+		codes = codes[:0]
+		for code := range opening.Balances {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+	}
+
+	byCurrency := make(map[string]*CurrencyStatement, len(codes))
+	st := Statement{UserUUID: userID, From: from, To: to, Currencies: make([]CurrencyStatement, len(codes))}
+	for i, code := range codes {
+		st.Currencies[i].Currency = code
+		st.Currencies[i].OpeningBalance = opening.Balances[code]
+		if currency != "" {
+			st.Currencies[i].OpeningBalance = opening.Balance(c)
+		}
+		st.Currencies[i].ClosingBalance = st.Currencies[i].OpeningBalance
+		byCurrency[code] = &st.Currencies[i]
+	}
+
+	filter := TransactionFilter{Currency: currency, From: from, To: to, SortBy: SortByCreatedAt, Limit: statementPageSize}
+	for {
+		transactions, next, err := s.GetTransactions(ctx, userID, filter)
+		if err != nil {
+			return Statement{}, err
+		}
+		for _, t := range transactions {
+			cs, ok := byCurrency[t.Currency]
+			if !ok {
+				continue
+			}
+			cs.ClosingBalance = cs.ClosingBalance.Add(t.Amount)
+			cs.Lines = append(cs.Lines, StatementLine{Transaction: t, RunningBalance: cs.ClosingBalance})
+		}
+		if next == nil {
+			return st, nil
+		}
+		filter.After = next
+	}
+}
+
+// WriteStatementCSV writes the statement as CSV with a row per entry between
+// the opening and the closing balance rows of each currency.
+func WriteStatementCSV(w io.Writer, st Statement) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"currency", "created_at", "type", "operation_uuid", "transaction_uuid",
+		"counterparty_wallet_uuid", "amount", "balance"})
+	for _, cs := range st.Currencies {
+		_ = cw.Write([]string{cs.Currency, st.From.UTC().Format(time.RFC3339), "opening_balance",
+			"", "", "", "", cs.OpeningBalance.String()})
+		for _, l := range cs.Lines {
+			_ = cw.Write([]string{cs.Currency, l.CreatedAt.UTC().Format(time.RFC3339Nano), string(l.Type),
+				l.OperationUUID, l.UUID, l.CounterpartyWalletUUID, l.Amount.String(), l.RunningBalance.String()})
+		}
+		_ = cw.Write([]string{cs.Currency, st.To.UTC().Format(time.RFC3339), "closing_balance",
+			"", "", "", "", cs.ClosingBalance.String()})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteStatementPDF writes the statement as a PDF document with a section per
+// currency.
+func WriteStatementPDF(w io.Writer, st Statement) error {
+	const dateLayout = "2006-01-02 15:04:05"
+	row := "%-19s %-9s %18s %18s %s"
+
+	doc := pdf.New()
+	doc.Line("WALLET STATEMENT")
+	doc.Line("")
+	doc.Line("User:   " + st.UserUUID)
+	doc.Line(fmt.Sprintf("Period: %s - %s UTC", st.From.UTC().Format(dateLayout), st.To.UTC().Format(dateLayout)))
+	if len(st.Currencies) == 0 {
+		doc.Line("")
+		doc.Line("No accounts.")
+	}
+	for _, cs := range st.Currencies {
+		doc.Line("")
+		doc.Line("Currency: " + cs.Currency)
+		doc.Line(fmt.Sprintf(row, "Date", "Type", "Amount", "Balance", "Operation"))
+		doc.Line(fmt.Sprintf(row, st.From.UTC().Format(dateLayout), "opening", "", cs.OpeningBalance, ""))
+		for _, l := range cs.Lines {
+			doc.Line(fmt.Sprintf(row, l.CreatedAt.UTC().Format(dateLayout), l.Type, l.Amount, l.RunningBalance, l.OperationUUID))
+		}
+		doc.Line(fmt.Sprintf(row, st.To.UTC().Format(dateLayout), "closing", "", cs.ClosingBalance, ""))
+	}
+	_, err := doc.WriteTo(w)
+	return err
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"wallet/internal/domain/wallet"
+	"wallet/internal/domain/wallet/db"
+	"wallet/pkg/clients/psql"
+	"wallet/pkg/logger"
+)
+
+func TestAdminActions(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		userID     = "3c8a5e17-2b9d-4f61-a0e4-7d1c6b2f9a58"
+		operatorID = "e4f1a2b3-5c6d-4e7f-8091-a2b3c4d5e6f7"
+	)
+	ctx := context.Background()
+	qi := `INSERT INTO "user"(id, email, username, password) VALUES ($1, $2, $3, $4)`
+	qd := `DELETE FROM "user" WHERE id = $1`
+	_, err = psqlClient.Exec(ctx, qi, userID, "admin@gmail.com", "frozen", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, qd, userID); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	service := wallet.NewService(db.NewRepository(psqlClient, log), log, newMemoryCache(), nil, nil, nil, wallet.RateConfig{})
+	if err = service.CreateUserWallet(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	amount, err := service.ParseAmount(ctx, "10", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := service.FreezeWallet(ctx, operatorID, userID, "chargeback investigation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Frozen == nil || w.Frozen.OperatorUUID != operatorID {
+		t.Fatal("want the wallet frozen by the operator")
+	}
+	if _, err = service.FreezeWallet(ctx, operatorID, userID, "again"); !errors.Is(err, wallet.ErrWalletFrozen) {
+		t.Fatalf("want ErrWalletFrozen, got %v", err)
+	}
+	if _, err = service.WalletDeposit(ctx, userID, amount); !errors.Is(err, wallet.ErrWalletFrozen) {
+		t.Fatalf("want the owner's deposit rejected, got %v", err)
+	}
+
+	// Adjustments are made while the wallet is frozen.
+	w, err = service.AdjustBalance(ctx, operatorID, userID, amount, "goodwill credit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Balances["USD"].String(); got != "10.00" {
+		t.Fatalf("want balance 10.00, got %s", got)
+	}
+	if _, err = service.AdjustBalance(ctx, operatorID, userID, amount.Add(amount).Neg(), "too much"); !errors.Is(err, wallet.ErrNotEnoughFunds) {
+		t.Fatalf("want ErrNotEnoughFunds, got %v", err)
+	}
+
+	if _, err = service.UnfreezeWallet(ctx, operatorID, userID, "resolved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.WalletWithdraw(ctx, userID, amount); err != nil {
+		t.Fatal(err)
+	}
+
+	actions, err := service.GetAdminActions(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{wallet.AdminActionUnfreeze, wallet.AdminActionAdjust, wallet.AdminActionFreeze}
+	if len(actions) != len(want) {
+		t.Fatalf("want %d actions, got %d", len(want), len(actions))
+	}
+	for i, a := range actions {
+		if a.Action != want[i] || a.OperatorUUID != operatorID {
+			t.Fatalf("action %d: want %s by the operator, got %s by %s", i, want[i], a.Action, a.OperatorUUID)
+		}
+	}
+	if actions[1].Amount == nil || actions[1].Amount.String() != "10.00" || actions[1].OperationUUID == "" {
+		t.Fatal("want the adjustment with its amount and journal entry")
+	}
+
+	// Views of the wallet are recorded too.
+	if _, err = service.GetWallet(ctx, operatorID, userID); err != nil {
+		t.Fatal(err)
+	}
+	transactions, _, err := service.GetWalletTransactions(ctx, operatorID, userID, wallet.TransactionFilter{Limit: 10, Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) == 0 {
+		t.Fatal("want the transactions of the wallet")
+	}
+	if actions, err = service.GetAdminActions(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{wallet.AdminActionViewTransactions, wallet.AdminActionViewWallet, wallet.AdminActionUnfreeze}
+	if len(actions) < len(want) {
+		t.Fatalf("want at least %d actions, got %d", len(want), len(actions))
+	}
+	for i, w := range want {
+		if actions[i].Action != w || actions[i].OperatorUUID != operatorID || actions[i].WalletUUID != actions[2].WalletUUID {
+			t.Fatalf("action %d: want %s by the operator, got %s by %s", i, w, actions[i].Action, actions[i].OperatorUUID)
+		}
+	}
+
+	if _, err = service.GetWallet(ctx, operatorID, "00000000-0000-0000-0000-0000000000ff"); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Fatalf("want ErrWalletNotFound, got %v", err)
+	}
+	if _, _, err = service.GetWalletTransactions(ctx, operatorID, "00000000-0000-0000-0000-0000000000ff", wallet.TransactionFilter{Limit: 10}); !errors.Is(err, wallet.ErrWalletNotFound) {
+		t.Fatalf("want ErrWalletNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain/wallet"
+	"wallet/internal/domain/wallet/db"
+	"wallet/pkg/clients/psql"
+	"wallet/pkg/logger"
+)
+
+func TestConcurrentBalanceChanges(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const userID = "4d0f7a5e-2c1b-4b8e-9a53-0f3c8e6d1a27"
+	const workers = 50
+	ctx := context.Background()
+	qi := `INSERT INTO "user"(id, email, username, password) VALUES ($1, $2, $3, $4)`
+	qd := `DELETE FROM "user" WHERE id = $1`
+	_, err = psqlClient.Exec(ctx, qi, userID, "concurrency@gmail.com", "concurrency", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, qd, userID); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	storage := db.NewRepository(psqlClient, log)
+	service := wallet.NewService(storage, log, newMemoryCache(), nil, nil, nil, wallet.RateConfig{})
+	if err = service.CreateUserWallet(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	usd := func(s string) wallet.Money {
+		m, err := service.ParseAmount(ctx, wallet.Decimal(s), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	t.Run("Parallel Deposits", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := service.WalletDeposit(ctx, userID, usd("2.50")); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatal(err)
+		}
+		w, err := service.GetBalance(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Balances["USD"] != usd("125.00") {
+			t.Fatalf("want 125.00, got %s", w.Balances["USD"])
+		}
+	})
+	t.Run("Parallel Withdrawals", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		succeeded := 0
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := service.WalletWithdraw(ctx, userID, usd("10"))
+				switch {
+				case err == nil:
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				case !errors.Is(err, wallet.ErrInvalidAmountOrCurrency):
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		if succeeded != 12 {
+			t.Fatalf("want 12 successful withdrawals, got %d", succeeded)
+		}
+		w, err := service.GetBalance(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.Balances["USD"] != usd("5.00") {
+			t.Fatalf("want 5.00, got %s", w.Balances["USD"])
+		}
+	})
+	t.Run("Ledger Matches Balance", func(t *testing.T) {
+		transactions, _, err := service.GetTransactions(ctx, userID, wallet.TransactionFilter{
+			Currency: "USD",
+			Limit:    100,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transactions) != workers+12 {
+			t.Fatalf("want %d entries, got %d", workers+12, len(transactions))
+		}
+		sum := usd("0")
+		for _, e := range transactions {
+			sum = sum.Add(e.Amount)
+		}
+		if sum != usd("5.00") {
+			t.Fatalf("want ledger sum 5.00, got %s", sum)
+		}
+	})
+}
+
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string]string)}
+}
+
+func (c *memoryCache) GetValue(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key], nil
+}
+
+func (c *memoryCache) SetValue(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *memoryCache) DeleteValue(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *memoryCache) PopValue(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value := c.values[key]
+	delete(c.values, key)
+	return value, nil
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"wallet/internal/domain/wallet"
+)
+
+func TestMoney(t *testing.T) {
+	usd := wallet.Currency{Code: "USD", Precision: 2, Rounding: wallet.RoundHalfEven}
+	eur := wallet.Currency{Code: "EUR", Precision: 2, Rounding: wallet.RoundHalfEven}
+	rub := wallet.Currency{Code: "RUB", Precision: 2, Rounding: wallet.RoundHalfUp}
+	jpy := wallet.Currency{Code: "JPY", Precision: 0, Rounding: wallet.RoundHalfEven}
+
+	t.Run("Parse", func(t *testing.T) {
+		cases := []struct {
+			in    string
+			minor int64
+			ok    bool
+		}{
+			{"10", 1000, true},
+			{"10.5", 1050, true},
+			{"0.01", 1, true},
+			{"-3.10", -310, true},
+			{"12.300", 1230, true},
+			{"12.345", 0, false},
+			{"1e3", 0, false},
+			{"1/3", 0, false},
+			{"", 0, false},
+		}
+		for _, c := range cases {
+			m, err := wallet.ParseMoney(c.in, usd)
+			if (err == nil) != c.ok {
+				t.Fatalf("%q: want ok=%v, got %v", c.in, c.ok, err)
+			}
+			if c.ok && m.Minor != c.minor {
+				t.Fatalf("%q: want %d, got %d", c.in, c.minor, m.Minor)
+			}
+		}
+		if _, err := wallet.ParseMoney("1.5", jpy); err == nil {
+			t.Fatal("want error for fractional JPY amount")
+		}
+	})
+	t.Run("Format", func(t *testing.T) {
+		cases := map[int64]string{0: "0.00", 5: "0.05", -5: "-0.05", 123456: "1234.56"}
+		for minor, want := range cases {
+			data, _ := json.Marshal(wallet.Money{Minor: minor, Currency: "EUR", Precision: 2})
+			if string(data) != `"`+want+`"` {
+				t.Fatalf("want %q, got %s", want, data)
+			}
+		}
+	})
+	t.Run("Percent", func(t *testing.T) {
+		cases := []struct {
+			minor   int64
+			percent wallet.Decimal
+			mode    wallet.RoundingMode
+			want    int64
+		}{
+			{10000, "0.5", wallet.RoundHalfEven, 50},
+			{1050, "1", wallet.RoundHalfEven, 10}, // 10.5 -> 10
+			{1050, "1", wallet.RoundHalfUp, 11},
+			{1099, "1", wallet.RoundDown, 10},
+			{12345, "0", wallet.RoundHalfEven, 0},
+		}
+		for _, c := range cases {
+			m := wallet.Money{Minor: c.minor, Currency: "USD", Precision: 2}
+			got, err := m.Percent(c.percent, c.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Minor != c.want {
+				t.Fatalf("%s%% of %s: want %d, got %d", c.percent, m, c.want, got.Minor)
+			}
+		}
+		if _, err := usd.Zero().Percent("1e2", wallet.RoundHalfEven); err == nil {
+			t.Fatal("want error for invalid percent")
+		}
+	})
+	t.Run("Convert", func(t *testing.T) {
+		cases := []struct {
+			minor int64
+			rate  float32
+			to    wallet.Currency
+			want  int64
+		}{
+			{1000, 1.1, eur, 909},  // 9.0909...
+			{1000, 0.8, eur, 1250}, // exact
+			{1, 0.4, eur, 2},       // 0.025 -> half-even rounds to 0.02
+			{3, 0.4, eur, 8},       // 0.075 -> half-even rounds to 0.08
+			{1, 0.4, rub, 3},       // 0.025 -> half-up rounds to 0.03
+			{1050, 0.01, jpy, 1050},
+		}
+		for _, c := range cases {
+			m := wallet.Money{Minor: c.minor, Currency: "USD", Precision: 2}
+			got, err := m.Convert(c.rate, c.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Minor != c.want || got.Currency != c.to.Code {
+				t.Fatalf("%s / %v: want %d %s, got %d %s", m, c.rate, c.want, c.to.Code, got.Minor, got.Currency)
+			}
+		}
+	})
+}
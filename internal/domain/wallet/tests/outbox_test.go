@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"wallet/internal/domain/outbox"
+	outboxDB "wallet/internal/domain/outbox/db"
+	"wallet/internal/domain/wallet"
+	"wallet/internal/domain/wallet/db"
+	"wallet/pkg/clients/psql"
+	"wallet/pkg/logger"
+)
+
+func TestOutbox(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const userID = "9b2e6c41-7d3a-4f0e-8c15-2a6b9e4d7f03"
+	ctx := context.Background()
+	qi := `INSERT INTO "user"(id, email, username, password) VALUES ($1, $2, $3, $4)`
+	qd := `DELETE FROM "user" WHERE id = $1`
+	_, err = psqlClient.Exec(ctx, qi, userID, "outbox@gmail.com", "outbox", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, qd, userID); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	service := wallet.NewService(db.NewRepository(psqlClient, log), log, newMemoryCache(), nil, nil, nil, wallet.RateConfig{})
+	publisher := outbox.NewMemoryPublisher()
+	relay := outbox.NewRelay(outboxDB.NewRepository(psqlClient, log), publisher, log, 10)
+	// Publish whatever other tests left in the outbox, so only the events
+	// of this test are checked below.
+	if err = relay.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	published := len(publisher.Events())
+
+	if err = service.CreateUserWallet(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	amount, err := service.ParseAmount(ctx, "10", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.WalletDeposit(ctx, userID, amount); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.WalletWithdraw(ctx, userID, amount.Add(amount)); err == nil {
+		t.Fatal("want error for withdrawal above the balance")
+	}
+	if _, err = service.WalletWithdraw(ctx, userID, amount); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = relay.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	events := publisher.Events()[published:]
+	want := []string{wallet.EventWalletCreated, wallet.EventDeposited, wallet.EventWithdrawn}
+	if len(events) != len(want) {
+		t.Fatalf("want %d events, got %d", len(want), len(events))
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Fatalf("event %d: want %s, got %s", i, want[i], e.Type)
+		}
+		if e.AggregateID != events[0].AggregateID {
+			t.Fatalf("event %d: want aggregate %s, got %s", i, events[0].AggregateID, e.AggregateID)
+		}
+	}
+	var deposited struct {
+		UserUUID string `json:"user_uuid"`
+		Amount   string `json:"amount"`
+		Balance  string `json:"balance"`
+	}
+	if err = json.Unmarshal(events[1].Payload, &deposited); err != nil {
+		t.Fatal(err)
+	}
+	if deposited.UserUUID != userID || deposited.Amount != "10.00" || deposited.Balance != "10.00" {
+		t.Fatalf("unexpected Deposited payload %s", events[1].Payload)
+	}
+
+	if n, err := relay.PublishPending(ctx); err != nil || n != 0 {
+		t.Fatalf("want no events left, got %d (%v)", n, err)
+	}
+
+	t.Run("Transfer", func(t *testing.T) {
+		const recipientID = "4f8c2a6e-1b9d-4e37-a05c-7d3e9b1f6a28"
+		if _, err := psqlClient.Exec(ctx, qi, recipientID, "outbox-recipient@gmail.com", "outbox-recipient", "password"); err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if _, err := psqlClient.Exec(ctx, qd, recipientID); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		if err := service.CreateUserWallet(ctx, recipientID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := service.WalletDeposit(ctx, userID, amount); err != nil {
+			t.Fatal(err)
+		}
+		transferred, err := service.ParseAmount(ctx, "4", "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = service.Transfer(ctx, userID, wallet.Recipient{UserID: recipientID}, transferred); err != nil {
+			t.Fatal(err)
+		}
+		published := len(publisher.Events())
+		if err = relay.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		payloads := make(map[string]map[string]interface{})
+		for _, e := range publisher.Events()[published:] {
+			var p map[string]interface{}
+			if err = json.Unmarshal(e.Payload, &p); err != nil {
+				t.Fatal(err)
+			}
+			payloads[e.Type] = p
+		}
+		sent, received := payloads[wallet.EventTransferred], payloads[wallet.EventTransferReceived]
+		if sent["balance"] != "6.00" || received["balance"] != "4.00" || received["user_uuid"] != recipientID {
+			t.Fatalf("want each side's own balance, got %v and %v", sent, received)
+		}
+		// Each event is delivered to the owner of its wallet, so the
+		// recipient must not learn the sender's balance.
+		for k, v := range received {
+			if v == sent["balance"] {
+				t.Fatalf("want no sender's balance in TransferReceived, got %s=%v", k, v)
+			}
+		}
+	})
+}
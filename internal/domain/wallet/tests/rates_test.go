@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"wallet/internal/domain/wallet"
+	"wallet/internal/domain/wallet/db"
+	"wallet/pkg/clients/psql"
+	"wallet/pkg/logger"
+)
+
+func TestPairRate(t *testing.T) {
+	rates := map[string]float32{"USD": 1, "EUR": 1.5, "RUB": 0.8}
+	if rate, ok := wallet.PairRate(rates, "USD", "EUR"); !ok || rate != 1.5 {
+		t.Fatalf("want 1.5 for USD/EUR, got %v (%v)", rate, ok)
+	}
+	if rate, ok := wallet.PairRate(rates, "EUR", "USD"); !ok || rate != 1/float32(1.5) {
+		t.Fatalf("want %v for EUR/USD, got %v (%v)", 1/float32(1.5), rate, ok)
+	}
+	if _, ok := wallet.PairRate(rates, "USD", "GBP"); ok {
+		t.Fatal("want no rate for an unknown currency")
+	}
+}
+
+func TestParsePair(t *testing.T) {
+	if from, to, ok := wallet.ParsePair(" usd/EUR"); !ok || from != "USD" || to != "EUR" {
+		t.Fatalf("want USD/EUR, got %s/%s (%v)", from, to, ok)
+	}
+	for _, pair := range []string{"USDEUR", "USD/USD", "US/EUR", "USD/EUR/RUB"} {
+		if _, _, ok := wallet.ParsePair(pair); ok {
+			t.Fatalf("want %q to be invalid", pair)
+		}
+	}
+}
+
+func TestRateHistory(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// The currencies are made up, so the rates can't mix with real ones.
+	qd := `DELETE FROM exchange_rate WHERE from_currency = 'TSA' AND to_currency = 'TSB'`
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, qd); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	storage := db.NewRepository(psqlClient, log)
+	service := wallet.NewService(storage, log, newMemoryCache(), nil, nil, nil, wallet.RateConfig{})
+	start := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+	rates := []wallet.ExchangeRate{
+		{FromCurrency: "TSA", ToCurrency: "TSB", Rate: 2, FetchedAt: start.Add(10 * time.Minute)},
+		{FromCurrency: "TSA", ToCurrency: "TSB", Rate: 3, FetchedAt: start.Add(20 * time.Minute)},
+		{FromCurrency: "TSA", ToCurrency: "TSB", Rate: 1, FetchedAt: start.Add(30 * time.Minute)},
+		{FromCurrency: "TSA", ToCurrency: "TSB", Rate: 1.5, FetchedAt: start.Add(40 * time.Minute)},
+		{FromCurrency: "TSA", ToCurrency: "TSB", Rate: 4, FetchedAt: start.Add(2*time.Hour + time.Minute)},
+	}
+	if err = storage.AddExchangeRates(ctx, rates); err != nil {
+		t.Fatal(err)
+	}
+
+	candles, err := service.GetRateHistory(ctx, "TSA", "TSB", time.Hour, start, start.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []wallet.Candle{
+		{OpenTime: start, Open: 2, High: 3, Low: 1, Close: 1.5, Count: 4},
+		{OpenTime: start.Add(2 * time.Hour), Open: 4, High: 4, Low: 4, Close: 4, Count: 1},
+	}
+	if len(candles) != len(want) {
+		t.Fatalf("want %d candles, got %d", len(want), len(candles))
+	}
+	for i := range want {
+		if !candles[i].OpenTime.Equal(want[i].OpenTime) || candles[i].Open != want[i].Open ||
+			candles[i].High != want[i].High || candles[i].Low != want[i].Low ||
+			candles[i].Close != want[i].Close || candles[i].Count != want[i].Count {
+			t.Fatalf("candle %d: want %+v, got %+v", i, want[i], candles[i])
+		}
+	}
+
+	if _, err = service.GetRateHistory(ctx, "TSA", "TSB", time.Minute, start, start.AddDate(0, 1, 0)); !errors.Is(err, wallet.ErrInvalidPeriod) {
+		t.Fatalf("want ErrInvalidPeriod for too many candles, got %v", err)
+	}
+}
+
+// flakyExchanger returns fixed rates until it is marked down.
+type flakyExchanger struct {
+	down bool
+}
+
+func (e *flakyExchanger) GetExchangeRateForCurrency(_ context.Context, from, to string) (float32, error) {
+	if e.down {
+		return 0, errors.New("exchanger is down")
+	}
+	rate, _ := wallet.PairRate(map[string]float32{"USD": 1, "EUR": 2, "RUB": 100}, from, to)
+	return rate, nil
+}
+
+func (e *flakyExchanger) GetExchangeRates(context.Context) (wallet.ExchangeRateResponse, error) {
+	if e.down {
+		return wallet.ExchangeRateResponse{}, errors.New("exchanger is down")
+	}
+	return wallet.ExchangeRateResponse{Rates: map[string]float32{"USD": 1, "EUR": 2, "RUB": 100}}, nil
+}
+
+func TestRatesFallback(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	log := logger.SetupLogger(logger.Prod, "")
+	storage := db.NewRepository(psqlClient, log)
+	cache := newMemoryCache()
+	exchanger := &flakyExchanger{}
+	service := wallet.NewService(storage, log, cache, exchanger, nil, nil, wallet.RateConfig{MaxStaleness: time.Hour})
+
+	res, err := service.GetExchangeRates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Origin == nil || res.Origin.Source != wallet.RateSourceExchanger || res.Origin.Stale {
+		t.Fatalf("want fresh rates of the exchanger, got %+v", res.Origin)
+	}
+
+	exchanger.down = true
+	if err = cache.DeleteValue(ctx, "exchange_rates"); err != nil {
+		t.Fatal(err)
+	}
+	res, err = service.GetExchangeRates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Origin.Source != wallet.RateSourceLastKnown || !res.Origin.Stale || res.Origin.AgeSeconds == nil || res.Rates["EUR"] != 2 {
+		t.Fatalf("want the last known rates, got %+v %v", res.Origin, res.Rates)
+	}
+	amount, err := service.ParseAmount(ctx, "10", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const userID = "5f1c2e8a-3b7d-4c9e-a061-7d2f4b8e9c13"
+	if _, err = service.CreateQuote(ctx, userID, amount, "EUR"); !errors.Is(err, wallet.ErrRatesUnavailable) {
+		t.Fatalf("want ErrRatesUnavailable for a stale rate, got %v", err)
+	}
+	lenient := wallet.NewService(storage, log, cache, exchanger, nil, nil, wallet.RateConfig{MaxStaleness: time.Hour, AllowStaleExchanges: true})
+	q, err := lenient.CreateQuote(ctx, userID, amount, "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Rate != 2 || q.RateOrigin.Source != wallet.RateSourceLastKnown {
+		t.Fatalf("want a quote at the last known rate, got %v from %+v", q.Rate, q.RateOrigin)
+	}
+
+	// Without the last known rates the currency table is used.
+	strict := wallet.NewService(storage, log, newMemoryCache(), exchanger, nil, nil, wallet.RateConfig{MaxStaleness: time.Hour})
+	res, err = strict.GetExchangeRates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Origin.Source != wallet.RateSourceCurrencyTable || res.Origin.AgeSeconds != nil || res.Rates["USD"] != 1 {
+		t.Fatalf("want the rates of the currency table, got %+v %v", res.Origin, res.Rates)
+	}
+
+	// The rates of the table keep their precision.
+	var rubRate string
+	if err = psqlClient.QueryRow(ctx, `SELECT rate::TEXT FROM currency WHERE code = 'RUB'`).Scan(&rubRate); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, `UPDATE currency SET rate = $1::NUMERIC WHERE code = 'RUB'`, rubRate); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if _, err = psqlClient.Exec(ctx, `UPDATE currency SET rate = 0.0123456789 WHERE code = 'RUB'`); err != nil {
+		t.Fatal(err)
+	}
+	res, err = wallet.NewService(storage, log, newMemoryCache(), exchanger, nil, nil, wallet.RateConfig{}).GetExchangeRates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rates["RUB"] != float32(0.0123456789) {
+		t.Fatalf("want the RUB rate of the table unrounded, got %v", res.Rates["RUB"])
+	}
+}
@@ -2,9 +2,11 @@ package tests
 
 import (
 	"context"
+	"errors"
 	"github.com/joho/godotenv"
 	"os"
 	"testing"
+	"time"
 	"wallet/internal/domain/wallet"
 	"wallet/internal/domain/wallet/db"
 	"wallet/pkg/clients/psql"
@@ -74,23 +76,69 @@ func TestService(t *testing.T) {
 	log := logger.SetupLogger(logger.Local, "")
 	storage := db.NewRepository(psqlClient, log)
 	newWallet := wallet.Wallet{
-		UserUUID:   userID,
-		BalanceEUR: 100.12,
-		BalanceUSD: 42.12,
-		BalanceRUB: 123.1,
+		UserUUID: userID,
+		Balances: map[string]wallet.Money{
+			"EUR": {Minor: 10012, Currency: "EUR", Precision: 2},
+			"USD": {Minor: 4212, Currency: "USD", Precision: 2},
+			"RUB": {Minor: 12310, Currency: "RUB", Precision: 2},
+		},
 	}
 
 	t.Run("Create One", func(t *testing.T) {
-		err := storage.CreateWallet(ctx, userID)
+		_, err := storage.CreateWallet(ctx, userID)
 		if err != nil {
 			t.Fatal(err)
 		}
 	})
-	t.Run("Update One", func(t *testing.T) {
-		err := storage.UpdateWallet(ctx, userID, newWallet)
+	t.Run("Apply Transactions", func(t *testing.T) {
+		w, err := storage.GetWalletByUserID(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entries []wallet.Transaction
+		for currency, amount := range newWallet.Balances {
+			entries = append(entries, wallet.Transaction{
+				WalletUUID: w.UUID,
+				Type:       wallet.TransactionDeposit,
+				Currency:   currency,
+				Amount:     amount,
+			}, wallet.Transaction{
+				AccountType: wallet.AccountSettlement,
+				Type:        wallet.TransactionDeposit,
+				Currency:    currency,
+				Amount:      amount.Neg(),
+			})
+		}
+		applied, err := storage.ApplyTransactions(ctx, entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applied) != len(entries) {
+			t.Fatalf("want %d entries, got %d", len(entries), len(applied))
+		}
+		for i, e := range applied {
+			if e.OperationUUID != applied[0].OperationUUID {
+				t.Fatalf("entry %d: want operation %s, got %s", i, applied[0].OperationUUID, e.OperationUUID)
+			}
+			if e.WalletUUID != "" && e.BalanceAfter != entries[i].Amount {
+				t.Fatalf("entry %d: want balance %s, got %s", i, entries[i].Amount, e.BalanceAfter)
+			}
+		}
+	})
+	t.Run("Reject Unbalanced Entry", func(t *testing.T) {
+		w, err := storage.GetWalletByUserID(ctx, userID)
 		if err != nil {
 			t.Fatal(err)
 		}
+		entries := []wallet.Transaction{{
+			WalletUUID: w.UUID,
+			Type:       wallet.TransactionDeposit,
+			Currency:   "USD",
+			Amount:     wallet.Money{Minor: 100, Currency: "USD", Precision: 2},
+		}}
+		if _, err = storage.ApplyTransactions(ctx, entries); err == nil {
+			t.Fatal("want error for unbalanced entry")
+		}
 	})
 	t.Run("Get One", func(t *testing.T) {
 		w, err := storage.GetWalletByUserID(ctx, userID)
@@ -101,14 +149,46 @@ func TestService(t *testing.T) {
 		if w.UserUUID != newWallet.UserUUID {
 			t.Fatalf("want %s, got %s", userID, w.UserUUID)
 		}
-		if w.BalanceEUR != newWallet.BalanceEUR {
-			t.Fatalf("want %f, got %f", newWallet.BalanceEUR, w.BalanceEUR)
+		if len(w.Balances) != len(newWallet.Balances) {
+			t.Fatalf("want %d balances, got %d", len(newWallet.Balances), len(w.Balances))
+		}
+		for currency, want := range newWallet.Balances {
+			if w.Balances[currency] != want {
+				t.Fatalf("%s: want %s, got %s", currency, want, w.Balances[currency])
+			}
+		}
+	})
+	t.Run("Get One At", func(t *testing.T) {
+		if _, err := storage.GetWalletByUserIDAt(ctx, "00000000-0000-0000-0000-0000000000ff", time.Now()); !errors.Is(err, wallet.ErrWalletNotFound) {
+			t.Fatalf("want ErrWalletNotFound, got %v", err)
 		}
-		if w.BalanceUSD != newWallet.BalanceUSD {
-			t.Fatalf("want %f, got %f", newWallet.BalanceUSD, w.BalanceUSD)
+		w, err := storage.GetWalletByUserIDAt(ctx, userID, time.Unix(0, 0))
+		if err != nil {
+			t.Fatal(err)
 		}
-		if w.BalanceRUB != newWallet.BalanceRUB {
-			t.Fatalf("want %f, got %f", newWallet.BalanceRUB, w.BalanceRUB)
+		for currency, b := range w.Balances {
+			if b.Minor != 0 {
+				t.Fatalf("%s: want zero balance before any postings, got %s", currency, b)
+			}
+		}
+
+		// The same balances must come out with and without a snapshot.
+		now := time.Now().Add(time.Minute)
+		for _, snapshot := range []bool{false, true} {
+			if snapshot {
+				if err = storage.CreateSnapshots(ctx, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w, err = storage.GetWalletByUserIDAt(ctx, userID, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for currency, want := range newWallet.Balances {
+				if w.Balances[currency] != want {
+					t.Fatalf("snapshot=%v %s: want %s, got %s", snapshot, currency, want, w.Balances[currency])
+				}
+			}
 		}
 	})
 	_, err = psqlClient.Exec(ctx, qd, userID)
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+	"wallet/internal/domain/wallet"
+)
+
+func TestStatementFormats(t *testing.T) {
+	usd := func(minor int64) wallet.Money {
+		return wallet.Money{Minor: minor, Currency: "USD", Precision: 2}
+	}
+	from := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	st := wallet.Statement{
+		UserUUID: "4d0f7a5e-2c1b-4b8e-9a53-0f3c8e6d1a27",
+		From:     from,
+		To:       from.AddDate(0, 1, 0),
+		Currencies: []wallet.CurrencyStatement{{
+			Currency:       "USD",
+			OpeningBalance: usd(1000),
+			Lines: []wallet.StatementLine{
+				{Transaction: wallet.Transaction{Type: wallet.TransactionDeposit, Amount: usd(250), CreatedAt: from.Add(time.Hour)}, RunningBalance: usd(1250)},
+				{Transaction: wallet.Transaction{Type: wallet.TransactionWithdraw, Amount: usd(-50), CreatedAt: from.Add(2 * time.Hour)}, RunningBalance: usd(1200)},
+			},
+			ClosingBalance: usd(1200),
+		}},
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := wallet.WriteStatementCSV(&buf, st); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 5 {
+			t.Fatalf("want 5 rows, got %d", len(rows))
+		}
+		balance := func(row []string) string { return row[len(row)-1] }
+		if rows[1][2] != "opening_balance" || balance(rows[1]) != "10.00" {
+			t.Fatalf("unexpected opening row %v", rows[1])
+		}
+		if balance(rows[3]) != "12.00" || rows[3][6] != "-0.50" {
+			t.Fatalf("unexpected entry row %v", rows[3])
+		}
+		if rows[4][2] != "closing_balance" || balance(rows[4]) != "12.00" {
+			t.Fatalf("unexpected closing row %v", rows[4])
+		}
+	})
+	t.Run("PDF", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := wallet.WriteStatementPDF(&buf, st); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if !strings.HasPrefix(out, "%PDF-") || !strings.HasSuffix(out, "%%EOF\n") {
+			t.Fatal("output is not a PDF document")
+		}
+		if !strings.Contains(out, "12.50") || !strings.Contains(out, "Currency: USD") {
+			t.Fatal("statement lines are missing")
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain/stepup"
+	"wallet/internal/domain/wallet"
+	"wallet/internal/domain/wallet/db"
+	"wallet/pkg/clients/psql"
+	"wallet/pkg/logger"
+)
+
+const confirmationCode = "123456"
+
+// fixedCodeStepUp answers every challenge with confirmationCode.
+type fixedCodeStepUp struct {
+	mu         sync.Mutex
+	challenges map[string]bool
+}
+
+func (s *fixedCodeStepUp) CreateChallenge(_ context.Context, userID string, _ stepup.Method) (stepup.Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := fmt.Sprintf("00000000-0000-0000-0000-%012d", len(s.challenges))
+	s.challenges[userID+id] = true
+	return stepup.Challenge{ID: id, Method: stepup.MethodOTP, ExpiresAt: time.Now().Add(time.Minute)}, nil
+}
+
+func (s *fixedCodeStepUp) VerifyChallenge(_ context.Context, userID, challengeID, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.challenges[userID+challengeID] {
+		return stepup.ErrChallengeNotFound
+	}
+	if code != confirmationCode {
+		return stepup.ErrInvalidCode
+	}
+	s.challenges[userID+challengeID] = false
+	return nil
+}
+
+func TestWithdrawConfirmation(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const userID = "5b2e9c14-7d3a-4f08-b6e1-2a9c8d4f7e35"
+	ctx := context.Background()
+	qi := `INSERT INTO "user"(id, email, username, password) VALUES ($1, $2, $3, $4)`
+	qd := `DELETE FROM "user" WHERE id = $1`
+	_, err = psqlClient.Exec(ctx, qi, userID, "stepup@gmail.com", "stepup", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, qd, userID); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	service := wallet.NewService(db.NewRepository(psqlClient, log), log, newMemoryCache(), nil, nil,
+		&fixedCodeStepUp{challenges: make(map[string]bool)}, wallet.RateConfig{})
+	currencies, err := service.GetCurrencies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var usd wallet.Currency
+	for _, c := range currencies {
+		if c.Code == "USD" {
+			usd = c
+		}
+	}
+	withThreshold := usd
+	withThreshold.ConfirmationThreshold = "100"
+	if _, err = service.UpdateCurrency(ctx, withThreshold); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := service.UpdateCurrency(ctx, usd); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err = service.CreateUserWallet(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	parse := func(amount wallet.Decimal) wallet.Money {
+		m, err := service.ParseAmount(ctx, amount, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	if _, err = service.WalletDeposit(ctx, userID, parse("300")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Amounts up to the threshold are withdrawn right away.
+	w, pending, err := service.RequestWithdraw(ctx, userID, parse("100"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != nil || w.Balances["USD"].String() != "200.00" {
+		t.Fatalf("want the withdrawal made, got %+v", pending)
+	}
+	if _, _, err = service.RequestWithdraw(ctx, userID, parse("250"), ""); !errors.Is(err, wallet.ErrInvalidAmountOrCurrency) {
+		t.Fatalf("want a withdrawal above the balance rejected before the challenge, got %v", err)
+	}
+
+	_, pending, err = service.RequestWithdraw(ctx, userID, parse("150"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending == nil || pending.Amount.String() != "150.00" {
+		t.Fatalf("want a pending withdrawal, got %+v", pending)
+	}
+	if w, err = service.GetBalance(ctx, userID); err != nil || w.Balances["USD"].String() != "200.00" {
+		t.Fatalf("want the balance untouched until confirmed, got %v %v", w.Balances, err)
+	}
+	if _, err = service.ConfirmWithdraw(ctx, userID, pending.ID, "000000"); !errors.Is(err, stepup.ErrInvalidCode) {
+		t.Fatalf("want ErrInvalidCode, got %v", err)
+	}
+	if w, err = service.ConfirmWithdraw(ctx, userID, pending.ID, confirmationCode); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Balances["USD"].String(); got != "50.00" {
+		t.Fatalf("want balance 50.00, got %s", got)
+	}
+	if _, err = service.ConfirmWithdraw(ctx, userID, pending.ID, confirmationCode); !errors.Is(err, wallet.ErrWithdrawalExpired) {
+		t.Fatalf("want ErrWithdrawalExpired confirming twice, got %v", err)
+	}
+}
+
+func TestTransferConfirmation(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		userID      = "8e4a2c61-9b3d-4f17-a5e0-6c1d7b2f9a43"
+		recipientID = "1f7d3b95-4e2a-4c68-b0d9-3a8e6c5f2b17"
+	)
+	ctx := context.Background()
+	qi := `INSERT INTO "user"(id, email, username, password) VALUES ($1, $2, $3, $4)`
+	qd := `DELETE FROM "user" WHERE id = $1`
+	if _, err = psqlClient.Exec(ctx, qi, userID, "stepup-sender@gmail.com", "stepup-sender", "password"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, qd, userID); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if _, err = psqlClient.Exec(ctx, qi, recipientID, "stepup-recipient@gmail.com", "stepup-recipient", "password"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := psqlClient.Exec(ctx, qd, recipientID); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	service := wallet.NewService(db.NewRepository(psqlClient, log), log, newMemoryCache(), nil, nil,
+		&fixedCodeStepUp{challenges: make(map[string]bool)}, wallet.RateConfig{})
+	currencies, err := service.GetCurrencies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var usd wallet.Currency
+	for _, c := range currencies {
+		if c.Code == "USD" {
+			usd = c
+		}
+	}
+	withThreshold := usd
+	withThreshold.ConfirmationThreshold = "100"
+	if _, err = service.UpdateCurrency(ctx, withThreshold); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := service.UpdateCurrency(ctx, usd); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, id := range []string{userID, recipientID} {
+		if err = service.CreateUserWallet(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parse := func(amount wallet.Decimal) wallet.Money {
+		m, err := service.ParseAmount(ctx, amount, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	if _, err = service.WalletDeposit(ctx, userID, parse("300")); err != nil {
+		t.Fatal(err)
+	}
+	to := wallet.Recipient{Username: "stepup-recipient"}
+
+	// Amounts up to the threshold are sent right away.
+	w, pending, err := service.RequestTransfer(ctx, userID, to, parse("100"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending != nil || w.Balances["USD"].String() != "200.00" {
+		t.Fatalf("want the transfer made, got %+v", pending)
+	}
+	if _, _, err = service.RequestTransfer(ctx, userID, to, parse("250"), ""); !errors.Is(err, wallet.ErrNotEnoughFunds) {
+		t.Fatalf("want a transfer above the balance rejected before the challenge, got %v", err)
+	}
+	if _, _, err = service.RequestTransfer(ctx, userID, wallet.Recipient{UserID: userID}, parse("150"), ""); !errors.Is(err, wallet.ErrInvalidRecipient) {
+		t.Fatalf("want a transfer to oneself rejected before the challenge, got %v", err)
+	}
+
+	_, pending, err = service.RequestTransfer(ctx, userID, to, parse("150"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending == nil || pending.Amount.String() != "150.00" {
+		t.Fatalf("want a pending transfer, got %+v", pending)
+	}
+	if w, err = service.GetBalance(ctx, recipientID); err != nil || w.Balances["USD"].String() != "100.00" {
+		t.Fatalf("want the recipient's balance untouched until confirmed, got %v %v", w.Balances, err)
+	}
+	// A transfer is confirmed with ConfirmTransfer only.
+	if _, err = service.ConfirmWithdraw(ctx, userID, pending.ID, confirmationCode); !errors.Is(err, wallet.ErrWithdrawalExpired) {
+		t.Fatalf("want ErrWithdrawalExpired confirming a transfer as a withdrawal, got %v", err)
+	}
+	if _, err = service.ConfirmTransfer(ctx, userID, pending.ID, "000000"); !errors.Is(err, stepup.ErrInvalidCode) {
+		t.Fatalf("want ErrInvalidCode, got %v", err)
+	}
+	if w, err = service.ConfirmTransfer(ctx, userID, pending.ID, confirmationCode); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Balances["USD"].String(); got != "50.00" {
+		t.Fatalf("want balance 50.00, got %s", got)
+	}
+	if w, err = service.GetBalance(ctx, recipientID); err != nil || w.Balances["USD"].String() != "250.00" {
+		t.Fatalf("want the recipient credited 250.00, got %v %v", w.Balances, err)
+	}
+	if _, err = service.ConfirmTransfer(ctx, userID, pending.ID, confirmationCode); !errors.Is(err, wallet.ErrTransferExpired) {
+		t.Fatalf("want ErrTransferExpired confirming twice, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"wallet/internal/domain/stepup"
+)
+
+// pendingRecord is a pending withdrawal or transfer as it is kept in the
+// cache. The amount is stored as a decimal string and parsed back with the
+// currency precision.
+type pendingRecord struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+	// RecipientID is the user a pending transfer goes to. The recipient is
+	// resolved on request, so the confirmed transfer goes to the user the
+	// challenge was answered for.
+	RecipientID string    `json:"recipient_id,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (s *ServiceWallet) RequestWithdraw(ctx context.Context, userID string, amount Money, method stepup.Method) (Wallet, *PendingWithdrawal, error) {
+	const op = "wallet.RequestWithdraw"
+	log := s.logger.With(slog.String("op", op))
+
+	if !amount.IsPositive() {
+		return Wallet{}, nil, ErrInvalidAmountOrCurrency
+	}
+	c, err := s.currency(ctx, amount.Currency)
+	if err != nil {
+		return Wallet{}, nil, err
+	}
+	confirm, err := c.needsConfirmation(amount)
+	if err != nil {
+		log.Error(err.Error())
+		return Wallet{}, nil, ErrSmtWentWrong
+	}
+	if !confirm {
+		w, err := s.WalletWithdraw(ctx, userID, amount)
+		return w, nil, err
+	}
+	if err = c.checkLimits(amount); err != nil {
+		return Wallet{}, nil, err
+	}
+	// The wallet is checked without a lock to not challenge the user for a
+	// withdrawal bound to fail. WalletWithdraw checks it again on
+	// confirmation.
+	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	if err != nil {
+		return Wallet{}, nil, domainError(log, err)
+	}
+	if w.Frozen != nil {
+		return Wallet{}, nil, ErrWalletFrozen
+	}
+	if w.Balance(c).Sub(amount).IsNegative() {
+		return Wallet{}, nil, ErrInvalidAmountOrCurrency
+	}
+
+	ch, err := s.holdPending(ctx, log, userID, method, pendingWithdrawalKey, pendingRecord{
+		Currency: amount.Currency,
+		Amount:   amount.String(),
+	})
+	if err != nil {
+		return Wallet{}, nil, err
+	}
+	log.Info("withdrawal pending confirmation", slog.String("user_id", userID), slog.String("challenge_id", ch.ID))
+	return Wallet{}, &PendingWithdrawal{Challenge: ch, Amount: amount, Currency: amount.Currency}, nil
+}
+
+// ConfirmWithdraw makes the pending withdrawal if the code answers its
+// challenge. The withdrawal is consumed even if it fails, e.g. for
+// insufficient funds.
+func (s *ServiceWallet) ConfirmWithdraw(ctx context.Context, userID, challengeID, code string) (Wallet, error) {
+	const op = "wallet.ConfirmWithdraw"
+	log := s.logger.With(slog.String("op", op))
+
+	_, amount, err := s.confirmPending(ctx, log, userID, challengeID, code, pendingWithdrawalKey, ErrWithdrawalExpired)
+	if err != nil {
+		return Wallet{}, err
+	}
+	return s.WalletWithdraw(ctx, userID, amount)
+}
+
+// RequestTransfer is RequestWithdraw for transfers: an amount above the
+// confirmation threshold is sent once ConfirmTransfer answers the challenge.
+func (s *ServiceWallet) RequestTransfer(ctx context.Context, userID string, to Recipient, amount Money, method stepup.Method) (Wallet, *PendingTransfer, error) {
+	const op = "wallet.RequestTransfer"
+	log := s.logger.With(slog.String("op", op))
+
+	if !amount.IsPositive() {
+		return Wallet{}, nil, ErrInvalidAmountOrCurrency
+	}
+	c, err := s.currency(ctx, amount.Currency)
+	if err != nil {
+		return Wallet{}, nil, err
+	}
+	confirm, err := c.needsConfirmation(amount)
+	if err != nil {
+		log.Error(err.Error())
+		return Wallet{}, nil, ErrSmtWentWrong
+	}
+	if !confirm {
+		w, err := s.Transfer(ctx, userID, to, amount)
+		return w, nil, err
+	}
+	if err = c.checkLimits(amount); err != nil {
+		return Wallet{}, nil, err
+	}
+	recipientID, err := s.storage.GetUserIDByRecipient(ctx, to)
+	if err != nil {
+		return Wallet{}, nil, domainError(log, err)
+	}
+	if recipientID == userID {
+		return Wallet{}, nil, ErrInvalidRecipient
+	}
+	// As for withdrawals, Transfer checks the wallet again on confirmation.
+	w, err := s.storage.GetWalletByUserID(ctx, userID)
+	if err != nil {
+		return Wallet{}, nil, domainError(log, err)
+	}
+	if w.Frozen != nil {
+		return Wallet{}, nil, ErrWalletFrozen
+	}
+	if w.Balance(c).Sub(amount).IsNegative() {
+		return Wallet{}, nil, ErrNotEnoughFunds
+	}
+
+	ch, err := s.holdPending(ctx, log, userID, method, pendingTransferKey, pendingRecord{
+		Currency:    amount.Currency,
+		Amount:      amount.String(),
+		RecipientID: recipientID,
+	})
+	if err != nil {
+		return Wallet{}, nil, err
+	}
+	log.Info("transfer pending confirmation", slog.String("user_id", userID), slog.String("challenge_id", ch.ID))
+	return Wallet{}, &PendingTransfer{Challenge: ch, Amount: amount, Currency: amount.Currency}, nil
+}
+
+// ConfirmTransfer makes the pending transfer if the code answers its
+// challenge. Like withdrawals, the transfer is consumed even if it fails.
+func (s *ServiceWallet) ConfirmTransfer(ctx context.Context, userID, challengeID, code string) (Wallet, error) {
+	const op = "wallet.ConfirmTransfer"
+	log := s.logger.With(slog.String("op", op))
+
+	r, amount, err := s.confirmPending(ctx, log, userID, challengeID, code, pendingTransferKey, ErrTransferExpired)
+	if err != nil {
+		return Wallet{}, err
+	}
+	return s.Transfer(ctx, userID, Recipient{UserID: r.RecipientID}, amount)
+}
+
+// holdPending challenges the user and keeps the operation under the key of
+// the challenge until it expires.
+func (s *ServiceWallet) holdPending(ctx context.Context, log *slog.Logger, userID string, method stepup.Method, key func(userID, challengeID string) string, r pendingRecord) (stepup.Challenge, error) {
+	ch, err := s.stepUp.CreateChallenge(ctx, userID, method)
+	if err != nil {
+		return stepup.Challenge{}, domainError(log, err)
+	}
+	r.ExpiresAt = ch.ExpiresAt
+	jsonData, err := json.Marshal(r)
+	if err != nil {
+		log.Error(err.Error())
+		return stepup.Challenge{}, ErrSmtWentWrong
+	}
+	if err = s.cache.SetValue(ctx, key(userID, ch.ID), string(jsonData), time.Until(ch.ExpiresAt)); err != nil {
+		log.Error(err.Error())
+		return stepup.Challenge{}, ErrSmtWentWrong
+	}
+	return ch, nil
+}
+
+// confirmPending answers the challenge of the operation held by holdPending
+// and takes the operation out of the cache. expired is returned if there is
+// no such operation any more.
+func (s *ServiceWallet) confirmPending(ctx context.Context, log *slog.Logger, userID, challengeID, code string, keyOf func(userID, challengeID string) string, expired error) (pendingRecord, Money, error) {
+	key := keyOf(userID, challengeID)
+	data, err := s.cache.GetValue(ctx, key)
+	if err != nil {
+		log.Error(err.Error())
+		return pendingRecord{}, Money{}, ErrSmtWentWrong
+	}
+	if data == "" {
+		return pendingRecord{}, Money{}, expired
+	}
+	if err = s.stepUp.VerifyChallenge(ctx, userID, challengeID, code); err != nil {
+		if errors.Is(err, stepup.ErrChallengeNotFound) {
+			_ = s.cache.DeleteValue(ctx, key)
+			return pendingRecord{}, Money{}, expired
+		}
+		return pendingRecord{}, Money{}, domainError(log, err)
+	}
+	// The answered challenge is consumed, so only one confirmation gets
+	// here, but the operation may have expired in between.
+	if data, err = s.cache.PopValue(ctx, key); err != nil {
+		log.Error(err.Error())
+		return pendingRecord{}, Money{}, ErrSmtWentWrong
+	}
+	if data == "" {
+		return pendingRecord{}, Money{}, expired
+	}
+	var r pendingRecord
+	if err = json.Unmarshal([]byte(data), &r); err != nil {
+		log.Error(err.Error())
+		return pendingRecord{}, Money{}, ErrSmtWentWrong
+	}
+	c, err := s.currency(ctx, r.Currency)
+	if err != nil {
+		return pendingRecord{}, Money{}, err
+	}
+	amount, err := ParseMoney(r.Amount, c)
+	if err != nil {
+		log.Error(err.Error())
+		return pendingRecord{}, Money{}, ErrSmtWentWrong
+	}
+	return r, amount, nil
+}
+
+// pendingWithdrawalKey and pendingTransferKey scope pending operations to
+// their user, so a challenge id of another user is reported as expired.
+func pendingWithdrawalKey(userID, challengeID string) string {
+	return fmt.Sprintf("pending_withdrawal:%s:%s", userID, challengeID)
+}
+
+func pendingTransferKey(userID, challengeID string) string {
+	return fmt.Sprintf("pending_transfer:%s:%s", userID, challengeID)
+}
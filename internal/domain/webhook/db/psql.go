@@ -0,0 +1,316 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"strings"
+	"time"
+	"wallet/internal/domain/webhook"
+)
+
+type Storage struct {
+	Client webhook.PsqlClient
+	logger *slog.Logger
+}
+
+func NewRepository(client webhook.PsqlClient, logger *slog.Logger) webhook.Storage {
+	return &Storage{client, logger}
+}
+
+const deliveryColumns = `d.id, d.endpoint_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+       d.next_attempt_at, COALESCE(d.last_status_code, 0), d.created_at`
+
+func scanDelivery(row pgx.Row, d *webhook.Delivery, extra ...interface{}) error {
+	dest := []interface{}{&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.CreatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func (s *Storage) CreateEndpoint(ctx context.Context, e webhook.Endpoint) (webhook.Endpoint, error) {
+	const op = "webhook.db.CreateEndpoint"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `INSERT INTO webhook_endpoint(user_id, url, secret, event_types)
+		  VALUES ($1, $2, $3, $4)
+		  RETURNING id, created_at`
+	err := s.Client.QueryRow(ctx, q, e.UserID, e.URL, e.Secret, e.EventTypes).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		log.Error(err.Error())
+		return e, err
+	}
+	return e, nil
+}
+
+func (s *Storage) CountEndpoints(ctx context.Context, userID string) (int, error) {
+	const op = "webhook.db.CountEndpoints"
+	log := s.logger.With(slog.String("op", op))
+
+	var n int
+	err := s.Client.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_endpoint WHERE user_id=$1`, userID).Scan(&n)
+	if err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *Storage) GetEndpoints(ctx context.Context, userID string) ([]webhook.Endpoint, error) {
+	const op = "webhook.db.GetEndpoints"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT id, user_id, url, event_types, created_at
+		  FROM webhook_endpoint
+		  WHERE user_id=$1
+		  ORDER BY created_at`
+	rows, err := s.Client.Query(ctx, q, userID)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := make([]webhook.Endpoint, 0)
+	for rows.Next() {
+		var e webhook.Endpoint
+		if err = rows.Scan(&e.ID, &e.UserID, &e.URL, &e.EventTypes, &e.CreatedAt); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (s *Storage) GetEndpoint(ctx context.Context, userID, id string) (webhook.Endpoint, error) {
+	const op = "webhook.db.GetEndpoint"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT id, user_id, url, event_types, created_at
+		  FROM webhook_endpoint
+		  WHERE user_id=$1 AND id=$2`
+	var e webhook.Endpoint
+	err := s.Client.QueryRow(ctx, q, userID, id).Scan(&e.ID, &e.UserID, &e.URL, &e.EventTypes, &e.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return e, webhook.ErrEndpointNotFound
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return e, err
+	}
+	return e, nil
+}
+
+func (s *Storage) DeleteEndpoint(ctx context.Context, userID, id string) error {
+	const op = "webhook.db.DeleteEndpoint"
+	log := s.logger.With(slog.String("op", op))
+
+	tag, err := s.Client.Exec(ctx, `DELETE FROM webhook_endpoint WHERE user_id=$1 AND id=$2`, userID, id)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return webhook.ErrEndpointNotFound
+	}
+	return nil
+}
+
+func (s *Storage) AddDeliveries(ctx context.Context, walletID string, eventID, eventType string, payload []byte) error {
+	const op = "webhook.db.AddDeliveries"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `INSERT INTO webhook_delivery(endpoint_id, event_id, event_type, payload)
+		  SELECT e.id, $2, $3, $4
+		  FROM webhook_endpoint e
+		  JOIN wallet w ON w.user_id = e.user_id
+		  WHERE w.id = $1 AND $3 = ANY(e.event_types)
+		  ON CONFLICT (endpoint_id, event_id) DO NOTHING`
+	if _, err := s.Client.Exec(ctx, q, walletID, eventID, eventType, payload); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) GetDeliveries(ctx context.Context, endpointID string, filter webhook.DeliveryFilter) ([]webhook.Delivery, error) {
+	const op = "webhook.db.GetDeliveries"
+	log := s.logger.With(slog.String("op", op))
+
+	args := []interface{}{endpointID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conds := []string{"d.endpoint_id = $1"}
+	if filter.Status != "" {
+		conds = append(conds, "d.status = "+arg(filter.Status))
+	}
+	q := fmt.Sprintf(`SELECT %s
+		  FROM webhook_delivery d
+		  WHERE %s
+		  ORDER BY d.created_at DESC, d.id
+		  LIMIT %s`, deliveryColumns, strings.Join(conds, " AND "), arg(filter.Limit))
+
+	rows, err := s.Client.Query(ctx, q, args...)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]webhook.Delivery, 0, filter.Limit)
+	for rows.Next() {
+		var d webhook.Delivery
+		if err = scanDelivery(rows, &d); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) GetDelivery(ctx context.Context, userID, id string) (webhook.Delivery, error) {
+	const op = "webhook.db.GetDelivery"
+	log := s.logger.With(slog.String("op", op))
+
+	q := fmt.Sprintf(`SELECT %s
+		  FROM webhook_delivery d
+		  JOIN webhook_endpoint e ON e.id = d.endpoint_id
+		  WHERE e.user_id=$1 AND d.id=$2`, deliveryColumns)
+	var d webhook.Delivery
+	err := scanDelivery(s.Client.QueryRow(ctx, q, userID, id), &d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return d, webhook.ErrDeliveryNotFound
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return d, err
+	}
+	return d, nil
+}
+
+func (s *Storage) GetAttempts(ctx context.Context, deliveryID string) ([]webhook.Attempt, error) {
+	const op = "webhook.db.GetAttempts"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `SELECT attempted_at, COALESCE(status_code, 0), COALESCE(error, ''), duration_ms
+		  FROM webhook_delivery_attempt
+		  WHERE delivery_id=$1
+		  ORDER BY id`
+	rows, err := s.Client.Query(ctx, q, deliveryID)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]webhook.Attempt, 0)
+	for rows.Next() {
+		var a webhook.Attempt
+		if err = rows.Scan(&a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMs); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return attempts, nil
+}
+
+func (s *Storage) ReplayDelivery(ctx context.Context, userID, id string) (webhook.Delivery, error) {
+	const op = "webhook.db.ReplayDelivery"
+	log := s.logger.With(slog.String("op", op))
+
+	q := fmt.Sprintf(`UPDATE webhook_delivery d
+		  SET status='pending', attempts=0, next_attempt_at=NOW()
+		  FROM webhook_endpoint e
+		  WHERE e.id = d.endpoint_id AND e.user_id=$1 AND d.id=$2
+		  RETURNING %s`, deliveryColumns)
+	var d webhook.Delivery
+	err := scanDelivery(s.Client.QueryRow(ctx, q, userID, id), &d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return d, webhook.ErrDeliveryNotFound
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return d, err
+	}
+	return d, nil
+}
+
+func (s *Storage) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhook.DueDelivery, error) {
+	const op = "webhook.db.ClaimDueDeliveries"
+	log := s.logger.With(slog.String("op", op))
+
+	q := fmt.Sprintf(`WITH due AS (
+			  SELECT id
+			  FROM webhook_delivery
+			  WHERE status = 'pending' AND next_attempt_at <= NOW()
+			  ORDER BY next_attempt_at
+			  LIMIT $1
+			  FOR UPDATE SKIP LOCKED
+		  )
+		  UPDATE webhook_delivery d
+		  SET next_attempt_at = NOW() + make_interval(secs => $2)
+		  FROM due, webhook_endpoint e
+		  WHERE d.id = due.id AND e.id = d.endpoint_id
+		  RETURNING %s, e.url, e.secret`, deliveryColumns)
+	rows, err := s.Client.Query(ctx, q, limit, lease.Seconds())
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := make([]webhook.DueDelivery, 0, limit)
+	for rows.Next() {
+		var d webhook.DueDelivery
+		if err = scanDelivery(rows, &d.Delivery, &d.URL, &d.Secret); err != nil {
+			log.Error(err.Error())
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	if err = rows.Err(); err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return due, nil
+}
+
+func (s *Storage) RecordAttempt(ctx context.Context, deliveryID string, a webhook.Attempt, status webhook.DeliveryStatus, nextAttemptAt time.Time) error {
+	const op = "webhook.db.RecordAttempt"
+	log := s.logger.With(slog.String("op", op))
+
+	q := `WITH a AS (
+			  INSERT INTO webhook_delivery_attempt(delivery_id, attempted_at, status_code, error, duration_ms)
+			  VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5)
+		  )
+		  UPDATE webhook_delivery
+		  SET status = $6,
+		      attempts = attempts + 1,
+		      last_status_code = NULLIF($3, 0),
+		      next_attempt_at = $7
+		  WHERE id = $1`
+	_, err := s.Client.Exec(ctx, q, deliveryID, a.AttemptedAt, a.StatusCode, a.Error, a.DurationMs, status, nextAttemptAt)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
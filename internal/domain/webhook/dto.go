@@ -0,0 +1,25 @@
+package webhook
+
+type CreateEndpointRequest struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2048" example:"https://example.com/webhooks/wallet"`
+	Secret     string   `json:"secret" validate:"required,min=16,max=128"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=WalletCreated Deposited Withdrawn Exchanged Transferred TransferReceived WalletFrozen WalletUnfrozen Adjusted"`
+}
+
+type EndpointsResponse struct {
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+type DeliveriesRequest struct {
+	Status string `form:"status" validate:"omitempty,oneof=pending succeeded dead"`
+	Limit  int    `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type DeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+type DeliveryResponse struct {
+	Delivery Delivery  `json:"delivery"`
+	Attempts []Attempt `json:"attempts"`
+}
@@ -0,0 +1,10 @@
+package webhook
+
+import "errors"
+
+var ErrSmtWentWrong = errors.New("something went wrong")
+var ErrEndpointNotFound = errors.New("webhook endpoint not found")
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+var ErrTooManyEndpoints = errors.New("too many webhook endpoints")
+var ErrInvalidURL = errors.New("webhook url must be https and must not point at a local or private address")
+var ErrForbiddenAddress = errors.New("webhook address is local or private")
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// blockedNetworks are the ranges not covered by the net.IP methods used in
+// checkIP that still must not be reachable from the service.
+var blockedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "this network"
+	mustParseCIDR("100.64.0.0/10"), // carrier-grade NAT
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// checkIP returns ErrForbiddenAddress for addresses of the host itself and of
+// internal networks.
+func checkIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return ErrForbiddenAddress
+	}
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// ValidateURL returns ErrInvalidURL unless the endpoint URL is https and its
+// host is neither localhost nor an address checkIP refuses. Host names are
+// checked again when connecting, since they may resolve to anything.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrInvalidURL
+	}
+	if ip := net.ParseIP(host); ip != nil && checkIP(ip) != nil {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// dialControl runs after the host name is resolved and before connecting, so
+// a name can't be pointed at an internal address after the endpoint is
+// registered.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ErrForbiddenAddress
+	}
+	return checkIP(ip)
+}
+
+// NewClient returns the client the deliveries are sent with. It refuses to
+// connect to the addresses checkIP refuses and ignores the proxy settings of
+// the environment, which would hide the address actually dialed.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: requestTimeout,
+		Control: dialControl,
+	}
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   requestTimeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		// A redirect is reported as a failed attempt rather than
+		// followed, the endpoint URL has to be fixed instead.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
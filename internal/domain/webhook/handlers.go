@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+// CreateEndpointHandler godoc
+// @Summary      Register webhook endpoint
+// @Description  Register a URL to receive the events of the user's wallet. Requests are signed with the secret, see the X-Webhook-Signature header: "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">". The URL must be https and must not point at a local or private address. Failed deliveries are retried with exponential backoff.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        request  body      CreateEndpointRequest  true  "Endpoint"
+// @Success      201      {object}  Endpoint
+// @Failure      400      {object}  map[string]interface{}  "Validation failed or webhook url must be https and must not point at a local or private address"
+// @Failure      401      {object}  map[string]string       "user not found"
+// @Failure      409      {object}  map[string]string       "too many webhook endpoints"
+// @Failure      500      {object}  map[string]string       "internal server error"
+// @Router       /api/v1/webhooks/endpoints/ [post]
+func CreateEndpointHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req CreateEndpointRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		e, err := s.CreateEndpoint(context.Background(), userID, req)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, e)
+	}
+}
+
+// GetEndpointsHandler godoc
+// @Summary      List webhook endpoints
+// @Description  Retrieve the webhook endpoints of the user
+// @Tags         webhooks
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Success      200  {object}  EndpointsResponse
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/webhooks/endpoints/ [get]
+func GetEndpointsHandler(s Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		endpoints, err := s.GetEndpoints(context.Background(), userID)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, EndpointsResponse{Endpoints: endpoints})
+	}
+}
+
+// DeleteEndpointHandler godoc
+// @Summary      Delete webhook endpoint
+// @Description  Delete the endpoint together with its deliveries
+// @Tags         webhooks
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        id   path      string  true  "Endpoint id"
+// @Success      204
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      404  {object}  map[string]string  "webhook endpoint not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/webhooks/endpoints/{id}/ [delete]
+func DeleteEndpointHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		if v.Var(id, "uuid") != nil {
+			writeJSONError(c, ErrEndpointNotFound)
+			return
+		}
+		if err := s.DeleteEndpoint(context.Background(), userID, id); err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetDeliveriesHandler godoc
+// @Summary      List webhook deliveries
+// @Description  Retrieve the latest deliveries of the endpoint with their status and the response code of the last attempt
+// @Tags         webhooks
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        id      path      string  true   "Endpoint id"
+// @Param        status  query     string  false  "Delivery status"  Enums(pending, succeeded, dead)
+// @Param        limit   query     int     false  "Number of deliveries (1-100, default 20)"
+// @Success      200  {object}  DeliveriesResponse
+// @Failure      400  {object}  map[string]interface{}  "Validation failed"
+// @Failure      401  {object}  map[string]string       "user not found"
+// @Failure      404  {object}  map[string]string       "webhook endpoint not found"
+// @Failure      500  {object}  map[string]string       "internal server error"
+// @Router       /api/v1/webhooks/endpoints/{id}/deliveries/ [get]
+func GetDeliveriesHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var req DeliveriesRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		if err := v.Struct(req); err != nil {
+			var validationErrors validator.ValidationErrors
+			errors.As(err, &validationErrors)
+			invalidFields := make([]string, len(validationErrors))
+
+			for i, fieldError := range validationErrors {
+				invalidFields[i] = fieldError.Field()
+			}
+
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Validation failed",
+				"fields": invalidFields,
+			})
+			return
+		}
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		if v.Var(id, "uuid") != nil {
+			writeJSONError(c, ErrEndpointNotFound)
+			return
+		}
+		filter := DeliveryFilter{Status: DeliveryStatus(req.Status), Limit: req.Limit}
+		deliveries, err := s.GetDeliveries(context.Background(), userID, id, filter)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, DeliveriesResponse{Deliveries: deliveries})
+	}
+}
+
+// GetDeliveryHandler godoc
+// @Summary      Get webhook delivery
+// @Description  Retrieve the delivery with the log of its attempts and their response codes
+// @Tags         webhooks
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        id   path      string  true  "Delivery id"
+// @Success      200  {object}  DeliveryResponse
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      404  {object}  map[string]string  "webhook delivery not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/webhooks/deliveries/{id}/ [get]
+func GetDeliveryHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		if v.Var(id, "uuid") != nil {
+			writeJSONError(c, ErrDeliveryNotFound)
+			return
+		}
+		d, attempts, err := s.GetDelivery(context.Background(), userID, id)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, DeliveryResponse{Delivery: d, Attempts: attempts})
+	}
+}
+
+// ReplayDeliveryHandler godoc
+// @Summary      Replay webhook delivery
+// @Description  Send the delivery again, e.g. after it went dead, with a new series of attempts
+// @Tags         webhooks
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer Token"  default(Bearer <token>)
+// @Param        id   path      string  true  "Delivery id"
+// @Success      202  {object}  Delivery
+// @Failure      401  {object}  map[string]string  "user not found"
+// @Failure      404  {object}  map[string]string  "webhook delivery not found"
+// @Failure      500  {object}  map[string]string  "internal server error"
+// @Router       /api/v1/webhooks/deliveries/{id}/replay/ [post]
+func ReplayDeliveryHandler(s Service, v *validator.Validate) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		if v.Var(id, "uuid") != nil {
+			writeJSONError(c, ErrDeliveryNotFound)
+			return
+		}
+		d, err := s.ReplayDelivery(context.Background(), userID, id)
+		if err != nil {
+			writeJSONError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, d)
+	}
+}
+
+// currentUser returns the id of the authenticated user, responding with an
+// error if there is none.
+func currentUser(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return "", false
+	}
+	userIDStr, ok := userID.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+		return "", false
+	}
+	return userIDStr, true
+}
+
+func writeJSONError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrEndpointNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTooManyEndpoints):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrSmtWentWrong.Error()})
+	}
+}
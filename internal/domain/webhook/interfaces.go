@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+	"wallet/internal/domain/outbox"
+)
+
+type PsqlClient interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type Storage interface {
+	CreateEndpoint(ctx context.Context, e Endpoint) (Endpoint, error)
+	CountEndpoints(ctx context.Context, userID string) (int, error)
+	GetEndpoints(ctx context.Context, userID string) ([]Endpoint, error)
+	// DeleteEndpoint and GetEndpoint return ErrEndpointNotFound unless the
+	// user has an endpoint with the id.
+	GetEndpoint(ctx context.Context, userID, id string) (Endpoint, error)
+	DeleteEndpoint(ctx context.Context, userID, id string) error
+	// AddDeliveries creates a delivery of the event for every endpoint of the
+	// owner of the wallet subscribed to its type. Events already added are
+	// skipped.
+	AddDeliveries(ctx context.Context, walletID string, eventID, eventType string, payload []byte) error
+	GetDeliveries(ctx context.Context, endpointID string, filter DeliveryFilter) ([]Delivery, error)
+	// GetDelivery and ReplayDelivery return ErrDeliveryNotFound unless the
+	// delivery belongs to an endpoint of the user.
+	GetDelivery(ctx context.Context, userID, id string) (Delivery, error)
+	GetAttempts(ctx context.Context, deliveryID string) ([]Attempt, error)
+	ReplayDelivery(ctx context.Context, userID, id string) (Delivery, error)
+	// ClaimDueDeliveries returns up to limit pending deliveries due for
+	// sending and postpones them by lease, so that other workers don't send
+	// them at the same time.
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error)
+	// RecordAttempt logs the attempt and moves the delivery to its new
+	// status. nextAttemptAt is used for pending deliveries only.
+	RecordAttempt(ctx context.Context, deliveryID string, a Attempt, status DeliveryStatus, nextAttemptAt time.Time) error
+}
+
+type Service interface {
+	CreateEndpoint(ctx context.Context, userID string, req CreateEndpointRequest) (Endpoint, error)
+	GetEndpoints(ctx context.Context, userID string) ([]Endpoint, error)
+	DeleteEndpoint(ctx context.Context, userID, id string) error
+	GetDeliveries(ctx context.Context, userID, endpointID string, filter DeliveryFilter) ([]Delivery, error)
+	GetDelivery(ctx context.Context, userID, id string) (Delivery, []Attempt, error)
+	// ReplayDelivery sends the delivery again regardless of its status,
+	// starting a new series of attempts.
+	ReplayDelivery(ctx context.Context, userID, id string) (Delivery, error)
+	// DeliverDue sends the deliveries due for sending and returns how many
+	// it attempted.
+	DeliverDue(ctx context.Context) (int, error)
+	// Publish turns a wallet event into deliveries to the subscribed endpoints.
+	outbox.EventPublisher
+}
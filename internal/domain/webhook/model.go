@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Endpoint is a URL the user receives the events of their wallet at. The
+// secret signs the requests and is never returned by the API.
+type Endpoint struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries are sent at NextAttemptAt.
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryDead deliveries failed maxAttempts times in a row. They are
+	// sent again only when replayed.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// Delivery is an event to be sent to an endpoint. Payload is the request
+// body.
+type Delivery struct {
+	ID             string          `json:"id"`
+	EndpointID     string          `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// Attempt is a single request of a delivery. StatusCode is zero if the
+// endpoint didn't respond, Error then tells why.
+type Attempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+}
+
+// DueDelivery is a delivery claimed for sending with what is needed to send
+// it.
+type DueDelivery struct {
+	Delivery
+	URL    string
+	Secret string
+}
+
+type DeliveryFilter struct {
+	Status DeliveryStatus
+	Limit  int
+}
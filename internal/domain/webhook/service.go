@@ -0,0 +1,291 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+	"wallet/internal/domain/outbox"
+)
+
+const (
+	HeaderSignature  = "X-Webhook-Signature"
+	HeaderEventType  = "X-Webhook-Event"
+	HeaderDeliveryID = "X-Webhook-Delivery"
+)
+
+const (
+	maxEndpoints = 10
+	// maxAttempts is the number of failed attempts after which a delivery is
+	// dead.
+	maxAttempts    = 8
+	retryBaseDelay = 30 * time.Second
+	maxRetryDelay  = 12 * time.Hour
+
+	deliveryBatchSize = 20
+	requestTimeout    = 10 * time.Second
+	// deliveryLease must be longer than requestTimeout, or a delivery could
+	// be claimed again while it is still being sent.
+	deliveryLease = time.Minute
+	// maxErrorLength limits the error text stored with an attempt.
+	maxErrorLength = 1000
+
+	defaultDeliveriesLimit = 20
+)
+
+type ServiceWebhook struct {
+	logger  *slog.Logger
+	storage Storage
+	client  *http.Client
+}
+
+// NewService sends the deliveries with the client, which should be the one
+// NewClient returns outside of tests.
+func NewService(storage Storage, logger *slog.Logger, client *http.Client) Service {
+	return &ServiceWebhook{
+		storage: storage,
+		logger:  logger,
+		client:  client,
+	}
+}
+
+// Sign returns the value of the HeaderSignature header of a request with the
+// body sent at t: "t=<unix time>,v1=<signature>", where the signature is the
+// hex encoded HMAC-SHA256 of "<unix time>.<body>" keyed with the endpoint
+// secret. Receivers should compute it the same way and reject requests with
+// an old time to guard against replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay returns how long to wait after the given number of failed
+// attempts in a row: retryBaseDelay doubled for every failure but the first,
+// at most maxRetryDelay.
+func RetryDelay(failures int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+func (s *ServiceWebhook) CreateEndpoint(ctx context.Context, userID string, req CreateEndpointRequest) (Endpoint, error) {
+	const op = "webhook.CreateEndpoint"
+	log := s.logger.With(slog.String("op", op))
+
+	if err := ValidateURL(req.URL); err != nil {
+		return Endpoint{}, err
+	}
+	n, err := s.storage.CountEndpoints(ctx, userID)
+	if err != nil {
+		log.Error(err.Error())
+		return Endpoint{}, ErrSmtWentWrong
+	}
+	if n >= maxEndpoints {
+		return Endpoint{}, ErrTooManyEndpoints
+	}
+	e, err := s.storage.CreateEndpoint(ctx, Endpoint{
+		UserID:     userID,
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return Endpoint{}, ErrSmtWentWrong
+	}
+	return e, nil
+}
+
+func (s *ServiceWebhook) GetEndpoints(ctx context.Context, userID string) ([]Endpoint, error) {
+	const op = "webhook.GetEndpoints"
+	log := s.logger.With(slog.String("op", op))
+
+	endpoints, err := s.storage.GetEndpoints(ctx, userID)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	return endpoints, nil
+}
+
+// DeleteEndpoint removes the endpoint together with its deliveries.
+func (s *ServiceWebhook) DeleteEndpoint(ctx context.Context, userID, id string) error {
+	const op = "webhook.DeleteEndpoint"
+	log := s.logger.With(slog.String("op", op))
+
+	if err := s.storage.DeleteEndpoint(ctx, userID, id); err != nil {
+		return domainError(log, err)
+	}
+	return nil
+}
+
+func (s *ServiceWebhook) GetDeliveries(ctx context.Context, userID, endpointID string, filter DeliveryFilter) ([]Delivery, error) {
+	const op = "webhook.GetDeliveries"
+	log := s.logger.With(slog.String("op", op))
+
+	if _, err := s.storage.GetEndpoint(ctx, userID, endpointID); err != nil {
+		return nil, domainError(log, err)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultDeliveriesLimit
+	}
+	deliveries, err := s.storage.GetDeliveries(ctx, endpointID, filter)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, ErrSmtWentWrong
+	}
+	return deliveries, nil
+}
+
+func (s *ServiceWebhook) GetDelivery(ctx context.Context, userID, id string) (Delivery, []Attempt, error) {
+	const op = "webhook.GetDelivery"
+	log := s.logger.With(slog.String("op", op))
+
+	d, err := s.storage.GetDelivery(ctx, userID, id)
+	if err != nil {
+		return Delivery{}, nil, domainError(log, err)
+	}
+	attempts, err := s.storage.GetAttempts(ctx, id)
+	if err != nil {
+		log.Error(err.Error())
+		return Delivery{}, nil, ErrSmtWentWrong
+	}
+	return d, attempts, nil
+}
+
+func (s *ServiceWebhook) ReplayDelivery(ctx context.Context, userID, id string) (Delivery, error) {
+	const op = "webhook.ReplayDelivery"
+	log := s.logger.With(slog.String("op", op))
+
+	d, err := s.storage.ReplayDelivery(ctx, userID, id)
+	if err != nil {
+		return Delivery{}, domainError(log, err)
+	}
+	return d, nil
+}
+
+// Publish creates the deliveries of a wallet event. The event as a whole,
+// with its id and type, is the body of the requests.
+func (s *ServiceWebhook) Publish(ctx context.Context, e outbox.Event) error {
+	const op = "webhook.Publish"
+	log := s.logger.With(slog.String("op", op))
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if err = s.storage.AddDeliveries(ctx, e.AggregateID, e.ID, e.Type, body); err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *ServiceWebhook) DeliverDue(ctx context.Context) (int, error) {
+	const op = "webhook.DeliverDue"
+	log := s.logger.With(slog.String("op", op))
+
+	due, err := s.storage.ClaimDueDeliveries(ctx, deliveryBatchSize, deliveryLease)
+	if err != nil {
+		log.Error(err.Error())
+		return 0, ErrSmtWentWrong
+	}
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func(d DueDelivery) {
+			defer wg.Done()
+			s.deliver(ctx, log, d)
+		}(d)
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+// deliver makes an attempt to send the delivery and schedules the next one
+// if it fails.
+func (s *ServiceWebhook) deliver(ctx context.Context, log *slog.Logger, d DueDelivery) {
+	a := s.send(ctx, d)
+	failures := d.Attempts + 1
+	status := DeliveryPending
+	switch {
+	case a.StatusCode >= 200 && a.StatusCode < 300:
+		status = DeliverySucceeded
+	case failures >= maxAttempts:
+		status = DeliveryDead
+	}
+	err := s.storage.RecordAttempt(ctx, d.ID, a, status, time.Now().Add(RetryDelay(failures)))
+	if err != nil {
+		log.Error(err.Error(), slog.String("delivery_id", d.ID))
+	}
+}
+
+func (s *ServiceWebhook) send(ctx context.Context, d DueDelivery) Attempt {
+	a := Attempt{AttemptedAt: time.Now()}
+	defer func() {
+		a.DurationMs = time.Since(a.AttemptedAt).Milliseconds()
+		if len(a.Error) > maxErrorLength {
+			a.Error = a.Error[:maxErrorLength]
+		}
+	}()
+
+	// Endpoints registered over http before it was refused are not sent to.
+	if u, err := url.Parse(d.URL); err != nil || u.Scheme != "https" {
+		a.Error = ErrInvalidURL.Error()
+		return a
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(d.Secret, a.AttemptedAt, d.Payload))
+	req.Header.Set(HeaderEventType, d.EventType)
+	req.Header.Set(HeaderDeliveryID, d.ID)
+	res, err := s.client.Do(req)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer res.Body.Close()
+	// The body is drained so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	a.StatusCode = res.StatusCode
+	if a.StatusCode < 200 || a.StatusCode >= 300 {
+		a.Error = res.Status
+	}
+	return a
+}
+
+// domainError passes errors meant for the client through as is and logs and
+// hides everything else behind ErrSmtWentWrong.
+func domainError(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, ErrEndpointNotFound),
+		errors.Is(err, ErrDeliveryNotFound):
+		return err
+	}
+	log.Error(err.Error())
+	return ErrSmtWentWrong
+}
@@ -0,0 +1,270 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wallet/internal/domain/webhook"
+	"wallet/pkg/logger"
+)
+
+const userID = "3c6f0d52-8a4e-4b1f-9d27-5e0a1b7c9f34"
+
+// memoryStorage keeps the endpoints and deliveries in memory in place of
+// Postgres. The queries themselves are tested in storage_test.go.
+type memoryStorage struct {
+	mu         sync.Mutex
+	endpoints  []webhook.Endpoint
+	deliveries []webhook.DueDelivery
+	attempts   map[string][]webhook.Attempt
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{attempts: make(map[string][]webhook.Attempt)}
+}
+
+func (m *memoryStorage) CreateEndpoint(_ context.Context, e webhook.Endpoint) (webhook.Endpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(m.endpoints))
+	e.CreatedAt = time.Now()
+	m.endpoints = append(m.endpoints, e)
+	return e, nil
+}
+
+func (m *memoryStorage) CountEndpoints(_ context.Context, userID string) (int, error) {
+	endpoints, _ := m.GetEndpoints(context.Background(), userID)
+	return len(endpoints), nil
+}
+
+func (m *memoryStorage) GetEndpoints(_ context.Context, userID string) ([]webhook.Endpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var endpoints []webhook.Endpoint
+	for _, e := range m.endpoints {
+		if e.UserID == userID {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
+
+func (m *memoryStorage) GetEndpoint(_ context.Context, userID, id string) (webhook.Endpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.endpoints {
+		if e.UserID == userID && e.ID == id {
+			return e, nil
+		}
+	}
+	return webhook.Endpoint{}, webhook.ErrEndpointNotFound
+}
+
+func (m *memoryStorage) DeleteEndpoint(context.Context, string, string) error {
+	return webhook.ErrEndpointNotFound
+}
+
+func (m *memoryStorage) AddDeliveries(context.Context, string, string, string, []byte) error {
+	return nil
+}
+
+func (m *memoryStorage) GetDeliveries(context.Context, string, webhook.DeliveryFilter) ([]webhook.Delivery, error) {
+	return nil, nil
+}
+
+func (m *memoryStorage) GetDelivery(_ context.Context, _, id string) (webhook.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			return d.Delivery, nil
+		}
+	}
+	return webhook.Delivery{}, webhook.ErrDeliveryNotFound
+}
+
+func (m *memoryStorage) GetAttempts(_ context.Context, deliveryID string) ([]webhook.Attempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.attempts[deliveryID], nil
+}
+
+func (m *memoryStorage) ReplayDelivery(context.Context, string, string) (webhook.Delivery, error) {
+	return webhook.Delivery{}, webhook.ErrDeliveryNotFound
+}
+
+func (m *memoryStorage) ClaimDueDeliveries(_ context.Context, limit int, lease time.Duration) ([]webhook.DueDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []webhook.DueDelivery
+	for i, d := range m.deliveries {
+		if len(due) < limit && d.Status == webhook.DeliveryPending && !d.NextAttemptAt.After(time.Now()) {
+			m.deliveries[i].NextAttemptAt = time.Now().Add(lease)
+			due = append(due, m.deliveries[i])
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryStorage) RecordAttempt(_ context.Context, deliveryID string, a webhook.Attempt, status webhook.DeliveryStatus, nextAttemptAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.deliveries {
+		if d.ID == deliveryID {
+			m.deliveries[i].Status = status
+			m.deliveries[i].Attempts++
+			m.deliveries[i].LastStatusCode = a.StatusCode
+			m.deliveries[i].NextAttemptAt = nextAttemptAt
+			m.attempts[deliveryID] = append(m.attempts[deliveryID], a)
+		}
+	}
+	return nil
+}
+
+// addDelivery queues a delivery due right away.
+func (m *memoryStorage) addDelivery(id, url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, webhook.DueDelivery{
+		Delivery: webhook.Delivery{
+			ID:            id,
+			EventID:       id,
+			EventType:     "Deposited",
+			Payload:       []byte(`{"type":"Deposited"}`),
+			Status:        webhook.DeliveryPending,
+			NextAttemptAt: time.Now(),
+		},
+		URL:    url,
+		Secret: "0123456789abcdef",
+	})
+}
+
+// makeDue moves the next attempt of the delivery to now, as if the retry
+// delay had passed.
+func (m *memoryStorage) makeDue(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.deliveries {
+		if d.ID == id {
+			m.deliveries[i].NextAttemptAt = time.Now()
+		}
+	}
+}
+
+func (m *memoryStorage) delivery(t *testing.T, id string) webhook.Delivery {
+	d, err := m.GetDelivery(context.Background(), userID, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDeliverDue(t *testing.T) {
+	ctx := context.Background()
+	var status atomic.Int32
+	var received sync.Map
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received.Store(r.Header.Get(webhook.HeaderDeliveryID), r.Header.Get(webhook.HeaderSignature)+" "+string(body))
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	storage := newMemoryStorage()
+	s := webhook.NewService(storage, logger.SetupLogger(logger.Prod, ""), srv.Client())
+
+	t.Run("Succeeded On 2xx", func(t *testing.T) {
+		status.Store(http.StatusNoContent)
+		storage.addDelivery("ok", srv.URL)
+		if n, err := s.DeliverDue(ctx); err != nil || n != 1 {
+			t.Fatalf("want 1 delivery attempted, got %d, %v", n, err)
+		}
+		d := storage.delivery(t, "ok")
+		if d.Status != webhook.DeliverySucceeded || d.Attempts != 1 || d.LastStatusCode != http.StatusNoContent {
+			t.Fatalf("unexpected delivery %+v", d)
+		}
+		got, ok := received.Load("ok")
+		if !ok || !strings.HasPrefix(got.(string), "t=") || !strings.HasSuffix(got.(string), ` {"type":"Deposited"}`) {
+			t.Fatalf("unexpected request %v", got)
+		}
+		if n, _ := s.DeliverDue(ctx); n != 0 {
+			t.Fatalf("want a succeeded delivery not sent again, got %d", n)
+		}
+	})
+
+	t.Run("Backs Off On Failure", func(t *testing.T) {
+		status.Store(http.StatusServiceUnavailable)
+		storage.addDelivery("retry", srv.URL)
+		before := time.Now()
+		if _, err := s.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		d := storage.delivery(t, "retry")
+		if d.Status != webhook.DeliveryPending || d.Attempts != 1 || d.LastStatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("unexpected delivery %+v", d)
+		}
+		if d.NextAttemptAt.Before(before.Add(webhook.RetryDelay(1))) || d.NextAttemptAt.After(time.Now().Add(webhook.RetryDelay(1))) {
+			t.Fatalf("want the next attempt in %s, got %s", webhook.RetryDelay(1), time.Until(d.NextAttemptAt))
+		}
+		if n, _ := s.DeliverDue(ctx); n != 0 {
+			t.Fatalf("want the delivery not sent before its next attempt, got %d", n)
+		}
+
+		storage.makeDue("retry")
+		before = time.Now()
+		if _, err := s.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		d = storage.delivery(t, "retry")
+		if d.Status != webhook.DeliveryPending || d.Attempts != 2 || d.NextAttemptAt.Before(before.Add(webhook.RetryDelay(2))) {
+			t.Fatalf("want the delay doubled after the second failure, got %+v", d)
+		}
+	})
+
+	t.Run("Dead After Max Attempts", func(t *testing.T) {
+		status.Store(http.StatusInternalServerError)
+		storage.addDelivery("dead", srv.URL)
+		for i := 0; i < 20 && storage.delivery(t, "dead").Status == webhook.DeliveryPending; i++ {
+			if _, err := s.DeliverDue(ctx); err != nil {
+				t.Fatal(err)
+			}
+			storage.makeDue("dead")
+		}
+		d := storage.delivery(t, "dead")
+		// maxAttempts
+		if d.Status != webhook.DeliveryDead || d.Attempts != 8 {
+			t.Fatalf("want the delivery dead after 8 attempts, got %+v", d)
+		}
+		if n, _ := s.DeliverDue(ctx); n != 0 {
+			t.Fatalf("want a dead delivery not sent again, got %d", n)
+		}
+	})
+
+	t.Run("Refused Addresses", func(t *testing.T) {
+		status.Store(http.StatusOK)
+		guarded := webhook.NewService(storage, logger.SetupLogger(logger.Prod, ""), webhook.NewClient())
+		storage.addDelivery("loopback", srv.URL)
+		storage.addDelivery("plain", strings.Replace(srv.URL, "https://", "http://", 1))
+		if _, err := guarded.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		for id, want := range map[string]error{"loopback": webhook.ErrForbiddenAddress, "plain": webhook.ErrInvalidURL} {
+			d := storage.delivery(t, id)
+			attempts, _ := storage.GetAttempts(ctx, id)
+			if d.Status != webhook.DeliveryPending || len(attempts) != 1 || attempts[0].StatusCode != 0 ||
+				!strings.Contains(attempts[0].Error, want.Error()) {
+				t.Fatalf("%s: want the attempt refused with %q, got %+v %+v", id, want, d, attempts)
+			}
+			if _, ok := received.Load(id); ok {
+				t.Fatalf("%s: want no request to reach the server", id)
+			}
+		}
+	})
+}
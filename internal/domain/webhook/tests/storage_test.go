@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"github.com/joho/godotenv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+	"wallet/internal/domain/outbox"
+	"wallet/internal/domain/webhook"
+	"wallet/internal/domain/webhook/db"
+	"wallet/pkg/clients/psql"
+	"wallet/pkg/logger"
+)
+
+type TestStorageConfig struct {
+	DBUser     string
+	DBPassword string
+	DBHost     string
+	DBPort     string
+	DBName     string
+}
+
+func loadTestConfig(t *testing.T) *TestStorageConfig {
+	err := godotenv.Load("../../../../config.env")
+	if err != nil {
+		t.Fatal("Error loading .env file")
+	}
+	requiredEnvVars := []string{
+		"POSTGRES_USER",
+		"POSTGRES_PASSWORD",
+		"POSTGRES_HOST",
+		"POSTGRES_PORT",
+		"POSTGRES_DB",
+	}
+
+	for _, envVar := range requiredEnvVars {
+		if os.Getenv(envVar) == "" {
+			t.Fatalf("%s must be set", envVar)
+		}
+	}
+	return &TestStorageConfig{
+		DBUser:     os.Getenv("POSTGRES_USER"),
+		DBPassword: os.Getenv("POSTGRES_PASSWORD"),
+		DBHost:     os.Getenv("POSTGRES_HOST"),
+		DBPort:     os.Getenv("POSTGRES_PORT"),
+		DBName:     os.Getenv("POSTGRES_DB"),
+	}
+}
+
+func TestStorage(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if cfg.DBHost == "postgres" {
+		cfg.DBHost = "localhost"
+	}
+	psqlClient, err := psql.NewClient(context.Background(), psql.PostgresConfig{
+		Addr:     cfg.DBHost,
+		Port:     cfg.DBPort,
+		Username: cfg.DBUser,
+		Password: cfg.DBPassword,
+		Database: cfg.DBName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		ownerID = "b2d7e4a1-6c3f-4a98-8e51-0f9c2d7b4e63"
+		otherID = "5e9a1c7d-3b2f-4d86-a4c0-8f1e6b3d2a95"
+	)
+	ctx := context.Background()
+	qi := `INSERT INTO "user"(id, email, username, password) VALUES ($1, $2, $3, $4)`
+	qd := `DELETE FROM "user" WHERE id = $1`
+	for _, id := range []string{ownerID, otherID} {
+		if _, err = psqlClient.Exec(ctx, qi, id, id+"@gmail.com", "webhooks-"+id[:8], "password"); err != nil {
+			t.Fatal(err)
+		}
+		defer func(id string) {
+			if _, err := psqlClient.Exec(ctx, qd, id); err != nil {
+				t.Fatal(err)
+			}
+		}(id)
+	}
+	var walletID string
+	if err = psqlClient.QueryRow(ctx, `INSERT INTO wallet(user_id) VALUES ($1) RETURNING id`, ownerID).Scan(&walletID); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	received := make(map[string]int)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	log := logger.SetupLogger(logger.Prod, "")
+	storage := db.NewRepository(psqlClient, log)
+	service := webhook.NewService(storage, log, srv.Client())
+	// The endpoints are created through the storage, the service refuses
+	// the loopback address of the test server.
+	deposits, err := storage.CreateEndpoint(ctx, webhook.Endpoint{
+		UserID:     ownerID,
+		URL:        srv.URL + "/deposits",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{"Deposited"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawals, err := storage.CreateEndpoint(ctx, webhook.Endpoint{
+		UserID:     ownerID,
+		URL:        srv.URL + "/withdrawals",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{"Withdrawn", "Exchanged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := outbox.Event{
+		ID:          "9f3b6d1e-2c4a-4e87-b5f0-7a1d8c3e6b42",
+		Type:        "Deposited",
+		AggregateID: walletID,
+		Payload:     []byte(`{"amount":"10.00","currency":"USD"}`),
+		CreatedAt:   time.Now(),
+	}
+	var deliveryID string
+
+	t.Run("Subscribed Types Only", func(t *testing.T) {
+		if err := service.Publish(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		// Publishing an event again doesn't duplicate its deliveries.
+		if err := service.Publish(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		got, err := service.GetDeliveries(ctx, ownerID, deposits.ID, webhook.DeliveryFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].EventID != event.ID || got[0].Status != webhook.DeliveryPending {
+			t.Fatalf("want one pending delivery of the event, got %+v", got)
+		}
+		deliveryID = got[0].ID
+		if got, err = service.GetDeliveries(ctx, ownerID, withdrawals.ID, webhook.DeliveryFilter{}); err != nil || len(got) != 0 {
+			t.Fatalf("want no deliveries to an endpoint not subscribed to the type, got %+v, %v", got, err)
+		}
+
+		if _, err = service.DeliverDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if received["/deposits"] != 1 || received["/withdrawals"] != 0 {
+			t.Fatalf("want the event sent to the subscribed endpoint only, got %v", received)
+		}
+		d, _, err := service.GetDelivery(ctx, ownerID, deliveryID)
+		if err != nil || d.Status != webhook.DeliverySucceeded {
+			t.Fatalf("want the delivery succeeded, got %+v, %v", d, err)
+		}
+	})
+
+	t.Run("Replay Dead", func(t *testing.T) {
+		a := webhook.Attempt{AttemptedAt: time.Now(), StatusCode: http.StatusBadGateway, Error: "502 Bad Gateway"}
+		if err := storage.RecordAttempt(ctx, deliveryID, a, webhook.DeliveryDead, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := service.ReplayDelivery(ctx, otherID, deliveryID); !errors.Is(err, webhook.ErrDeliveryNotFound) {
+			t.Fatalf("want ErrDeliveryNotFound replaying another user's delivery, got %v", err)
+		}
+		d, err := service.ReplayDelivery(ctx, ownerID, deliveryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Status != webhook.DeliveryPending || d.Attempts != 0 || d.NextAttemptAt.After(time.Now()) {
+			t.Fatalf("want the delivery pending and due, got %+v", d)
+		}
+		_, attempts, err := service.GetDelivery(ctx, ownerID, deliveryID)
+		if err != nil || len(attempts) != 2 {
+			t.Fatalf("want the attempts kept after a replay, got %+v, %v", attempts, err)
+		}
+	})
+
+	t.Run("Lease Expiry", func(t *testing.T) {
+		claimed := func() bool {
+			due, err := storage.ClaimDueDeliveries(ctx, 100, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range due {
+				if d.ID == deliveryID {
+					return true
+				}
+			}
+			return false
+		}
+		if !claimed() {
+			t.Fatal("want the replayed delivery claimed")
+		}
+		// A worker that claimed the delivery and died never records an
+		// attempt, others skip it until the lease expires.
+		if claimed() {
+			t.Fatal("want a leased delivery not claimed by another worker")
+		}
+		time.Sleep(1100 * time.Millisecond)
+		if !claimed() {
+			t.Fatal("want the delivery claimed again after the lease")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+	"wallet/internal/domain/webhook"
+)
+
+func TestSign(t *testing.T) {
+	const secret = "0123456789abcdef"
+	body := []byte(`{"id":"1","type":"Deposited"}`)
+	at := time.Unix(1736323200, 0)
+
+	sig := webhook.Sign(secret, at, body)
+	if !strings.HasPrefix(sig, "t=1736323200,v1=") {
+		t.Fatalf("unexpected signature header %q", sig)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1736323200." + string(body)))
+	if want := hex.EncodeToString(mac.Sum(nil)); strings.TrimPrefix(sig, "t=1736323200,v1=") != want {
+		t.Fatalf("want signature %s, got %s", want, sig)
+	}
+	if webhook.Sign("another secret..", at, body) == sig {
+		t.Fatal("signature doesn't depend on the secret")
+	}
+	if webhook.Sign(secret, at.Add(time.Second), body) == sig {
+		t.Fatal("signature doesn't depend on the time")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		7:  32 * time.Minute,
+		20: 12 * time.Hour,
+	}
+	for failures, want := range cases {
+		if got := webhook.RetryDelay(failures); got != want {
+			t.Fatalf("after %d failures: want %s, got %s", failures, want, got)
+		}
+	}
+}
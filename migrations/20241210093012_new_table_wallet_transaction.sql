@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS wallet_transaction (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    wallet_id UUID NOT NULL,
+    operation_id UUID NOT NULL, -- Общий идентификатор для всех проводок одной операции
+    type VARCHAR(16) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL, -- Со знаком: зачисление > 0, списание < 0
+    balance_after NUMERIC(15, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_wallet FOREIGN KEY (wallet_id) REFERENCES wallet(id) ON DELETE CASCADE,
+    CONSTRAINT chk_type CHECK (type IN ('opening', 'deposit', 'withdraw', 'exchange'))
+);
+CREATE INDEX IF NOT EXISTS idx_wallet_transaction_wallet_created
+    ON wallet_transaction (wallet_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_wallet_transaction_operation
+    ON wallet_transaction (operation_id);
+
+-- Журнал только дополняется: изменять проводки нельзя, а удалять можно
+-- только каскадом вместе с кошельком.
+CREATE OR REPLACE FUNCTION wallet_transaction_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' AND pg_trigger_depth() > 1 THEN
+    RETURN OLD;
+  END IF;
+  RAISE EXCEPTION 'wallet_transaction is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER wallet_transaction_append_only
+    BEFORE UPDATE OR DELETE ON wallet_transaction
+    FOR EACH ROW
+    EXECUTE PROCEDURE wallet_transaction_append_only();
+
+-- Переносим уже существующие остатки в журнал, чтобы сумма проводок
+-- совпадала с балансом кошелька.
+INSERT INTO wallet_transaction (wallet_id, operation_id, type, currency, amount, balance_after)
+SELECT w.id, gen_random_uuid(), 'opening', b.currency, b.amount, b.amount
+FROM wallet w
+CROSS JOIN LATERAL (VALUES ('EUR', w.balance_eur),
+                           ('USD', w.balance_usd),
+                           ('RUB', w.balance_rub)) AS b(currency, amount)
+WHERE b.amount <> 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS wallet_transaction_append_only ON wallet_transaction;
+DROP FUNCTION IF EXISTS wallet_transaction_append_only;
+DROP TABLE IF EXISTS wallet_transaction;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet
+    ADD CONSTRAINT chk_balance_non_negative
+    CHECK (balance_eur >= 0 AND balance_usd >= 0 AND balance_rub >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE wallet DROP CONSTRAINT IF EXISTS chk_balance_non_negative;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Без внешнего ключа: история не должна зависеть от удаления кошелька получателя.
+ALTER TABLE wallet_transaction ADD COLUMN counterparty_wallet_id UUID;
+ALTER TABLE wallet_transaction DROP CONSTRAINT chk_type;
+ALTER TABLE wallet_transaction
+    ADD CONSTRAINT chk_type CHECK (type IN ('opening', 'deposit', 'withdraw', 'exchange', 'transfer'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE wallet_transaction DROP CONSTRAINT chk_type;
+ALTER TABLE wallet_transaction
+    ADD CONSTRAINT chk_type CHECK (type IN ('opening', 'deposit', 'withdraw', 'exchange'));
+ALTER TABLE wallet_transaction DROP COLUMN IF EXISTS counterparty_wallet_id;
+-- +goose StatementEnd
@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE currency
+    ADD COLUMN precision SMALLINT NOT NULL DEFAULT 2 CHECK (precision BETWEEN 0 AND 8),
+    ADD COLUMN rounding VARCHAR(16) NOT NULL DEFAULT 'half_even'
+        CHECK (rounding IN ('half_even', 'half_up', 'down'));
+UPDATE currency SET rounding = 'half_up' WHERE code = 'RUB';
+
+CREATE TABLE IF NOT EXISTS wallet_balance (
+    wallet_id UUID NOT NULL,
+    currency_code VARCHAR(3) NOT NULL,
+    amount NUMERIC(28, 8) NOT NULL DEFAULT 0,
+    PRIMARY KEY (wallet_id, currency_code),
+    CONSTRAINT fk_wallet FOREIGN KEY (wallet_id) REFERENCES wallet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_currency FOREIGN KEY (currency_code) REFERENCES currency(code),
+    CONSTRAINT chk_balance_non_negative CHECK (amount >= 0)
+);
+
+INSERT INTO wallet_balance (wallet_id, currency_code, amount)
+SELECT w.id, b.currency, b.amount
+FROM wallet w
+CROSS JOIN LATERAL (VALUES ('EUR', w.balance_eur),
+                           ('USD', w.balance_usd),
+                           ('RUB', w.balance_rub)) AS b(currency, amount)
+WHERE b.amount <> 0;
+
+ALTER TABLE wallet
+    DROP COLUMN balance_eur,
+    DROP COLUMN balance_usd,
+    DROP COLUMN balance_rub;
+
+-- Точность задаётся валютой, поэтому в журнале храним суммы без фиксированных двух знаков.
+ALTER TABLE wallet_transaction
+    ALTER COLUMN amount TYPE NUMERIC(28, 8),
+    ALTER COLUMN balance_after TYPE NUMERIC(28, 8),
+    ADD CONSTRAINT fk_currency FOREIGN KEY (currency) REFERENCES currency(code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE wallet_transaction
+    DROP CONSTRAINT IF EXISTS fk_currency,
+    ALTER COLUMN amount TYPE NUMERIC(15, 2),
+    ALTER COLUMN balance_after TYPE NUMERIC(15, 2);
+
+ALTER TABLE wallet
+    ADD COLUMN balance_eur NUMERIC(15, 2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN balance_usd NUMERIC(15, 2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN balance_rub NUMERIC(15, 2) NOT NULL DEFAULT 0.00,
+    ADD CONSTRAINT chk_balance_non_negative
+        CHECK (balance_eur >= 0 AND balance_usd >= 0 AND balance_rub >= 0);
+
+UPDATE wallet w SET
+    balance_eur = COALESCE((SELECT amount FROM wallet_balance WHERE wallet_id = w.id AND currency_code = 'EUR'), 0),
+    balance_usd = COALESCE((SELECT amount FROM wallet_balance WHERE wallet_id = w.id AND currency_code = 'USD'), 0),
+    balance_rub = COALESCE((SELECT amount FROM wallet_balance WHERE wallet_id = w.id AND currency_code = 'RUB'), 0);
+
+DROP TABLE IF EXISTS wallet_balance;
+
+ALTER TABLE currency
+    DROP COLUMN IF EXISTS rounding,
+    DROP COLUMN IF EXISTS precision;
+-- +goose StatementEnd
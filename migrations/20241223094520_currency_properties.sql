@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE currency
+    ADD COLUMN name VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN symbol VARCHAR(8) NOT NULL DEFAULT '',
+    -- NULL означает отсутствие ограничения.
+    ADD COLUMN min_amount NUMERIC(28, 8) CHECK (min_amount > 0),
+    ADD COLUMN max_amount NUMERIC(28, 8) CHECK (max_amount > 0),
+    ADD COLUMN exchangeable BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD CONSTRAINT chk_amount_limits CHECK (min_amount IS NULL OR max_amount IS NULL OR min_amount <= max_amount);
+
+UPDATE currency SET name = 'US Dollar', symbol = '$' WHERE code = 'USD';
+UPDATE currency SET name = 'Euro', symbol = '€' WHERE code = 'EUR';
+UPDATE currency SET name = 'Russian Ruble', symbol = '₽' WHERE code = 'RUB';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE currency
+    DROP CONSTRAINT IF EXISTS chk_amount_limits,
+    DROP COLUMN IF EXISTS enabled,
+    DROP COLUMN IF EXISTS exchangeable,
+    DROP COLUMN IF EXISTS max_amount,
+    DROP COLUMN IF EXISTS min_amount,
+    DROP COLUMN IF EXISTS symbol,
+    DROP COLUMN IF EXISTS name;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Правила комиссии за обмен. Ступени задаются нижней границей суммы в исходной
+-- валюте: применяется ступень с наибольшей границей, не превышающей сумму.
+CREATE TABLE IF NOT EXISTS exchange_fee_rule (
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    min_amount NUMERIC(28, 8) NOT NULL DEFAULT 0 CHECK (min_amount >= 0),
+    spread_percent NUMERIC(7, 4) NOT NULL DEFAULT 0 CHECK (spread_percent >= 0 AND spread_percent < 100),
+    fixed_fee NUMERIC(28, 8) NOT NULL DEFAULT 0 CHECK (fixed_fee >= 0),
+    min_fee NUMERIC(28, 8) NOT NULL DEFAULT 0 CHECK (min_fee >= 0),
+    PRIMARY KEY (from_currency, to_currency, min_amount),
+    CONSTRAINT fk_from_currency FOREIGN KEY (from_currency) REFERENCES currency(code) ON DELETE CASCADE,
+    CONSTRAINT fk_to_currency FOREIGN KEY (to_currency) REFERENCES currency(code) ON DELETE CASCADE
+);
+
+-- Служебный пользователь, на кошелёк которого зачисляются комиссии.
+-- Пароль пустой, поэтому войти под ним нельзя.
+INSERT INTO "user"(id, email, username, password)
+VALUES ('00000000-0000-0000-0000-000000000001', 'house@wallet.internal', 'house', '');
+INSERT INTO wallet(user_id) VALUES ('00000000-0000-0000-0000-000000000001');
+
+ALTER TABLE wallet_transaction DROP CONSTRAINT chk_type;
+ALTER TABLE wallet_transaction
+    ADD CONSTRAINT chk_type CHECK (type IN ('opening', 'deposit', 'withdraw', 'exchange', 'transfer', 'fee'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE wallet_transaction DROP CONSTRAINT chk_type;
+ALTER TABLE wallet_transaction
+    ADD CONSTRAINT chk_type CHECK (type IN ('opening', 'deposit', 'withdraw', 'exchange', 'transfer'));
+
+DELETE FROM "user" WHERE id = '00000000-0000-0000-0000-000000000001';
+DROP TABLE IF EXISTS exchange_fee_rule;
+-- +goose StatementEnd
@@ -0,0 +1,147 @@
+-- +goose Up
+-- +goose StatementBegin
+-- План счетов. Остатки пользователей по валютам, валютная позиция (house_fx),
+-- доходы от комиссий (fee_revenue) и расчёты с внешним миром (settlement)
+-- ведутся как счета. Знак у всех счетов один, поэтому сумма остатков по
+-- каждой валюте всегда равна нулю.
+CREATE TABLE IF NOT EXISTS account (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    type VARCHAR(16) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    wallet_id UUID,
+    balance NUMERIC(28, 8) NOT NULL DEFAULT 0,
+    CONSTRAINT fk_wallet FOREIGN KEY (wallet_id) REFERENCES wallet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_currency FOREIGN KEY (currency) REFERENCES currency(code),
+    CONSTRAINT chk_type CHECK (type IN ('user', 'house_fx', 'fee_revenue', 'settlement')),
+    CONSTRAINT chk_owner CHECK ((type = 'user') = (wallet_id IS NOT NULL)),
+    -- Уходить в минус могут только служебные счета.
+    CONSTRAINT chk_balance_non_negative CHECK (type <> 'user' OR balance >= 0)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ux_account_user ON account (wallet_id, currency) WHERE type = 'user';
+CREATE UNIQUE INDEX IF NOT EXISTS ux_account_system ON account (type, currency) WHERE type <> 'user';
+
+-- Счета пользователей: по остаткам и по валютам, в которых были проводки.
+INSERT INTO account (type, wallet_id, currency, balance)
+SELECT 'user', p.wallet_id, p.currency, COALESCE(b.amount, 0)
+FROM (SELECT wallet_id, currency_code AS currency FROM wallet_balance
+      UNION
+      SELECT wallet_id, currency FROM wallet_transaction) p
+JOIN wallet w ON w.id = p.wallet_id
+LEFT JOIN wallet_balance b ON b.wallet_id = p.wallet_id AND b.currency_code = p.currency
+WHERE w.user_id <> '00000000-0000-0000-0000-000000000001';
+
+INSERT INTO account (type, currency)
+SELECT t.type, c.code
+FROM currency c
+CROSS JOIN (VALUES ('house_fx'), ('fee_revenue'), ('settlement')) AS t(type);
+
+ALTER TABLE wallet_transaction
+    ADD COLUMN account_id UUID,
+    ADD CONSTRAINT fk_account FOREIGN KEY (account_id) REFERENCES account(id) ON DELETE CASCADE,
+    ALTER COLUMN wallet_id DROP NOT NULL;
+
+-- Перенос истории в новую модель - единственное место, где журнал меняется.
+ALTER TABLE wallet_transaction DISABLE TRIGGER wallet_transaction_append_only;
+
+UPDATE wallet_transaction t SET account_id = a.id
+FROM account a
+WHERE a.type = 'user' AND a.wallet_id = t.wallet_id AND a.currency = t.currency;
+
+-- Кошелёк комиссий становится счётом доходов.
+UPDATE wallet_transaction t SET account_id = a.id, wallet_id = NULL, counterparty_wallet_id = NULL
+FROM account a, wallet w
+WHERE a.type = 'fee_revenue' AND a.currency = t.currency
+  AND w.id = t.wallet_id AND w.user_id = '00000000-0000-0000-0000-000000000001';
+UPDATE wallet_transaction t SET counterparty_wallet_id = NULL
+FROM wallet w
+WHERE w.id = t.counterparty_wallet_id AND w.user_id = '00000000-0000-0000-0000-000000000001';
+
+-- Недостающие вторые части проводок: пополнения, списания и начальные остатки
+-- проводятся через settlement, обмены - через house_fx.
+INSERT INTO wallet_transaction (account_id, operation_id, type, currency, amount, balance_after, created_at)
+SELECT l.account_id, l.operation_id, l.type, l.currency, l.amount,
+       SUM(l.amount) OVER (PARTITION BY l.account_id ORDER BY l.created_at, l.operation_id),
+       l.created_at
+FROM (SELECT a.id AS account_id, m.*
+      FROM (SELECT operation_id, currency, MIN(type) AS type, MIN(created_at) AS created_at, -SUM(amount) AS amount
+            FROM wallet_transaction
+            GROUP BY operation_id, currency
+            HAVING SUM(amount) <> 0) m
+      JOIN account a ON a.currency = m.currency
+          AND a.type = CASE WHEN m.type = 'exchange' THEN 'house_fx' ELSE 'settlement' END) l;
+
+UPDATE account a SET balance = COALESCE((SELECT SUM(t.amount) FROM wallet_transaction t WHERE t.account_id = a.id), 0)
+WHERE a.type <> 'user';
+
+ALTER TABLE wallet_transaction ENABLE TRIGGER wallet_transaction_append_only;
+ALTER TABLE wallet_transaction ALTER COLUMN account_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_wallet_transaction_account_created
+    ON wallet_transaction (account_id, created_at);
+
+DROP TABLE IF EXISTS wallet_balance;
+DELETE FROM "user" WHERE id = '00000000-0000-0000-0000-000000000001';
+
+-- Каждая операция должна быть сбалансирована по каждой валюте. Проверка
+-- отложена до конца транзакции, когда записаны все части проводки.
+CREATE OR REPLACE FUNCTION wallet_transaction_balanced()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM wallet_transaction
+             WHERE operation_id = NEW.operation_id
+             GROUP BY currency
+             HAVING SUM(amount) <> 0) THEN
+    RAISE EXCEPTION 'journal entry % is not balanced', NEW.operation_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER wallet_transaction_balanced
+    AFTER INSERT ON wallet_transaction
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE PROCEDURE wallet_transaction_balanced();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS wallet_transaction_balanced ON wallet_transaction;
+DROP FUNCTION IF EXISTS wallet_transaction_balanced;
+
+INSERT INTO "user"(id, email, username, password)
+VALUES ('00000000-0000-0000-0000-000000000001', 'house@wallet.internal', 'house', '');
+INSERT INTO wallet(user_id) VALUES ('00000000-0000-0000-0000-000000000001');
+
+CREATE TABLE IF NOT EXISTS wallet_balance (
+    wallet_id UUID NOT NULL,
+    currency_code VARCHAR(3) NOT NULL,
+    amount NUMERIC(28, 8) NOT NULL DEFAULT 0,
+    PRIMARY KEY (wallet_id, currency_code),
+    CONSTRAINT fk_wallet FOREIGN KEY (wallet_id) REFERENCES wallet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_currency FOREIGN KEY (currency_code) REFERENCES currency(code),
+    CONSTRAINT chk_balance_non_negative CHECK (amount >= 0)
+);
+INSERT INTO wallet_balance (wallet_id, currency_code, amount)
+SELECT wallet_id, currency, balance FROM account WHERE type = 'user';
+INSERT INTO wallet_balance (wallet_id, currency_code, amount)
+SELECT w.id, a.currency, a.balance
+FROM account a, wallet w
+WHERE a.type = 'fee_revenue' AND a.balance <> 0
+  AND w.user_id = '00000000-0000-0000-0000-000000000001';
+
+ALTER TABLE wallet_transaction DISABLE TRIGGER wallet_transaction_append_only;
+UPDATE wallet_transaction t SET wallet_id = w.id
+FROM account a, wallet w
+WHERE a.id = t.account_id AND a.type = 'fee_revenue'
+  AND w.user_id = '00000000-0000-0000-0000-000000000001';
+DELETE FROM wallet_transaction WHERE wallet_id IS NULL;
+ALTER TABLE wallet_transaction ENABLE TRIGGER wallet_transaction_append_only;
+
+DROP INDEX IF EXISTS idx_wallet_transaction_account_created;
+ALTER TABLE wallet_transaction
+    DROP CONSTRAINT IF EXISTS fk_account,
+    DROP COLUMN IF EXISTS account_id,
+    ALTER COLUMN wallet_id SET NOT NULL;
+
+DROP TABLE IF EXISTS account;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Периодические снимки остатков счетов. Остаток на момент T - это последний
+-- снимок не позже T плюс сумма проводок после снимка до T включительно.
+CREATE TABLE IF NOT EXISTS account_snapshot (
+    account_id UUID NOT NULL,
+    taken_at TIMESTAMPTZ NOT NULL,
+    balance NUMERIC(28, 8) NOT NULL,
+    PRIMARY KEY (account_id, taken_at),
+    CONSTRAINT fk_account FOREIGN KEY (account_id) REFERENCES account(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS account_snapshot;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Доменные события, записываемые в одной транзакции с изменением кошелька.
+-- Фоновый процесс публикует их и отмечает published_at.
+CREATE TABLE IF NOT EXISTS outbox_event (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    seq BIGSERIAL NOT NULL UNIQUE, -- порядок записи событий
+    type VARCHAR(64) NOT NULL,
+    aggregate_id UUID NOT NULL, -- кошелек, к которому относится событие
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMPTZ -- NULL, пока событие не опубликовано
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_event_pending ON outbox_event(seq) WHERE published_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox_event;
+-- +goose StatementEnd
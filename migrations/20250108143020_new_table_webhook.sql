@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Адреса, на которые пользователь получает события своего кошелька.
+CREATE TABLE IF NOT EXISTS webhook_endpoint (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(128) NOT NULL, -- ключ подписи HMAC-SHA256
+    event_types TEXT[] NOT NULL, -- типы событий, на которые подписан адрес
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoint_user_id ON webhook_endpoint(user_id);
+
+-- Доставка одного события на один адрес. Неудачные попытки повторяются с
+-- экспоненциальной задержкой, после последней доставка переходит в dead.
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    endpoint_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL, -- тело запроса
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INTEGER, -- NULL, если ответа не было
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (endpoint_id, event_id),
+    CONSTRAINT fk_webhook_endpoint FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoint(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due ON webhook_delivery(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempt (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id UUID NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    status_code INTEGER, -- NULL, если ответа не было
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    CONSTRAINT fk_webhook_delivery FOREIGN KEY (delivery_id) REFERENCES webhook_delivery(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempt_delivery_id ON webhook_delivery_attempt(delivery_id);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON webhook_delivery
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_delivery_attempt;
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook_endpoint;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Курсы, полученные от сервиса обмена. Хранятся все полученные значения,
+-- чтобы строить графики и подтверждать, по какому курсу прошел обмен.
+-- Внешних ключей на currency нет: сервис обмена может вернуть курс валюты,
+-- которой еще нет в справочнике.
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    id BIGSERIAL PRIMARY KEY,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_exchange_rate_pair ON exchange_rate(from_currency, to_currency, fetched_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS exchange_rate;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Заморозка кошелька сотрудником поддержки: пока кошелёк заморожен, владелец
+-- не может проводить по нему операции.
+ALTER TABLE wallet
+    ADD COLUMN frozen_at TIMESTAMPTZ,
+    ADD COLUMN frozen_by UUID,
+    ADD COLUMN freeze_reason TEXT;
+
+-- Ручные корректировки остатков проводятся через счёт корректировок.
+ALTER TABLE account DROP CONSTRAINT chk_type;
+ALTER TABLE account
+    ADD CONSTRAINT chk_type CHECK (type IN ('user', 'house_fx', 'fee_revenue', 'settlement', 'adjustment'));
+ALTER TABLE wallet_transaction DROP CONSTRAINT chk_type;
+ALTER TABLE wallet_transaction
+    ADD CONSTRAINT chk_type CHECK (type IN ('opening', 'deposit', 'withdraw', 'exchange', 'transfer', 'fee', 'adjustment'));
+
+-- Журнал действий сотрудников с кошельками. Без внешнего ключа на оператора:
+-- журнал не должен зависеть от удаления его пользователя.
+CREATE TABLE IF NOT EXISTS admin_action (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    operator_id UUID NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    wallet_id UUID NOT NULL,
+    operation_id UUID,
+    currency VARCHAR(3),
+    amount NUMERIC(28, 8),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_wallet FOREIGN KEY (wallet_id) REFERENCES wallet(id) ON DELETE CASCADE,
+    CONSTRAINT fk_currency FOREIGN KEY (currency) REFERENCES currency(code),
+    CONSTRAINT chk_action CHECK (action IN ('freeze', 'unfreeze', 'adjust')),
+    CONSTRAINT chk_amount CHECK ((action = 'adjust') = (amount IS NOT NULL AND currency IS NOT NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_admin_action_wallet_created ON admin_action (wallet_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS admin_action;
+
+ALTER TABLE wallet_transaction DISABLE TRIGGER wallet_transaction_append_only;
+DELETE FROM wallet_transaction WHERE type = 'adjustment';
+ALTER TABLE wallet_transaction ENABLE TRIGGER wallet_transaction_append_only;
+ALTER TABLE wallet_transaction DROP CONSTRAINT chk_type;
+ALTER TABLE wallet_transaction
+    ADD CONSTRAINT chk_type CHECK (type IN ('opening', 'deposit', 'withdraw', 'exchange', 'transfer', 'fee'));
+DELETE FROM account WHERE type = 'adjustment';
+ALTER TABLE account DROP CONSTRAINT chk_type;
+ALTER TABLE account
+    ADD CONSTRAINT chk_type CHECK (type IN ('user', 'house_fx', 'fee_revenue', 'settlement'));
+
+ALTER TABLE wallet
+    DROP COLUMN IF EXISTS frozen_at,
+    DROP COLUMN IF EXISTS frozen_by,
+    DROP COLUMN IF EXISTS freeze_reason;
+-- +goose StatementEnd
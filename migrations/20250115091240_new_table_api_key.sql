@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ключи API для интеграций сервер-сервер. Хранится только хэш секрета,
+-- ключ целиком показывается один раз при создании. Префикс открыт и
+-- позволяет найти ключ без перебора.
+CREATE TABLE IF NOT EXISTS api_key (
+    id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    secret_hash BYTEA NOT NULL, -- SHA-256 секрета
+    scopes TEXT[] NOT NULL, -- права ключа
+    expires_at TIMESTAMPTZ, -- NULL - бессрочный
+    last_used_at TIMESTAMPTZ, -- обновляется не чаще раза в минуту
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_api_key_user_id ON api_key(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_key;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Выводы на сумму выше порога подтверждаются вторым фактором. NULL - без
+-- подтверждения.
+ALTER TABLE currency
+    ADD COLUMN confirmation_threshold NUMERIC(28, 8) CHECK (confirmation_threshold > 0);
+
+-- Секреты TOTP (RFC 6238) для приложений-аутентификаторов. Секрет хранится
+-- открыто: он нужен для проверки кодов. Пока confirmed_at пуст, подключение
+-- не подтверждено первым кодом и не используется.
+CREATE TABLE IF NOT EXISTS totp_secret (
+    user_id UUID PRIMARY KEY,
+    secret BYTEA NOT NULL,
+    last_step BIGINT NOT NULL DEFAULT 0, -- шаг последнего принятого кода, защита от повтора
+    confirmed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS totp_secret;
+ALTER TABLE currency DROP COLUMN IF EXISTS confirmation_threshold;
+-- +goose StatementEnd
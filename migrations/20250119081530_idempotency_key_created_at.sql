@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Индекс для периодического удаления просроченных ключей.
+CREATE INDEX IF NOT EXISTS idx_idempotency_key_created_at ON idempotency_key(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_idempotency_key_created_at;
+-- +goose StatementEnd
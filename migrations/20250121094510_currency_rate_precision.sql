@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Курсы таблицы currency используются для обменов, когда обменник и
+-- последние известные курсы недоступны, два знака после запятой их искажают.
+ALTER TABLE currency
+    ALTER COLUMN rate TYPE NUMERIC(20, 10),
+    ALTER COLUMN rate SET DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE currency
+    ALTER COLUMN rate TYPE NUMERIC(15, 2),
+    ALTER COLUMN rate SET DEFAULT 0.00;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Просмотры кошелька и его операций сотрудниками тоже попадают в журнал.
+ALTER TABLE admin_action
+    ALTER COLUMN action TYPE VARCHAR(32),
+    DROP CONSTRAINT chk_action,
+    ADD CONSTRAINT chk_action CHECK (action IN ('freeze', 'unfreeze', 'adjust', 'view_wallet', 'view_transactions'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM admin_action WHERE action IN ('view_wallet', 'view_transactions');
+ALTER TABLE admin_action
+    DROP CONSTRAINT chk_action,
+    ADD CONSTRAINT chk_action CHECK (action IN ('freeze', 'unfreeze', 'adjust')),
+    ALTER COLUMN action TYPE VARCHAR(16);
+-- +goose StatementEnd
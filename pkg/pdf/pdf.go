@@ -0,0 +1,114 @@
+// Package pdf writes simple text-only PDF documents. It uses the standard
+// Courier font, which every PDF reader provides, so nothing is embedded and
+// no external tools are needed.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	pageWidth  = 595 // A4 in points
+	pageHeight = 842
+	margin     = 40
+	fontSize   = 8
+	leading    = 10
+
+	// LineWidth is the number of characters that fit on a line. Courier
+	// glyphs are 0.6 em wide.
+	LineWidth    = (pageWidth - 2*margin) * 10 / (fontSize * 6)
+	linesPerPage = (pageHeight - 2*margin) / leading
+)
+
+// Document is a sequence of pages of monospaced text lines.
+type Document struct {
+	pages [][]string
+}
+
+func New() *Document {
+	return &Document{pages: [][]string{nil}}
+}
+
+// Line adds a line of text, starting a new page when the current one is full.
+// Lines longer than LineWidth are cut and characters outside of ASCII are
+// replaced with "?".
+func (d *Document) Line(text string) {
+	if len(d.pages[len(d.pages)-1]) == linesPerPage {
+		d.PageBreak()
+	}
+	if len(text) > LineWidth {
+		text = text[:LineWidth]
+	}
+	d.pages[len(d.pages)-1] = append(d.pages[len(d.pages)-1], text)
+}
+
+// PageBreak starts a new page.
+func (d *Document) PageBreak() {
+	d.pages = append(d.pages, nil)
+}
+
+// WriteTo writes the document in PDF format.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	// Objects 1-3 are the catalog, the page tree and the font, every page
+	// takes two more objects: the page and its content stream.
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, lines := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 5+2*i))
+		content := pageContent(lines)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+func pageContent(lines []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, leading, margin, pageHeight-margin)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "(%s) Tj T*\n", escape(line))
+	}
+	b.WriteString("ET")
+	return b.String()
+}
+
+// escape makes text safe to put in a PDF string literal.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}